@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+var (
+	slow = flag.Bool("slow", false, "also run the challenges that take minutes")
+	seed = flag.Int64("seed", 35, "seed for the deterministic randomness source")
+)
+
+func TestChallenges(t *testing.T) {
+	utils.UseSeededRand(*seed)
+	defer utils.UseCryptoRand()
+	for _, c := range allChallenges() {
+		c := c
+		t.Run(c.ID(), func(t *testing.T) {
+			if c.Slow && !*slow {
+				t.Skip("slow challenge, run with -slow")
+			}
+			if err := c.checkInputs(); err != nil {
+				t.Skip(err)
+			}
+			res := c.Run()
+			if res.Err != nil {
+				t.Fatalf("%s: %v", c.Title, res.Err)
+			}
+			if !res.Pass {
+				t.Fatalf("%s: recovered %q expected %q", c.Title, res.Recovered, res.Expected)
+			}
+			t.Logf("%s: %v, %d queries", c.Title, res.Elapsed, res.Queries)
+		})
+	}
+}
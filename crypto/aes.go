@@ -4,9 +4,10 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/sha256"
 	"encoding/binary"
+	"errors"
 	"fmt"
-	"math/rand"
 
 	"github.com/sukunrt/cryptopals/utils"
 )
@@ -19,23 +20,48 @@ const CBC Mode = "CBC"
 const ECB Mode = "ECB"
 const CTR Mode = "CTR"
 
-func newAESCipher(key []byte) cipher.Block {
-	cipher, err := aes.NewCipher(key)
-	if err != nil {
-		panic(err)
+var (
+	// ErrNotECB is returned by the ECB attacks when the oracle doesn't encrypt in ECB mode
+	ErrNotECB = errors.New("oracle does not encrypt in ECB mode")
+	// ErrAESKeySize is returned for a key that isn't 16, 24 or 32 bytes
+	ErrAESKeySize = errors.New("aes: invalid key size")
+	// ErrIVSize is returned for an IV or nonce of the wrong length
+	ErrIVSize = errors.New("invalid IV size")
+)
+
+// newAESCipher returns the AES-128, AES-192 or AES-256 block cipher for key
+func newAESCipher(key []byte) (cipher.Block, error) {
+	switch len(key) {
+	case 16, 24, 32:
+		return aes.NewCipher(key)
 	}
-	return cipher
+	return nil, fmt.Errorf("%d byte key: %w", len(key), ErrAESKeySize)
 }
 
+// checkIV returns an error unless iv is n bytes long
+func checkIV(iv []byte, n int) error {
+	if len(iv) != n {
+		return fmt.Errorf("%d byte IV, want %d: %w", len(iv), n, ErrIVSize)
+	}
+	return nil
+}
+
+// RandAESKey returns a random AES-128 key
 func RandAESKey() []byte {
 	return utils.RandBytes(AESBlockSize)
 }
 
-func PadKey(k []byte) []byte {
-	if len(k) < AESBlockSize {
-		k = append(k, make([]byte, AESBlockSize-len(k))...)
+// AESKeyFromSecret derives a size byte AES key from a shared secret, such as
+// a Diffie-Hellman one, as the start of its SHA-256 hash. Secrets are rarely
+// a valid key as they are and are not uniformly random
+func AESKeyFromSecret(secret []byte, size int) ([]byte, error) {
+	switch size {
+	case 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("%d byte key: %w", size, ErrAESKeySize)
 	}
-	return k[:AESBlockSize]
+	h := sha256.Sum256(secret)
+	return h[:size], nil
 }
 
 // AESInECBCipher encrypts or decrypts bytes in aes with ecb mode
@@ -47,26 +73,37 @@ type AESInECBCipher struct {
 // Encrypt encrypts bytes in ECB mode
 func (cipher AESInECBCipher) Encrypt(b []byte) []byte {
 	msg := utils.PadBytes(b, AESBlockSize)
-	cipherText := make([]byte, len(msg))
-	for i := 0; i < len(msg); i += AESBlockSize {
-		cipher.cipher.Encrypt(cipherText[i:], msg[i:i+AESBlockSize])
-	}
-	return cipherText
+	cipher.Encrypter().CryptBlocks(msg, msg)
+	return msg
 }
 
-// Decrypt decrypts bytes in ECB Mode
+// Decrypt decrypts bytes in ECB Mode. Invalid padding is left in place, use
+// DecryptStrict to get an error for it
 func (cipher AESInECBCipher) Decrypt(b []byte) []byte {
 	plainText := make([]byte, len(b))
-	for i := 0; i < len(b); i += AESBlockSize {
-		cipher.cipher.Decrypt(plainText[i:], b[i:])
-	}
+	cipher.Decrypter().CryptBlocks(plainText, b)
 	plainText = utils.RemovePad(plainText)
 	return plainText
 }
 
+// DecryptStrict decrypts b and removes the padding, returning an error
+// wrapping ErrInvalidPadding if it is invalid
+func (cipher AESInECBCipher) DecryptStrict(b []byte) ([]byte, error) {
+	if len(b)%AESBlockSize != 0 {
+		return nil, ErrPartialBlock
+	}
+	plainText := make([]byte, len(b))
+	cipher.Decrypter().CryptBlocks(plainText, b)
+	return utils.Unpad(plainText, AESBlockSize)
+}
+
 // NewAESInECBCipher returns a new cipher struct
-func NewAESInECBCipher(key []byte) AESInECBCipher {
-	return AESInECBCipher{key: key, cipher: newAESCipher(key)}
+func NewAESInECBCipher(key []byte) (AESInECBCipher, error) {
+	c, err := newAESCipher(key)
+	if err != nil {
+		return AESInECBCipher{}, err
+	}
+	return AESInECBCipher{key: key, cipher: c}, nil
 }
 
 // AESInCBCCipher encrypts or decrypts bytes with AES in CBC Mode
@@ -76,69 +113,82 @@ type AESInCBCCipher struct {
 }
 
 // NewAESInCBCCipher returns a new AESInCBCCipher struct
-func NewAESInCBCCipher(key []byte) AESInCBCCipher {
-	return AESInCBCCipher{key: PadKey(key), cipher: newAESCipher(PadKey(key))}
+func NewAESInCBCCipher(key []byte) (AESInCBCCipher, error) {
+	c, err := newAESCipher(key)
+	if err != nil {
+		return AESInCBCCipher{}, err
+	}
+	return AESInCBCCipher{key: key, cipher: c}, nil
 }
 
 // Encrypt encrypts b with the ac.key and iv
-func (ac AESInCBCCipher) Encrypt(b []byte, iv []byte) []byte {
-	msg := utils.PadBytes(b, AESBlockSize)
-	cipherText := make([]byte, len(msg))
-	prevIV := make([]byte, len(iv))
-	copy(prevIV, iv)
-	for i := 0; i < len(msg); i += AESBlockSize {
-		subMsg := utils.XorBytes(msg[i:i+AESBlockSize], prevIV)
-		ac.cipher.Encrypt(cipherText[i:], subMsg)
-		prevIV = cipherText[i : i+AESBlockSize]
+func (ac AESInCBCCipher) Encrypt(b []byte, iv []byte) ([]byte, error) {
+	m, err := ac.Encrypter(iv)
+	if err != nil {
+		return nil, err
 	}
-	return cipherText
+	msg := utils.PadBytes(b, AESBlockSize)
+	m.CryptBlocks(msg, msg)
+	return msg, nil
 }
 
 // DecryptWithoutPadding decrypts the msg without removing the padding
 // from the final plaintext
-func (ac AESInCBCCipher) DecryptWithoutPadding(b []byte, IV []byte) []byte {
+func (ac AESInCBCCipher) DecryptWithoutPadding(b []byte, IV []byte) ([]byte, error) {
+	m, err := ac.Decrypter(IV)
+	if err != nil {
+		return nil, err
+	}
+	if len(b)%AESBlockSize != 0 {
+		return nil, ErrPartialBlock
+	}
 	plainText := make([]byte, len(b))
-	prevIV := make([]byte, len(IV))
-	decryptedBlock := make([]byte, AESBlockSize)
-	copy(prevIV, IV)
-	for i := 0; i < len(b); i += AESBlockSize {
-		ac.cipher.Decrypt(decryptedBlock, b[i:i+AESBlockSize])
-		plainTextBlock := utils.XorBytes(decryptedBlock, prevIV)
-		copy(plainText[i:], plainTextBlock)
-		prevIV = b[i : i+AESBlockSize]
+	m.CryptBlocks(plainText, b)
+	return plainText, nil
+}
+
+// Decrypt decrypts the block with ac.key and iv. Invalid padding is left in
+// place, use DecryptStrict to get an error for it
+func (ac AESInCBCCipher) Decrypt(b []byte, iv []byte) ([]byte, error) {
+	plainText, err := ac.DecryptWithoutPadding(b, iv)
+	if err != nil {
+		return nil, err
 	}
-	return plainText
+	return utils.RemovePad(plainText), nil
 }
 
-// Decrypt decrypts the block with ac.key and iv
-func (ac AESInCBCCipher) Decrypt(b []byte, iv []byte) []byte {
-	plainText := ac.DecryptWithoutPadding(b, iv)
-	plainText = utils.RemovePad(plainText)
-	return plainText
+// DecryptStrict decrypts b with iv and removes the padding, returning an
+// error wrapping ErrInvalidPadding if it is invalid
+func (ac AESInCBCCipher) DecryptStrict(b []byte, iv []byte) ([]byte, error) {
+	plainText, err := ac.DecryptWithoutPadding(b, iv)
+	if err != nil {
+		return nil, err
+	}
+	return utils.Unpad(plainText, AESBlockSize)
 }
 
 // AESInCTRCipher encrypts and decrypts bytes in CTR Mode
 // It uses a 8 byte nonce and 8 byte little endian ctr
 type AESInCTRCipher struct {
-	key    []byte
 	nonce  []byte
 	cipher cipher.Block
 }
 
-func NewAESInCTRCipher(key []byte) AESInCTRCipher {
-	return AESInCTRCipher{
-		key:    utils.RandBytes(AESBlockSize),
-		nonce:  utils.RandBytes(AESBlockSize / 2),
-		cipher: newAESCipher(key),
-	}
+// NewAESInCTRCipher returns a CTR cipher with a random nonce
+func NewAESInCTRCipher(key []byte) (AESInCTRCipher, error) {
+	return NewAESInCTRCipherWithNonce(key, utils.RandBytes(AESBlockSize/2))
 }
 
-func NewAESInCTRCipherWithNonce(key []byte, nonce []byte) AESInCTRCipher {
-	return AESInCTRCipher{
-		key:    utils.RandBytes(AESBlockSize),
-		nonce:  nonce,
-		cipher: newAESCipher(key),
+// NewAESInCTRCipherWithNonce returns a CTR cipher with the 8 byte nonce
+func NewAESInCTRCipherWithNonce(key []byte, nonce []byte) (AESInCTRCipher, error) {
+	if err := checkIV(nonce, AESBlockSize/2); err != nil {
+		return AESInCTRCipher{}, err
 	}
+	c, err := newAESCipher(key)
+	if err != nil {
+		return AESInCTRCipher{}, err
+	}
+	return AESInCTRCipher{nonce: nonce, cipher: c}, nil
 }
 
 func (ac AESInCTRCipher) getKey(round int) []byte {
@@ -151,23 +201,11 @@ func (ac AESInCTRCipher) getKey(round int) []byte {
 	return key
 }
 
+// EncryptAtOffset encrypts b as the bytes starting offset bytes into the stream
 func (ac AESInCTRCipher) EncryptAtOffset(b []byte, offset int) []byte {
-	cipherBlocks := make([][]byte, 0)
-	paddingLen := (offset % AESBlockSize)
-	b = utils.ConcatBytes(utils.RepBytes(0, paddingLen), b)
-	round := offset / AESBlockSize
-	for i := 0; i < len(b); i += AESBlockSize {
-		key := ac.getKey(round)
-		var cipherBlock []byte
-		if len(b) < i+AESBlockSize {
-			cipherBlock = utils.XorBytes(key[:len(b)-i], b[i:])
-		} else {
-			cipherBlock = utils.XorBytes(key, b[i:i+AESBlockSize])
-		}
-		cipherBlocks = append(cipherBlocks, cipherBlock)
-		round++
-	}
-	return utils.ConcatBytes(cipherBlocks...)[paddingLen:]
+	res := make([]byte, len(b))
+	ac.Stream(offset).XORKeyStream(res, b)
+	return res
 }
 
 func (ac AESInCTRCipher) Encrypt(b []byte) []byte {
@@ -178,14 +216,17 @@ func (ac AESInCTRCipher) Decrypt(b []byte) []byte {
 	return ac.Encrypt(b)
 }
 
-func AESInECBWithSecretEncryptor(key []byte, secret []byte) func([]byte) []byte {
-	aesCipher := NewAESInECBCipher(key)
+func AESInECBWithSecretEncryptor(key []byte, secret []byte) (func([]byte) []byte, error) {
+	aesCipher, err := NewAESInECBCipher(key)
+	if err != nil {
+		return nil, err
+	}
 	return func(b []byte) []byte {
 		msg := make([]byte, len(b)+len(secret))
 		copy(msg, b)
 		copy(msg[len(b):], secret)
 		return aesCipher.Encrypt(msg)
-	}
+	}, nil
 }
 
 func DetectAESinECBMode(b []byte) int {
@@ -201,44 +242,72 @@ func DetectAESinECBMode(b []byte) int {
 	return cnt
 }
 
-// RandAESEncrypt encrypts b with either CBC or ECB mode with a random key and iv
-func randAESEncrypt(b []byte) ([]byte, Mode) {
-	maxPadding := 10
-	prefixPadding := utils.RandBytes(rand.Intn(maxPadding))
-	suffixPadding := utils.RandBytes(rand.Intn(maxPadding))
-	msg := make([]byte, len(b)+len(prefixPadding)+len(suffixPadding))
-	copy(msg, prefixPadding)
-	copy(msg[len(prefixPadding):], b)
-	copy(msg[len(prefixPadding)+len(b):], suffixPadding)
-	key := utils.RandBytes(AESBlockSize)
-	modes := []Mode{CBC, ECB}
-	mode := modes[rand.Intn(2)]
-	if mode == CBC {
-		IV := utils.RandBytes(AESBlockSize)
-		aesCipher := NewAESInCBCCipher(key)
-		return aesCipher.Encrypt(msg, IV), CBC
+// randAESEncrypter returns a source that encrypts with a random key, iv and
+// mode, picked once, after adding 5 to 10 random bytes before and after what
+// it's given
+func randAESEncrypter() (func([]byte) []byte, Mode) {
+	key := RandAESKey()
+	IV := utils.RandBytes(AESBlockSize)
+	modes := []Mode{CBC, ECB, CTR, CFB, CFB8, OFB, PCBC, XTS, CBCCTS}
+	mode := modes[utils.RandIntn(len(modes))]
+	// the key and iv are always the right size so none of these fail
+	var enc func([]byte) []byte
+	switch mode {
+	case CBC:
+		c, _ := NewAESInCBCCipher(key)
+		enc = func(b []byte) []byte { res, _ := c.Encrypt(b, IV); return res }
+	case CTR:
+		c, _ := NewAESInCTRCipher(key)
+		enc = c.Encrypt
+	case CFB:
+		c, _ := NewAESInCFBCipher(key)
+		enc = func(b []byte) []byte { res, _ := c.Encrypt(b, IV); return res }
+	case CFB8:
+		c, _ := NewAESInCFB8Cipher(key)
+		enc = func(b []byte) []byte { res, _ := c.Encrypt(b, IV); return res }
+	case OFB:
+		c, _ := NewAESInOFBCipher(key)
+		enc = func(b []byte) []byte { res, _ := c.Encrypt(b, IV); return res }
+	case PCBC:
+		c, _ := NewAESInPCBCCipher(key)
+		enc = func(b []byte) []byte { res, _ := c.Encrypt(b, IV); return res }
+	case XTS:
+		c, _ := NewAESInXTSCipher(append(key, RandAESKey()...))
+		// the message is never shorter than a block with the extra bytes
+		enc = func(b []byte) []byte { res, _ := c.Encrypt(b, uint64(utils.RandIntn(1<<20))); return res }
+	case CBCCTS:
+		c, _ := NewAESInCBCCTSCipher(key)
+		enc = func(b []byte) []byte { res, _ := c.Encrypt(b, IV); return res }
+	default:
+		c, _ := NewAESInECBCipher(key)
+		enc = c.Encrypt
 	}
-	return NewAESInECBCipher(key).Encrypt(msg), ECB
+	return func(b []byte) []byte {
+		prefixPadding := utils.RandBytes(5 + utils.RandIntn(6))
+		suffixPadding := utils.RandBytes(5 + utils.RandIntn(6))
+		return enc(utils.ConcatBytes(prefixPadding, b, suffixPadding))
+	}, mode
 }
 
-// DetectAESMode detects whether a particular source encrypts messages in
-// ECB Mode or CBC Mode
+// DetectAESMode detects whether a particular source encrypts messages in ECB
+// mode, in a padded chaining mode, reported as CBC, or in a mode that keeps
+// the length of the plaintext, reported as CTR. The source may add bytes
+// around what it's given
 func DetectAESMode(encFunc func([]byte) []byte) Mode {
-	// We send a message of 3*AESBlockSize length string containing the same letter
-	// This block should return identical consecutive blocks in the cipherText if the
-	// mode is ECB otherwise it is CBC
-	// 2 * AESBlockSize + epsilon doesn't work because the block may be broken up
-	// such that randomText + block1, block1 + block2, block2 + randomText
-	randBlock := utils.RandBytes(AESBlockSize)
-	minBlocksRequired := 3
-	msg := make([]byte, minBlocksRequired*AESBlockSize)
-	for i := 0; i < len(msg); i += AESBlockSize {
-		copy(msg[i:], randBlock)
-	}
-	cipherText := encFunc(msg)
-	for i := 0; i+AESBlockSize < len(cipherText); i += AESBlockSize {
-		if bytes.Equal(cipherText[i:i+AESBlockSize], cipherText[i+AESBlockSize:i+2*AESBlockSize]) {
-			return ECB
+	// 55 identical bytes make at least two identical whole blocks whatever
+	// comes before them. Padded modes only ever give whole blocks, a mode that
+	// keeps the length gives a partial block for one of a block's worth of
+	// input lengths unless the source adds a random number of bytes that
+	// always happens to make up a whole block
+	for i := 0; i < AESBlockSize; i++ {
+		cipherText := encFunc(utils.RepBytes('A', 55+i))
+		for j := 0; j+2*AESBlockSize <= len(cipherText); j += AESBlockSize {
+			if bytes.Equal(cipherText[j:j+AESBlockSize], cipherText[j+AESBlockSize:j+2*AESBlockSize]) {
+				return ECB
+			}
+		}
+		if len(cipherText)%AESBlockSize != 0 {
+			return CTR
 		}
 	}
 	return CBC
@@ -246,7 +315,7 @@ func DetectAESMode(encFunc func([]byte) []byte) Mode {
 
 // BreakSecretInECB takes an encryptor function which uses a secret suffix to encrypt
 // the message and returns the secret used by the encryptor
-func BreakSecretInECB(encFunc func(b []byte) []byte) []byte {
+func BreakSecretInECB(encFunc func(b []byte) []byte) ([]byte, error) {
 	fc := byte('A')
 	blockSize := 0
 	for i := 1; i <= 100; i++ {
@@ -262,8 +331,8 @@ func BreakSecretInECB(encFunc func(b []byte) []byte) []byte {
 	}
 
 	mode := DetectAESMode(encFunc)
-	if mode == CBC {
-		panic("Cannot break CBC Mode")
+	if mode != ECB {
+		return nil, ErrNotECB
 	}
 
 	secret := make([]byte, blockSize)
@@ -296,12 +365,12 @@ func BreakSecretInECB(encFunc func(b []byte) []byte) []byte {
 		}
 		secret = append(secret, s)
 	}
-	return secret[blockSize : len(secret)-1]
+	return secret[blockSize : len(secret)-1], nil
 }
 
 // BreakSecretInECBWithRandomPrefix breaks encFunc which encrypts bytes by
 // adding a random prefix and adds a random suffix
-func BreakSecretInECBWithRandomPrefix(encFunc func([]byte) []byte) []byte {
+func BreakSecretInECBWithRandomPrefix(encFunc func([]byte) []byte) ([]byte, error) {
 	blockSize := 0
 	fc := byte('B')
 	for i := 2; i < 100; i++ {
@@ -320,8 +389,8 @@ func BreakSecretInECBWithRandomPrefix(encFunc func([]byte) []byte) []byte {
 		}
 	}
 	mode := DetectAESMode(encFunc)
-	if mode == CBC {
-		panic("Cannot break CBC Mode")
+	if mode != ECB {
+		return nil, ErrNotECB
 	}
 
 	maxTries := 1 << 16
@@ -339,7 +408,7 @@ func BreakSecretInECBWithRandomPrefix(encFunc func([]byte) []byte) []byte {
 			targetMsg[blockSize-1] = byte(targetByte)
 			found := false
 			for t := 0; t < maxTries; t++ {
-				prefixLen := rand.Intn(blockSize)
+				prefixLen := utils.RandIntn(blockSize)
 				prefix := utils.RepBytes(fc, prefixLen)
 				msg := utils.ConcatBytes(prefix, marker, targetMsg)
 				cipherText := encFunc(msg)
@@ -357,7 +426,7 @@ func BreakSecretInECBWithRandomPrefix(encFunc func([]byte) []byte) []byte {
 				}
 			}
 			if !found {
-				panic(fmt.Sprintf("Failed to find a cipher for byte %d", targetByte))
+				return nil, fmt.Errorf("failed to find a cipher for byte %d: %w", targetByte, ErrAttackFailed)
 			}
 		}
 		blockPos := bytePos % blockSize
@@ -367,7 +436,7 @@ func BreakSecretInECBWithRandomPrefix(encFunc func([]byte) []byte) []byte {
 		found := false
 		done := false
 		for t := 0; t < maxTries; t++ {
-			prefixLen := rand.Intn(blockSize)
+			prefixLen := utils.RandIntn(blockSize)
 			prefix := utils.RepBytes(fc, prefixLen)
 			msg := utils.ConcatBytes(prefix, marker, paddingBytes)
 			cipherText := encFunc(msg)
@@ -394,114 +463,53 @@ func BreakSecretInECBWithRandomPrefix(encFunc func([]byte) []byte) []byte {
 		}
 	}
 	secret = utils.RemovePad(secret[blockSize:])
-	return secret
+	return secret, nil
 }
 
-func BreakCBCWithBitFlipping(encFunc func([]byte, []byte) []byte, passFunc func([]byte, []byte) bool) {
+// BreakCBCWithBitFlipping flips bits in the ciphertext produced by encFunc until
+// passFunc accepts it and returns the forged ciphertext and iv
+func BreakCBCWithBitFlipping(encFunc func([]byte, []byte) []byte, passFunc func([]byte, []byte) bool) ([]byte, []byte, error) {
 	fc := byte('A')
 	input := utils.RepBytes(fc, 2*AESBlockSize)
 	targetBlock := []byte(";admin=true;k=AAAAAAAAAAAAAAAAA")[:AESBlockSize]
 	diff := utils.XorBytes(input[:AESBlockSize], targetBlock)
 	buff := make([]byte, AESBlockSize)
 	maxTries := 1 << 16
-	success := false
 	for t := 0; t < maxTries; t++ {
-		prefixLen := rand.Intn(AESBlockSize)
+		prefixLen := utils.RandIntn(AESBlockSize)
 		prefix := utils.RepBytes(fc, prefixLen)
 		msg := utils.ConcatBytes(prefix, input)
 		iv := utils.RandBytes(AESBlockSize)
 		cipherText := encFunc(msg, iv)
-		for i := 0; i < len(cipherText); i++ {
+		for i := 0; i+AESBlockSize <= len(cipherText); i++ {
 			copy(buff, cipherText[i:i+AESBlockSize])
 			copy(cipherText[i:], utils.XorBytes(buff, diff))
 			if passFunc(cipherText, iv) {
-				success = true
-				break
+				return cipherText, iv, nil
 			}
 			copy(cipherText[i:], buff)
 		}
-		if success {
-			break
-		}
-	}
-	if success {
-		fmt.Println("PWN")
-	} else {
-		fmt.Println("Access Denied")
 	}
+	return nil, nil, ErrAttackFailed
 }
 
-func BreakCTRWithBitFlipping(encFunc func([]byte) []byte, passFunc func([]byte) bool) {
+// BreakCTRWithBitFlipping flips bits in the ciphertext produced by encFunc until
+// passFunc accepts it and returns the forged ciphertext
+func BreakCTRWithBitFlipping(encFunc func([]byte) []byte, passFunc func([]byte) bool) ([]byte, error) {
 	fc := byte('A')
 	msg := utils.RepBytes(fc, 3*AESBlockSize)
 	required := []byte("A;admin=true;k=AAAAAAAAAAAAAAAAAAA")[:AESBlockSize]
 	xorMsg := utils.XorBytes(msg[:AESBlockSize], required)
 	cipherText := encFunc(msg)
 	buff := make([]byte, AESBlockSize)
-	success := false
-	for i := 0; i < len(cipherText); i += AESBlockSize {
+	for i := 0; i+AESBlockSize <= len(cipherText); i += AESBlockSize {
 		copy(buff, cipherText[i:])
 		attack := utils.XorBytes(cipherText[i:i+AESBlockSize], xorMsg)
 		copy(cipherText[i:], attack)
 		if passFunc(cipherText) {
-			success = true
-			break
+			return cipherText, nil
 		}
 		copy(cipherText[i:], buff)
 	}
-	if success {
-		fmt.Println("PWN")
-	} else {
-		fmt.Println("FAILED")
-	}
-}
-
-func breakCBCWithPaddingOracleBlock(cipherText []byte, IV []byte,
-	paddingOracle func([]byte, []byte) bool, n int) []byte {
-	var a []byte
-	if n == 0 {
-		a = IV
-	} else {
-		a = cipherText[(n-1)*AESBlockSize : n*AESBlockSize]
-	}
-	endPos := (n + 1) * AESBlockSize
-	plainText := make([]byte, AESBlockSize)
-	buff := make([]byte, AESBlockSize)
-	targetByte := 1
-	for ; targetByte < AESBlockSize; targetByte++ {
-		pos := AESBlockSize - targetByte
-		suffix := utils.XorBytes(plainText, utils.RepBytes(byte(targetByte), AESBlockSize))
-		suffix = suffix[pos+1:]
-		found := false
-		prefix := utils.RepBytes('A', AESBlockSize-len(suffix))
-		for tryB := 0; tryB < 1<<8; tryB++ {
-			prefix[len(prefix)-1] = byte(tryB)
-			msg := utils.ConcatBytes(prefix, suffix)
-			xorMsg := utils.XorBytes(msg, a)
-			copy(buff, a)
-			copy(a, xorMsg)
-			if paddingOracle(cipherText[:endPos], IV) {
-				plainText[pos] = byte(targetByte) ^ msg[pos]
-				found = true
-			}
-			copy(a, buff)
-			if found {
-				break
-			}
-		}
-		if !found {
-			fmt.Println(targetByte)
-			panic("Could not decode byte at position")
-		}
-	}
-	return plainText
-}
-
-func BreakCBCWithPaddingOracle(cipherText []byte, IV []byte, paddingOracle func([]byte, []byte) bool) []byte {
-	plainTexts := make([][]byte, 0)
-	for i := 0; i < len(cipherText); i += AESBlockSize {
-		block := breakCBCWithPaddingOracleBlock(cipherText, IV, paddingOracle, i/AESBlockSize)
-		plainTexts = append(plainTexts, block)
-	}
-	return utils.ConcatBytes(plainTexts...)
+	return nil, ErrAttackFailed
 }
@@ -2,54 +2,219 @@ package crypto
 
 import (
 	"bytes"
-	crand "crypto/rand"
-	"encoding/binary"
+	"errors"
 	"fmt"
-	"math/rand"
 	"testing"
+
+	"github.com/sukunrt/cryptopals/utils"
 )
 
-func init() {
-	b := make([]byte, 8)
-	crand.Read(b)
-	rand.Seed(int64(binary.BigEndian.Uint64(b)))
+// must returns v and panics on err, for constructors given valid sizes
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
 }
 
 func TestAESEncryptAndAESDecryptINCBCMode(t *testing.T) {
 	tt := "This is some standard plaintext"
-	key := "1234567890123456"
 	iv := "2345678901234567"
-	cipher := NewAESInCBCCipher([]byte(key))
-	encryptedBytes := cipher.Encrypt([]byte(tt), []byte(iv))
-	decryptedBytes := cipher.Decrypt(encryptedBytes, []byte(iv))
-	if string(decryptedBytes) != tt {
-		t.Fatalf("Failed to encrypt or decrypt AES in CBC mode")
+	for _, key := range []string{
+		"1234567890123456",
+		"123456789012345678901234",
+		"12345678901234567890123456789012",
+	} {
+		cipher := must(NewAESInCBCCipher([]byte(key)))
+		encryptedBytes := must(cipher.Encrypt([]byte(tt), []byte(iv)))
+		decryptedBytes := must(cipher.Decrypt(encryptedBytes, []byte(iv)))
+		if string(decryptedBytes) != tt {
+			t.Fatalf("Failed to encrypt or decrypt AES in CBC mode with a %d byte key", len(key))
+		}
 	}
 }
 
-func TestAESOracle(t *testing.T) {
-	var modeUsed Mode
-	encFunc := func(b []byte) []byte {
-		cipherText, mode := randAESEncrypt(b)
-		modeUsed = mode
-		return cipherText
+func TestInvalidKeysAndIVs(t *testing.T) {
+	for _, n := range []int{0, 8, 15, 17, 20, 33, 64} {
+		if _, err := NewAESInCBCCipher(make([]byte, n)); !errors.Is(err, ErrAESKeySize) {
+			t.Fatalf("%d byte key: err = %v", n, err)
+		}
+		if _, err := NewAESInGCMCipher(make([]byte, n)); !errors.Is(err, ErrAESKeySize) {
+			t.Fatalf("gcm: %d byte key: err = %v", n, err)
+		}
+	}
+	if _, err := NewAESInXTSCipher(make([]byte, 40)); !errors.Is(err, ErrAESKeySize) {
+		t.Fatalf("xts: 40 byte key: err = %v", err)
+	}
+	if _, err := NewAESInGCMCipherWithTagSize(RandAESKey(), 2); !errors.Is(err, ErrGCMTagSize) {
+		t.Fatalf("gcm: 2 byte tag: err = %v", err)
+	}
+
+	key := RandAESKey()
+	for _, n := range []int{0, 8, 17} {
+		iv := make([]byte, n)
+		if _, err := must(NewAESInCBCCipher(key)).Encrypt(nil, iv); !errors.Is(err, ErrIVSize) {
+			t.Fatalf("cbc: %d byte IV: err = %v", n, err)
+		}
+		if _, err := must(NewAESInCFBCipher(key)).Decrypt(nil, iv); !errors.Is(err, ErrIVSize) {
+			t.Fatalf("cfb: %d byte IV: err = %v", n, err)
+		}
+		if _, err := must(NewAESInPCBCCipher(key)).Decrypter(iv); !errors.Is(err, ErrIVSize) {
+			t.Fatalf("pcbc: %d byte IV: err = %v", n, err)
+		}
+		if _, err := must(NewAESInCBCCTSCipher(key)).Encrypt(make([]byte, 20), iv); !errors.Is(err, ErrIVSize) {
+			t.Fatalf("cbc-cts: %d byte IV: err = %v", n, err)
+		}
+	}
+	if _, err := NewAESInCTRCipherWithNonce(key, make([]byte, 16)); !errors.Is(err, ErrIVSize) {
+		t.Fatalf("ctr: 16 byte nonce: err = %v", err)
 	}
+	if _, err := must(NewAESInCBCCipher(key)).Decrypt(make([]byte, 20), make([]byte, 16)); !errors.Is(err, ErrPartialBlock) {
+		t.Fatalf("cbc: partial block: err = %v", err)
+	}
+}
+
+func TestDecryptStrict(t *testing.T) {
+	key, iv := RandAESKey(), utils.RandBytes(AESBlockSize)
+	cbc := must(NewAESInCBCCipher(key))
+	ecb := must(NewAESInECBCipher(key))
+	pcbc := must(NewAESInPCBCCipher(key))
+	msg := []byte("This is some standard plaintext, a few blocks long")
+	for _, c := range []struct {
+		name     string
+		enc, dec func([]byte) ([]byte, error)
+	}{
+		{"CBC", func(b []byte) ([]byte, error) { return cbc.Encrypt(b, iv) },
+			func(b []byte) ([]byte, error) { return cbc.DecryptStrict(b, iv) }},
+		{"ECB", func(b []byte) ([]byte, error) { return ecb.Encrypt(b), nil }, ecb.DecryptStrict},
+		{"PCBC", func(b []byte) ([]byte, error) { return pcbc.Encrypt(b, iv) },
+			func(b []byte) ([]byte, error) { return pcbc.DecryptStrict(b, iv) }},
+	} {
+		ct := must(c.enc(msg))
+		if got, err := c.dec(ct); err != nil || !bytes.Equal(got, msg) {
+			t.Fatalf("%s: decrypted to %q, %v", c.name, got, err)
+		}
+		// whole blocks with no padding at all
+		ct = must(c.enc(msg[:2*AESBlockSize]))[:2*AESBlockSize]
+		if _, err := c.dec(ct); !errors.Is(err, utils.ErrInvalidPadding) {
+			t.Fatalf("%s: err = %v", c.name, err)
+		}
+		if _, err := c.dec(ct[:20]); !errors.Is(err, ErrPartialBlock) {
+			t.Fatalf("%s: partial block: err = %v", c.name, err)
+		}
+	}
+}
+
+func TestAESKeyFromSecret(t *testing.T) {
+	for _, size := range []int{16, 24, 32} {
+		k, err := AESKeyFromSecret([]byte{1}, size)
+		if err != nil || len(k) != size {
+			t.Fatalf("%d byte key: got %d bytes, %v", size, len(k), err)
+		}
+		if _, err := NewAESInCBCCipher(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, _ := AESKeyFromSecret([]byte{1}, 16)
+	b, _ := AESKeyFromSecret([]byte{1, 0}, 16)
+	if bytes.Equal(a, b) {
+		t.Fatal("different secrets gave the same key")
+	}
+	if _, err := AESKeyFromSecret([]byte{1}, 20); !errors.Is(err, ErrAESKeySize) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+// detectableMode returns the mode DetectAESMode reports for m. Without the
+// key PCBC looks just like CBC and all the modes that keep the length of the
+// plaintext look like CTR
+func detectableMode(m Mode) Mode {
+	switch m {
+	case PCBC:
+		return CBC
+	case CFB, CFB8, OFB, XTS, CBCCTS:
+		return CTR
+	}
+	return m
+}
+
+func TestAESOracle(t *testing.T) {
 	maxTries := 100
 	for i := 0; i < maxTries; i++ {
+		encFunc, modeUsed := randAESEncrypter()
 		mode := DetectAESMode(encFunc)
-		if mode != modeUsed {
+		if mode != detectableMode(modeUsed) {
 			fmt.Println(mode, modeUsed)
 			t.Fatalf("Mode detection oracle failed")
 		}
 	}
+
+	// sources that always add the same bytes, the first try with CTR is a
+	// whole number of blocks
+	ctr := must(NewAESInCTRCipher(RandAESKey()))
+	cbc := must(NewAESInCBCCipher(RandAESKey()))
+	for want, encFunc := range map[Mode]func([]byte) []byte{
+		CTR: func(b []byte) []byte { return ctr.Encrypt(append(b, "123456789"...)) },
+		CBC: func(b []byte) []byte { return must(cbc.Encrypt(b, make([]byte, AESBlockSize))) },
+		ECB: must(NewAESInECBCipher(RandAESKey())).Encrypt,
+	} {
+		if mode := DetectAESMode(encFunc); mode != want {
+			t.Fatalf("detected %s, want %s", mode, want)
+		}
+	}
 }
 
 func TestBreakSecretInECB(t *testing.T) {
 	key := []byte("YeLLOW SubmariNE")
 	secret := []byte("This is a good secret to test things")
-	encFunc := AESInECBWithSecretEncryptor(key, secret)
-	found := BreakSecretInECB(encFunc)
+	encFunc := must(AESInECBWithSecretEncryptor(key, secret))
+	found, err := BreakSecretInECB(encFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if !bytes.Equal(secret, found) {
 		t.Fatalf("Failed to find secret in ECB")
 	}
 }
+
+func TestBreakSecretInECBRejectsOtherModes(t *testing.T) {
+	secret := []byte("This is a good secret to test things")
+	ctr := must(NewAESInCTRCipher(RandAESKey()))
+	cfb := must(NewAESInCFBCipher(RandAESKey()))
+	cbc := must(NewAESInCBCCipher(RandAESKey()))
+	for _, c := range []struct {
+		name string
+		enc  func([]byte) []byte
+	}{
+		{"CTR", ctr.Encrypt},
+		{"CFB", func(b []byte) []byte { return must(cfb.Encrypt(b, make([]byte, AESBlockSize))) }},
+		{"CBC", func(b []byte) []byte { return must(cbc.Encrypt(b, make([]byte, AESBlockSize))) }},
+	} {
+		encFunc := func(b []byte) []byte { return c.enc(utils.ConcatBytes(b, secret)) }
+		if _, err := BreakSecretInECB(encFunc); !errors.Is(err, ErrNotECB) {
+			t.Fatalf("%s: err = %v, want %v", c.name, err, ErrNotECB)
+		}
+		if _, err := BreakSecretInECBWithRandomPrefix(encFunc); !errors.Is(err, ErrNotECB) {
+			t.Fatalf("%s: random prefix: err = %v, want %v", c.name, err, ErrNotECB)
+		}
+	}
+}
+
+func TestBreakCBCWithBitFlipping(t *testing.T) {
+	cipher := must(NewAESInCBCCipher(RandAESKey()))
+	encFunc := func(b, iv []byte) []byte {
+		cookie := utils.GenerateUserCookie(string(b))
+		return must(cipher.Encrypt([]byte(cookie), iv))
+	}
+	passFunc := func(b, iv []byte) bool {
+		msg := string(must(cipher.Decrypt(b, iv)))
+		return utils.FindKeyInCookie(msg, "admin") == "true" && utils.FindKeyInCookie(msg, "userdata") != ""
+	}
+	cipherText, iv, err := BreakCBCWithBitFlipping(encFunc, passFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !passFunc(cipherText, iv) {
+		t.Fatalf("forged cipher text was not accepted")
+	}
+}
@@ -0,0 +1,466 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+const (
+	CFB    Mode = "CFB"
+	CFB8   Mode = "CFB8"
+	OFB    Mode = "OFB"
+	PCBC   Mode = "PCBC"
+	XTS    Mode = "XTS"
+	CBCCTS Mode = "CBC-CTS"
+)
+
+// ErrShortData is returned by the modes that steal ciphertext when they get
+// less than a block
+var ErrShortData = errors.New("data is shorter than a block")
+
+// AESInCFBCipher encrypts or decrypts bytes with AES in CFB mode. Each
+// segment of plaintext is xored with the encryption of the last 16 bytes of
+// ciphertext, the segment size is 16 bytes for CFB128 and 1 byte for CFB8
+type AESInCFBCipher struct {
+	cipher  cipher.Block
+	key     []byte
+	segment int
+}
+
+// NewAESInCFBCipher returns a CFB128 cipher
+func NewAESInCFBCipher(key []byte) (AESInCFBCipher, error) {
+	c, err := newAESCipher(key)
+	if err != nil {
+		return AESInCFBCipher{}, err
+	}
+	return AESInCFBCipher{cipher: c, key: key, segment: AESBlockSize}, nil
+}
+
+// NewAESInCFB8Cipher returns a CFB8 cipher
+func NewAESInCFB8Cipher(key []byte) (AESInCFBCipher, error) {
+	c, err := newAESCipher(key)
+	if err != nil {
+		return AESInCFBCipher{}, err
+	}
+	return AESInCFBCipher{cipher: c, key: key, segment: 1}, nil
+}
+
+// cfbStream is AESInCFBCipher as a cipher.Stream. reg is the shift
+// register, ks its encryption and seg the ciphertext of the segment in
+// progress, used bytes of it done
+type cfbStream struct {
+	b           cipher.Block
+	reg, ks     []byte
+	seg         []byte
+	used        int
+	decrypt     bool
+	segmentSize int
+}
+
+func newCFBStream(ac AESInCFBCipher, iv []byte, decrypt bool) (*cfbStream, error) {
+	if err := checkIV(iv, AESBlockSize); err != nil {
+		return nil, err
+	}
+	return &cfbStream{
+		b:           ac.cipher,
+		reg:         append([]byte{}, iv...),
+		ks:          make([]byte, AESBlockSize),
+		seg:         make([]byte, ac.segment),
+		decrypt:     decrypt,
+		segmentSize: ac.segment,
+	}, nil
+}
+
+func (s *cfbStream) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("crypto: output smaller than input")
+	}
+	for i, in := range src {
+		if s.used == 0 {
+			s.b.Encrypt(s.ks, s.reg)
+		}
+		out := in ^ s.ks[s.used]
+		if s.decrypt {
+			s.seg[s.used] = in
+		} else {
+			s.seg[s.used] = out
+		}
+		dst[i] = out
+		s.used++
+		if s.used == s.segmentSize {
+			copy(s.reg, s.reg[s.segmentSize:])
+			copy(s.reg[AESBlockSize-s.segmentSize:], s.seg)
+			s.used = 0
+		}
+	}
+}
+
+// Encrypter returns the cipher.Stream encrypting with ac from iv
+func (ac AESInCFBCipher) Encrypter(iv []byte) (cipher.Stream, error) {
+	return newCFBStream(ac, iv, false)
+}
+
+// Decrypter returns the cipher.Stream decrypting with ac from iv
+func (ac AESInCFBCipher) Decrypter(iv []byte) (cipher.Stream, error) {
+	return newCFBStream(ac, iv, true)
+}
+
+// xorStream returns b xored with the keystream of s
+func xorStream(s cipher.Stream, err error, b []byte) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	res := make([]byte, len(b))
+	s.XORKeyStream(res, b)
+	return res, nil
+}
+
+// Encrypt encrypts b with iv, CFB needs no padding
+func (ac AESInCFBCipher) Encrypt(b []byte, iv []byte) ([]byte, error) {
+	s, err := ac.Encrypter(iv)
+	return xorStream(s, err, b)
+}
+
+// Decrypt decrypts b with iv
+func (ac AESInCFBCipher) Decrypt(b []byte, iv []byte) ([]byte, error) {
+	s, err := ac.Decrypter(iv)
+	return xorStream(s, err, b)
+}
+
+// AESInOFBCipher encrypts and decrypts bytes with AES in OFB mode. The
+// keystream is the iv encrypted over and over, independent of the data
+type AESInOFBCipher struct {
+	cipher cipher.Block
+	key    []byte
+}
+
+func NewAESInOFBCipher(key []byte) (AESInOFBCipher, error) {
+	c, err := newAESCipher(key)
+	if err != nil {
+		return AESInOFBCipher{}, err
+	}
+	return AESInOFBCipher{cipher: c, key: key}, nil
+}
+
+// ofbStream is AESInOFBCipher as a cipher.Stream, used bytes of the current
+// keystream block are spent
+type ofbStream struct {
+	b    cipher.Block
+	ks   []byte
+	used int
+}
+
+func (s *ofbStream) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("crypto: output smaller than input")
+	}
+	for i, in := range src {
+		if s.used == AESBlockSize {
+			s.b.Encrypt(s.ks, s.ks)
+			s.used = 0
+		}
+		dst[i] = in ^ s.ks[s.used]
+		s.used++
+	}
+}
+
+// Stream returns the keystream from iv as a cipher.Stream. Encryption and
+// decryption are the same
+func (ac AESInOFBCipher) Stream(iv []byte) (cipher.Stream, error) {
+	if err := checkIV(iv, AESBlockSize); err != nil {
+		return nil, err
+	}
+	return &ofbStream{b: ac.cipher, ks: append([]byte{}, iv...), used: AESBlockSize}, nil
+}
+
+func (ac AESInOFBCipher) Encrypt(b []byte, iv []byte) ([]byte, error) {
+	s, err := ac.Stream(iv)
+	return xorStream(s, err, b)
+}
+
+func (ac AESInOFBCipher) Decrypt(b []byte, iv []byte) ([]byte, error) {
+	return ac.Encrypt(b, iv)
+}
+
+// AESInPCBCCipher encrypts or decrypts bytes with AES in PCBC mode, CBC with
+// the previous plaintext block xored in along with the previous ciphertext
+// block. A changed ciphertext block garbles all the plaintext after it
+type AESInPCBCCipher struct {
+	cipher cipher.Block
+	key    []byte
+}
+
+func NewAESInPCBCCipher(key []byte) (AESInPCBCCipher, error) {
+	c, err := newAESCipher(key)
+	if err != nil {
+		return AESInPCBCCipher{}, err
+	}
+	return AESInPCBCCipher{cipher: c, key: key}, nil
+}
+
+// pcbcMode is AESInPCBCCipher as a cipher.BlockMode, iv is P_i-1 ^ C_i-1
+type pcbcMode struct {
+	b       cipher.Block
+	iv, tmp []byte
+	decrypt bool
+}
+
+func (m *pcbcMode) BlockSize() int { return AESBlockSize }
+
+func (m *pcbcMode) CryptBlocks(dst, src []byte) {
+	checkBlocks(src, dst)
+	for i := 0; i < len(src); i += AESBlockSize {
+		in, out := src[i:i+AESBlockSize], dst[i:i+AESBlockSize]
+		copy(m.tmp, in)
+		if m.decrypt {
+			m.b.Decrypt(out, in)
+			xorInto(out, out, m.iv)
+		} else {
+			xorInto(out, in, m.iv)
+			m.b.Encrypt(out, out)
+		}
+		// tmp holds this block's input, out its output
+		xorInto(m.iv, m.tmp, out)
+	}
+}
+
+func newPCBCMode(b cipher.Block, iv []byte, decrypt bool) (*pcbcMode, error) {
+	if err := checkIV(iv, AESBlockSize); err != nil {
+		return nil, err
+	}
+	return &pcbcMode{b: b, iv: append([]byte{}, iv...), tmp: make([]byte, AESBlockSize), decrypt: decrypt}, nil
+}
+
+// Encrypter returns the cipher.BlockMode encrypting with ac from iv
+func (ac AESInPCBCCipher) Encrypter(iv []byte) (cipher.BlockMode, error) {
+	return newPCBCMode(ac.cipher, iv, false)
+}
+
+// Decrypter returns the cipher.BlockMode decrypting with ac from iv
+func (ac AESInPCBCCipher) Decrypter(iv []byte) (cipher.BlockMode, error) {
+	return newPCBCMode(ac.cipher, iv, true)
+}
+
+// Encrypt pads b and encrypts it with iv
+func (ac AESInPCBCCipher) Encrypt(b []byte, iv []byte) ([]byte, error) {
+	m, err := ac.Encrypter(iv)
+	if err != nil {
+		return nil, err
+	}
+	msg := utils.PadBytes(b, AESBlockSize)
+	m.CryptBlocks(msg, msg)
+	return msg, nil
+}
+
+// decryptPadded decrypts b with iv leaving the padding in place
+func (ac AESInPCBCCipher) decryptPadded(b []byte, iv []byte) ([]byte, error) {
+	m, err := ac.Decrypter(iv)
+	if err != nil {
+		return nil, err
+	}
+	if len(b)%AESBlockSize != 0 {
+		return nil, ErrPartialBlock
+	}
+	plainText := make([]byte, len(b))
+	m.CryptBlocks(plainText, b)
+	return plainText, nil
+}
+
+// Decrypt decrypts b with iv and removes the padding. Invalid padding is left
+// in place, use DecryptStrict to get an error for it
+func (ac AESInPCBCCipher) Decrypt(b []byte, iv []byte) ([]byte, error) {
+	plainText, err := ac.decryptPadded(b, iv)
+	if err != nil {
+		return nil, err
+	}
+	return utils.RemovePad(plainText), nil
+}
+
+// DecryptStrict decrypts b with iv and removes the padding, returning an
+// error wrapping ErrInvalidPadding if it is invalid
+func (ac AESInPCBCCipher) DecryptStrict(b []byte, iv []byte) ([]byte, error) {
+	plainText, err := ac.decryptPadded(b, iv)
+	if err != nil {
+		return nil, err
+	}
+	return utils.Unpad(plainText, AESBlockSize)
+}
+
+// AESInXTSCipher encrypts or decrypts disk sectors with AES in XTS mode. The
+// key is two AES keys of the same size, the second one encrypts the sector
+// number into the tweak that whitens each block and is multiplied by x in
+// GF(2^128) from one block to the next. A sector that isn't whole blocks
+// steals ciphertext from its last whole block
+type AESInXTSCipher struct {
+	k1, k2 cipher.Block
+	key    []byte
+}
+
+// NewAESInXTSCipher returns an XTS cipher for a 32, 48 or 64 byte key
+func NewAESInXTSCipher(key []byte) (AESInXTSCipher, error) {
+	h := len(key) / 2
+	if len(key)%2 != 0 {
+		return AESInXTSCipher{}, fmt.Errorf("xts: %d byte key: %w", len(key), ErrAESKeySize)
+	}
+	k1, err := newAESCipher(key[:h])
+	if err != nil {
+		return AESInXTSCipher{}, fmt.Errorf("xts: %w", err)
+	}
+	k2, err := newAESCipher(key[h:])
+	if err != nil {
+		return AESInXTSCipher{}, fmt.Errorf("xts: %w", err)
+	}
+	return AESInXTSCipher{k1: k1, k2: k2, key: key}, nil
+}
+
+// mulX multiplies the tweak t by x, t is little endian as in IEEE 1619
+func mulX(t []byte) {
+	carry := t[AESBlockSize-1] >> 7
+	for i := AESBlockSize - 1; i > 0; i-- {
+		t[i] = t[i]<<1 | t[i-1]>>7
+	}
+	t[0] = t[0]<<1 ^ 0x87*carry
+}
+
+// xtsBlock runs one block through k1 whitened with the tweak t
+func (ac AESInXTSCipher) xtsBlock(dst, src, t []byte, decrypt bool) {
+	xorInto(dst, src, t)
+	if decrypt {
+		ac.k1.Decrypt(dst, dst)
+	} else {
+		ac.k1.Encrypt(dst, dst)
+	}
+	xorInto(dst, dst, t)
+}
+
+func (ac AESInXTSCipher) crypt(b []byte, sector uint64, decrypt bool) ([]byte, error) {
+	if len(b) < AESBlockSize {
+		return nil, ErrShortData
+	}
+	t := make([]byte, AESBlockSize)
+	binary.LittleEndian.PutUint64(t, sector)
+	ac.k2.Encrypt(t, t)
+
+	res := make([]byte, len(b))
+	full := len(b) / AESBlockSize
+	r := len(b) % AESBlockSize
+	if r != 0 {
+		// the last whole block goes with the partial one
+		full--
+	}
+	for i := 0; i < full; i++ {
+		ac.xtsBlock(res[i*AESBlockSize:], b[i*AESBlockSize:(i+1)*AESBlockSize], t, decrypt)
+		mulX(t)
+	}
+	if r == 0 {
+		return res, nil
+	}
+	// the last whole block and the partial one. Encrypting, the whole block
+	// is done with the current tweak and the tail of its output is stolen to
+	// fill the partial one, done with the next tweak. Decrypting, the tweaks
+	// are used the other way round
+	i := full * AESBlockSize
+	t1, t2 := append([]byte{}, t...), t
+	mulX(t2)
+	if decrypt {
+		t1, t2 = t2, t1
+	}
+	cc := make([]byte, AESBlockSize)
+	ac.xtsBlock(cc, b[i:i+AESBlockSize], t1, decrypt)
+	pp := utils.ConcatBytes(b[i+AESBlockSize:], cc[r:])
+	copy(res[i+AESBlockSize:], cc[:r])
+	ac.xtsBlock(res[i:], pp, t2, decrypt)
+	return res, nil
+}
+
+// Encrypt encrypts the data of sector sector, at least a block of it
+func (ac AESInXTSCipher) Encrypt(b []byte, sector uint64) ([]byte, error) {
+	return ac.crypt(b, sector, false)
+}
+
+// Decrypt decrypts the data of sector sector
+func (ac AESInXTSCipher) Decrypt(b []byte, sector uint64) ([]byte, error) {
+	return ac.crypt(b, sector, true)
+}
+
+// AESInCBCCTSCipher encrypts or decrypts bytes with AES in CBC mode with
+// ciphertext stealing instead of padding, in the CS3 variant of NIST SP
+// 800-38A's addendum used by Kerberos. The last block is zero padded for
+// CBC, then the last two ciphertext blocks are swapped and the final one is
+// cut to the length of the partial block, so the ciphertext is as long as the
+// plaintext
+type AESInCBCCTSCipher struct {
+	cbc AESInCBCCipher
+}
+
+func NewAESInCBCCTSCipher(key []byte) (AESInCBCCTSCipher, error) {
+	cbc, err := NewAESInCBCCipher(key)
+	if err != nil {
+		return AESInCBCCTSCipher{}, err
+	}
+	return AESInCBCCTSCipher{cbc: cbc}, nil
+}
+
+// Encrypt encrypts b, at least a block of it, with iv
+func (ac AESInCBCCTSCipher) Encrypt(b []byte, iv []byte) ([]byte, error) {
+	if len(b) < AESBlockSize {
+		return nil, ErrShortData
+	}
+	r := len(b) % AESBlockSize
+	if r == 0 {
+		r = AESBlockSize
+	}
+	m, err := ac.cbc.Encrypter(iv)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]byte, len(b)-r+AESBlockSize)
+	copy(res, b)
+	m.CryptBlocks(res, res)
+	if len(b) == AESBlockSize {
+		return res, nil
+	}
+	n := len(res)
+	last := append([]byte{}, res[n-AESBlockSize:]...)
+	copy(res[n-AESBlockSize:], res[n-2*AESBlockSize:n-2*AESBlockSize+r])
+	copy(res[n-2*AESBlockSize:], last)
+	return res[:len(b)], nil
+}
+
+// Decrypt decrypts b with iv
+func (ac AESInCBCCTSCipher) Decrypt(b []byte, iv []byte) ([]byte, error) {
+	if len(b) < AESBlockSize {
+		return nil, ErrShortData
+	}
+	m, err := ac.cbc.Decrypter(iv)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == AESBlockSize {
+		res := make([]byte, AESBlockSize)
+		m.CryptBlocks(res, b)
+		return res, nil
+	}
+	r := len(b) % AESBlockSize
+	if r == 0 {
+		r = AESBlockSize
+	}
+	// i is where the swapped whole block starts
+	i := len(b) - r - AESBlockSize
+	d := make([]byte, AESBlockSize)
+	ac.cbc.cipher.Decrypt(d, b[i:i+AESBlockSize])
+	// d is the zero padded last plaintext block xored with the stolen block,
+	// whose tail it gives back
+	stolen := utils.ConcatBytes(b[i+AESBlockSize:], d[r:])
+
+	res := make([]byte, len(b)+AESBlockSize-r)
+	copy(res, b[:i])
+	copy(res[i:], stolen)
+	copy(res[i+AESBlockSize:], b[i:i+AESBlockSize])
+	m.CryptBlocks(res, res)
+	return res[:len(b)], nil
+}
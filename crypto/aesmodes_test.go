@@ -0,0 +1,131 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+// the expected ciphertexts were made with openssl enc and, for XTS, with
+// python's cryptography
+func TestCFBAndOFB(t *testing.T) {
+	key := utils.FromHexString("000102030405060708090a0b0c0d0e0f")
+	iv := utils.FromHexString("0f0e0d0c0b0a09080706050403020100")
+	msg := []byte("hello world, this is a longer message!!")
+	for _, c := range []struct {
+		name     string
+		enc, dec func([]byte, []byte) ([]byte, error)
+		want     string
+	}{
+		{"CFB", must(NewAESInCFBCipher(key)).Encrypt, must(NewAESInCFBCipher(key)).Decrypt,
+			"48cc95fedb6c2c87767398f04cdaf10345611b81ec77378f5116cb735a1efc2e7906d77d9f377f"},
+		{"CFB8", must(NewAESInCFB8Cipher(key)).Encrypt, must(NewAESInCFB8Cipher(key)).Decrypt,
+			"48bee9f3feef6b43590f954e244ced94293ec3ff07174d5c34366026bb5f358204a3ccfec2d346"},
+		{"OFB", must(NewAESInOFBCipher(key)).Encrypt, must(NewAESInOFBCipher(key)).Decrypt,
+			"48cc95fedb6c2c87767398f04cdaf103972e441c670380a9eb6a4ceea7141d2ef8ef7e75524da6"},
+	} {
+		got := must(c.enc(msg, iv))
+		if utils.ToHexString(got) != c.want {
+			t.Fatalf("%s: got %x", c.name, got)
+		}
+		if pt := must(c.dec(got, iv)); !bytes.Equal(pt, msg) {
+			t.Fatalf("%s: decrypted to %q", c.name, pt)
+		}
+	}
+
+	// CFB8 a byte at a time and decrypting in place
+	cfb := must(NewAESInCFB8Cipher(key))
+	ct := make([]byte, len(msg))
+	s := must(cfb.Encrypter(iv))
+	for i := range msg {
+		s.XORKeyStream(ct[i:i+1], msg[i:i+1])
+	}
+	must(cfb.Decrypter(iv)).XORKeyStream(ct, ct)
+	if !bytes.Equal(ct, msg) {
+		t.Fatalf("CFB8 in pieces decrypted to %q", ct)
+	}
+}
+
+func TestPCBC(t *testing.T) {
+	key, iv := RandAESKey(), utils.RandBytes(AESBlockSize)
+	ac := must(NewAESInPCBCCipher(key))
+	msg := []byte("This is some standard plaintext, long enough for a few blocks")
+	ct := must(ac.Encrypt(msg, iv))
+	if got := must(ac.Decrypt(ct, iv)); !bytes.Equal(got, msg) {
+		t.Fatalf("decrypted to %q", got)
+	}
+	// unlike CBC an error in one block garbles every block after it
+	ct[AESBlockSize] ^= 1
+	pt := make([]byte, len(ct))
+	must(ac.Decrypter(iv)).CryptBlocks(pt, ct)
+	if !bytes.Equal(pt[:AESBlockSize], msg[:AESBlockSize]) {
+		t.Fatal("block before the error is garbled")
+	}
+	if bytes.Equal(pt[3*AESBlockSize:], utils.PadBytes(msg, AESBlockSize)[3*AESBlockSize:]) {
+		t.Fatal("last block survived the error")
+	}
+}
+
+func TestXTS(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	ac := must(NewAESInXTSCipher(key))
+	for _, want := range []string{
+		"1a5a022abfdf28123fc98668f8e5378990481a158e96551a4c3794932fcc4dbd",
+		// stolen ciphertext
+		"1a5a022abfdf28123fc98668f8e53789467a74449254dcc879fcca39b2499af090481a158e96551a",
+	} {
+		msg := make([]byte, len(want)/2)
+		for i := range msg {
+			msg[i] = byte(100 + i)
+		}
+		got, err := ac.Encrypt(msg, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if utils.ToHexString(got) != want {
+			t.Fatalf("%d bytes: got %x", len(msg), got)
+		}
+		if pt, err := ac.Decrypt(got, 5); err != nil || !bytes.Equal(pt, msg) {
+			t.Fatalf("%d bytes: decrypted to %x, %v", len(msg), pt, err)
+		}
+		if pt, _ := ac.Decrypt(got, 6); bytes.Equal(pt, msg) {
+			t.Fatal("decrypted with the wrong sector")
+		}
+	}
+	if _, err := ac.Encrypt(make([]byte, 15), 0); !errors.Is(err, ErrShortData) {
+		t.Fatalf("err = %v, want %v", err, ErrShortData)
+	}
+}
+
+func TestCBCCTS(t *testing.T) {
+	key := utils.FromHexString("000102030405060708090a0b0c0d0e0f")
+	iv := make([]byte, AESBlockSize)
+	ac := must(NewAESInCBCCTSCipher(key))
+	// openssl's CS1 output with the last two blocks swapped
+	got, err := ac.Encrypt(make([]byte, 40), iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "c6a13b37878f5b826f4f8162a1c8d8791d2bd041b903bc5fa2dca1378dc1f3e2af9d9926f7dac871"
+	if utils.ToHexString(got) != want {
+		t.Fatalf("got %x", got)
+	}
+	for _, n := range []int{16, 17, 31, 32, 40, 100} {
+		msg := utils.RandBytes(n)
+		ct, err := ac.Encrypt(msg, iv)
+		if err != nil || len(ct) != n {
+			t.Fatalf("%d bytes: %d byte ciphertext, %v", n, len(ct), err)
+		}
+		if pt, err := ac.Decrypt(ct, iv); err != nil || !bytes.Equal(pt, msg) {
+			t.Fatalf("%d bytes: decrypted to %x, %v", n, pt, err)
+		}
+	}
+	if _, err := ac.Decrypt(make([]byte, 15), iv); !errors.Is(err, ErrShortData) {
+		t.Fatalf("err = %v, want %v", err, ErrShortData)
+	}
+}
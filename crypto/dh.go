@@ -3,6 +3,7 @@ package crypto
 import (
 	"bytes"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 
 	bi "github.com/sukunrt/bigint"
@@ -22,7 +23,7 @@ type DH struct {
 
 // randBigInt returns a random integer between x and y
 func randBigInt(x, y bi.Int) bi.Int {
-	return bi.RandInt(y.Sub(x)).Add(x)
+	return RandInt(y.Sub(x)).Add(x)
 }
 
 // NewDH returns a new Diffie Hellman
@@ -102,32 +103,49 @@ type DHGroup struct {
 	O bi.Int
 }
 
+// ErrDHInvalidParams is returned for DH parameters that fail validation
+var ErrDHInvalidParams = errors.New("dh: invalid parameters")
+
+// Validate checks that P is prime and 1 < G < P-1. If the order O is known,
+// i.e. not zero, it also has to be a prime dividing P-1 with G^O = 1
+func (g DHGroup) Validate() error {
+	if !MillerRabin(g.P) {
+		return fmt.Errorf("p is not prime: %w", ErrDHInvalidParams)
+	}
+	if g.G.Cmp(bi.One) <= 0 || g.G.Cmp(g.P.Sub(bi.One)) >= 0 {
+		return fmt.Errorf("g is out of range: %w", ErrDHInvalidParams)
+	}
+	if g.O.Equal(bi.Zero) {
+		return nil
+	}
+	if !MillerRabin(g.O) || !g.P.Sub(bi.One).Mod(g.O).Equal(bi.Zero) || !bi.Exp(g.G, g.O, g.P).Equal(bi.One) {
+		return fmt.Errorf("g doesn't have prime order q: %w", ErrDHInvalidParams)
+	}
+	return nil
+}
+
+// DHSmallSubgroup sends an element of order r for every prime r below mx that
+// divides (p-1)/o exactly once
 func DHSmallSubgroup(p bi.Int, o bi.Int, mx int) chan DHGroup {
 	resCh := make(chan DHGroup)
 	go func() {
 		p1 := p.Sub(bi.One)
 		j := p1.Div(o)
-		for r := bi.Two; r.Int() < mx; r = r.Add(bi.One) {
-			if j.Mod(r).Equal(bi.Zero) && !j.Div(r).Mod(r).Equal(bi.Zero) {
-				double := false
-				for x := bi.Two; x.Mul(x).Cmp(r) <= 0; x = x.Add(bi.One) {
-					if r.Mod(x).Equal(bi.Zero) && r.Div(x).Mod(x).Equal(bi.Zero) {
-						double = true
-						break
-					}
-				}
-				if double {
-					continue
-				}
-				m := p1.Div(r)
-				// We want h such that h^r = 1 => h ^ m != 1
-				for {
-					h := bi.RandInt(p1.Sub(bi.Two)).Add(bi.One)
-					h = bi.Exp(h, m, p)
-					if !h.Equal(bi.One) && !h.Equal(bi.Zero) {
-						resCh <- DHGroup{P: p, G: h, O: r}
-						break
-					}
+		// the primes are tried one at a time rather than with TrialDivision
+		// since the attacks stop reading once they have enough of them
+		for _, pr := range primesBelow(mx) {
+			r := bi.FromInt(pr)
+			if !j.Mod(r).Equal(bi.Zero) || j.Div(r).Mod(r).Equal(bi.Zero) {
+				continue
+			}
+			m := p1.Div(r)
+			// We want h such that h^r = 1 => h ^ m != 1
+			for {
+				h := RandInt(p1.Sub(bi.Two)).Add(bi.One)
+				h = bi.Exp(h, m, p)
+				if !h.Equal(bi.One) && !h.Equal(bi.Zero) {
+					resCh <- DHGroup{P: p, G: h, O: r}
+					break
 				}
 			}
 		}
@@ -142,7 +160,10 @@ type HandshakeMsg struct {
 	PK  bi.Int
 }
 
-func DHSmallSubgroupAttack(p bi.Int, g bi.Int, o bi.Int, handshake func(bi.Int) HandshakeMsg) (bi.Int, bi.Int) {
+// DHSmallSubgroupAttack recovers the private key of the peer behind handshake by
+// sending it elements of small subgroups of Z_p* and matching the returned mac. It
+// returns the key and the modulus it was recovered under
+func DHSmallSubgroupAttack(p bi.Int, g bi.Int, o bi.Int, handshake func(bi.Int) HandshakeMsg) (bi.Int, bi.Int, error) {
 	var rs []bi.Int
 	var ks []bi.Int // y = k mod r
 	rp := bi.One
@@ -162,7 +183,7 @@ OUTER:
 			hash.Reset()
 			_, err := hash.Write(b)
 			if err != nil {
-				panic(err)
+				return bi.Zero, bi.Zero, err
 			}
 			mac := hash.Sum(nil)
 			if bytes.Equal(mac, hm.Mac) {
@@ -177,15 +198,28 @@ OUTER:
 			}
 		}
 		if !found {
-			panic("failed")
+			return bi.Zero, bi.Zero, fmt.Errorf("no mac match in subgroup of order %s: %w", g.O, ErrAttackFailed)
 		}
 	}
-	fmt.Println(rp, o)
 	y := CRT(ks, rs)
-	return y.Mod(p), rp
+	return y.Mod(p), rp, nil
+}
+
+// KangarooFunc finds y such that g^y = target mod p and a <= y <= b, like
+// PollardKangarooDiscreteLog
+type KangarooFunc func(target, a, b, g, p bi.Int) (bi.Int, error)
+
+// DHSmallSubgroupWithPollardKangarooAttack recovers the private key of the peer
+// behind handshake when the small subgroups of Z_p* only give part of the key. The
+// rest is recovered with Pollard's kangaroo algorithm
+func DHSmallSubgroupWithPollardKangarooAttack(p bi.Int, g bi.Int, o bi.Int, handshake func(bi.Int) HandshakeMsg) (bi.Int, error) {
+	return DHSmallSubgroupWithKangarooAttack(p, g, o, handshake, PollardKangarooDiscreteLog)
 }
 
-func DHSmallSubgroupWithPollardKangarooAttack(p bi.Int, g bi.Int, o bi.Int, handshake func(bi.Int) HandshakeMsg) bi.Int {
+// DHSmallSubgroupWithKangarooAttack is DHSmallSubgroupWithPollardKangarooAttack
+// with the kangaroo supplied by the caller, so a faster one like the parallel
+// kangaroo in dlog can be plugged in
+func DHSmallSubgroupWithKangarooAttack(p bi.Int, g bi.Int, o bi.Int, handshake func(bi.Int) HandshakeMsg, kangaroo KangarooFunc) (bi.Int, error) {
 	var rs []bi.Int
 	var ks []bi.Int // y = k mod r
 	rp := bi.One
@@ -207,7 +241,7 @@ OUTER:
 			hash.Reset()
 			_, err := hash.Write(b)
 			if err != nil {
-				panic(err)
+				return bi.Zero, err
 			}
 			mac := hash.Sum(nil)
 			if bytes.Equal(mac, hm.Mac) {
@@ -216,14 +250,13 @@ OUTER:
 				ks = append(ks, i)
 				rp = rp.Mul(g.O)
 				if o.Div(rp).Cmp(bi.FromInt(1<<36)) <= 0 {
-					fmt.Println(rp, o)
 					break OUTER
 				}
 				break
 			}
 		}
 		if !found {
-			panic("failed")
+			return bi.Zero, fmt.Errorf("no mac match in subgroup of order %s: %w", g.O, ErrAttackFailed)
 		}
 	}
 	k := CRT(ks, rs)
@@ -233,11 +266,10 @@ OUTER:
 	yt := bi.Zero
 	if !yy.Equal(bi.One) {
 		var err error
-		fmt.Println(yy, o.Div(rp))
-		yt, err = PollardKangarooDiscreteLog(yy, bi.Zero, o.Div(rp).Add(bi.Ten), gg, p)
+		yt, err = kangaroo(yy, bi.Zero, o.Div(rp).Add(bi.Ten), gg, p)
 		if err != nil {
-			panic(err)
+			return bi.Zero, err
 		}
 	}
-	return k.Add(yt.Mul(rp)).Mod(o)
+	return k.Add(yt.Mul(rp)).Mod(o), nil
 }
@@ -52,7 +52,7 @@ func TestDHSmallSubgroupAttack(t *testing.T) {
 	for i, tt := range tests {
 		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
 			hash := sha256.New()
-			got, m := DHSmallSubgroupAttack(tt.P, tt.G, tt.O, func(gx bi.Int) HandshakeMsg {
+			got, m, err := DHSmallSubgroupAttack(tt.P, tt.G, tt.O, func(gx bi.Int) HandshakeMsg {
 				msg := "Hello World"
 				K := bi.Exp(gx, tt.Y, tt.P)
 				hash.Reset()
@@ -64,6 +64,9 @@ func TestDHSmallSubgroupAttack(t *testing.T) {
 					PK:  K,
 				}
 			})
+			if err != nil {
+				t.Fatal(err)
+			}
 			if !got.Equal(tt.Y) || m.Cmp(tt.O) < 0 {
 				t.Fatalf("priv key mismatch got: %s want: %s", got, tt.Y)
 			}
@@ -89,7 +92,7 @@ func TestDHSmallSubgroupPollardKangarooAttack(t *testing.T) {
 	for i, tt := range tests {
 		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
 			hash := sha256.New()
-			got := DHSmallSubgroupWithPollardKangarooAttack(tt.P, tt.G, tt.O, func(gx bi.Int) HandshakeMsg {
+			got, err := DHSmallSubgroupWithPollardKangarooAttack(tt.P, tt.G, tt.O, func(gx bi.Int) HandshakeMsg {
 				msg := "Hello World"
 				K := bi.Exp(gx, tt.Y, tt.P)
 				hash.Reset()
@@ -101,6 +104,9 @@ func TestDHSmallSubgroupPollardKangarooAttack(t *testing.T) {
 					PK:  bi.Exp(tt.G, tt.Y, tt.P),
 				}
 			})
+			if err != nil {
+				t.Fatal(err)
+			}
 			if !got.Equal(tt.Y) {
 				t.Fatalf("priv key mismatch got: %s want: %s", got, tt.Y)
 			}
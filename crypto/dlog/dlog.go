@@ -0,0 +1,99 @@
+// Package dlog implements discrete logarithm algorithms: baby-step
+// giant-step, Pollard's rho and kangaroo methods and the Pohlig-Hellman
+// reduction. They work in any cyclic group that implements Group.
+package dlog
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	bi "github.com/sukunrt/bigint"
+	"github.com/sukunrt/cryptopals/crypto"
+)
+
+// ErrNotFound is returned when the logarithm isn't in the searched range or
+// a randomised search gave up
+var ErrNotFound = errors.New("dlog: logarithm not found")
+
+// Group is a group written multiplicatively. Key must return the same string
+// for equal elements, it is used for lookup tables and to pseudo-randomly
+// pick the steps of random walks
+type Group[E any] interface {
+	Identity() E
+	Mul(a, b E) E
+	Exp(a E, k bi.Int) E
+	Inverse(a E) E
+	Equal(a, b E) bool
+	Key(a E) string
+}
+
+// ModP is the multiplicative group of integers mod the prime P
+type ModP struct {
+	P bi.Int
+}
+
+func (g ModP) Identity() bi.Int {
+	return bi.One
+}
+
+func (g ModP) Mul(a, b bi.Int) bi.Int {
+	return a.Mul(b).Mod(g.P)
+}
+
+func (g ModP) Exp(a bi.Int, k bi.Int) bi.Int {
+	if k.Cmp(bi.Zero) < 0 {
+		return bi.Exp(g.Inverse(a), bi.Zero.Sub(k), g.P)
+	}
+	return bi.Exp(a, k, g.P)
+}
+
+func (g ModP) Inverse(a bi.Int) bi.Int {
+	return crypto.ModInv(a, g.P)
+}
+
+func (g ModP) Equal(a, b bi.Int) bool {
+	return a.Mod(g.P).Equal(b.Mod(g.P))
+}
+
+func (g ModP) Key(a bi.Int) string {
+	return string(a.Mod(g.P).Bytes())
+}
+
+// hash returns a 64 bit hash of the key of a
+func hash[E any](g Group[E], a E) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(g.Key(a)))
+	return h.Sum64()
+}
+
+// BSGS finds x in [0, n) with base^x = target using Shanks' baby-step
+// giant-step algorithm in O(sqrt(n)) time and memory
+func BSGS[E any](g Group[E], base, target E, n bi.Int) (bi.Int, error) {
+	m := n.Sqrt()
+	if m.Mul(m).Cmp(n) < 0 {
+		m = m.Add(bi.One)
+	}
+	// baby steps: base^j for j < m
+	table := make(map[string]bi.Int)
+	e := g.Identity()
+	for j := bi.Zero; j.Cmp(m) < 0; j = j.Add(bi.One) {
+		k := g.Key(e)
+		if _, ok := table[k]; !ok {
+			table[k] = j
+		}
+		e = g.Mul(e, base)
+	}
+	// giant steps: target * base^(-im)
+	giant := g.Inverse(g.Exp(base, m))
+	y := target
+	for i := bi.Zero; i.Cmp(m) < 0; i = i.Add(bi.One) {
+		if j, ok := table[g.Key(y)]; ok {
+			if x := i.Mul(m).Add(j); x.Cmp(n) < 0 {
+				return x, nil
+			}
+		}
+		y = g.Mul(y, giant)
+	}
+	return bi.Zero, fmt.Errorf("no x below %s: %w", n, ErrNotFound)
+}
@@ -0,0 +1,157 @@
+package dlog
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	bi "github.com/sukunrt/bigint"
+	"github.com/sukunrt/cryptopals/crypto"
+)
+
+// schnorrGroup returns p = 2q + 1 and an element of prime order q
+func schnorrGroup(t *testing.T) (ModP, bi.Int, bi.Int) {
+	t.Helper()
+	// q = 2147483693 and p = 2q + 1 are both prime, 4 is a square so it has order q
+	q := bi.FromInt(2147483693)
+	g := ModP{P: q.Mul(bi.Two).Add(bi.One)}
+	return g, bi.FromInt(4), q
+}
+
+func TestBSGS(t *testing.T) {
+	g, base, q := schnorrGroup(t)
+	x := crypto.RandInt(q)
+	got, err := BSGS[bi.Int](g, base, g.Exp(base, x), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(x) {
+		t.Fatalf("got %s, want %s", got, x)
+	}
+	if _, err := BSGS[bi.Int](g, base, g.Exp(base, bi.FromInt(1000)), bi.FromInt(100)); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("found a log outside the range, err = %v", err)
+	}
+}
+
+func TestRho(t *testing.T) {
+	g, base, q := schnorrGroup(t)
+	for name, cf := range map[string]CycleFinding{"floyd": Floyd, "brent": Brent, "distinguished": DistinguishedPoints} {
+		t.Run(name, func(t *testing.T) {
+			x := crypto.RandInt(q)
+			got, err := Rho[bi.Int](g, base, g.Exp(base, x), q, RhoOptions{CycleFinding: cf})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(x) {
+				t.Fatalf("got %s, want %s", got, x)
+			}
+		})
+	}
+}
+
+func TestKangaroo(t *testing.T) {
+	g, base, q := schnorrGroup(t)
+	a := crypto.RandInt(q.Div(bi.Two))
+	b := a.Add(bi.FromInt(1 << 24))
+	x := a.Add(crypto.RandInt(bi.FromInt(1 << 24)))
+	for name, jumps := range map[string][]bi.Int{"default": nil, "custom": {bi.One, bi.FromInt(10), bi.FromInt(100), bi.FromInt(1000), bi.FromInt(4000), bi.FromInt(9000)}} {
+		t.Run(name, func(t *testing.T) {
+			got, err := Kangaroo[bi.Int](g, base, g.Exp(base, x), a, b, jumps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(x) {
+				t.Fatalf("got %s, want %s", got, x)
+			}
+		})
+	}
+}
+
+func TestFactor(t *testing.T) {
+	// 2^3 * 3 * 65537^2 * 2147483693
+	n := bi.FromInt(8 * 3).Mul(bi.FromInt(65537)).Mul(bi.FromInt(65537)).Mul(bi.FromInt(2147483693))
+	prod := bi.One
+	for _, pp := range Factor(n) {
+		if !crypto.MillerRabin(pp.P) {
+			t.Fatalf("factor %s is not prime", pp.P)
+		}
+		prod = prod.Mul(bi.Exp(pp.P, bi.FromInt(pp.E), bi.Zero))
+	}
+	if !prod.Equal(n) {
+		t.Fatalf("factors multiply to %s, want %s", prod, n)
+	}
+}
+
+func TestPohligHellman(t *testing.T) {
+	// p-1 = 2 * 3 * 5 * 7 * 11 * 13 * 17 * 19 * 23 * 31 and 7 generates Z_p*
+	g := ModP{P: bi.FromInt(6915878971)}
+	base := bi.FromInt(7)
+	order := g.P.Sub(bi.One)
+	x := crypto.RandInt(order)
+	got, err := PohligHellman[bi.Int](g, base, g.Exp(base, x), order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(x) {
+		t.Fatalf("got %s, want %s", got, x)
+	}
+
+	// 2 generates Z_p* for the Schnorr group, whose order has a large prime
+	// factor
+	sg, _, q := schnorrGroup(t)
+	x = crypto.RandInt(q.Mul(bi.Two))
+	got, err = PohligHellman[bi.Int](sg, bi.Two, sg.Exp(bi.Two, x), sg.P.Sub(bi.One))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(x) {
+		t.Fatalf("got %s, want %s", got, x)
+	}
+}
+
+func TestParallelKangaroo(t *testing.T) {
+	g, base, q := schnorrGroup(t)
+	a := crypto.RandInt(q.Div(bi.Two))
+	b := a.Add(bi.FromInt(1 << 30))
+	x := a.Add(crypto.RandInt(bi.FromInt(1 << 30)))
+	var calls atomic.Int32
+	opts := ParallelOptions{
+		Workers:          4,
+		Progress:         func(Progress) { calls.Add(1) },
+		ProgressInterval: time.Millisecond,
+	}
+	got, err := ParallelKangaroo[bi.Int](context.Background(), g, base, g.Exp(base, x), a, b, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(x) {
+		t.Fatalf("got %s, want %s", got, x)
+	}
+	if calls.Load() == 0 {
+		t.Fatal("progress was never reported")
+	}
+}
+
+func TestParallelKangarooCancel(t *testing.T) {
+	g, base, _ := schnorrGroup(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	// 2^40 is outside the interval so the search never ends by itself
+	target := g.Exp(base, bi.FromInt(1<<40))
+	_, err := ParallelKangaroo[bi.Int](ctx, g, base, target, bi.Zero, bi.FromInt(1<<20), ParallelOptions{Workers: 2})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestParallelKangarooMaxSteps(t *testing.T) {
+	g, base, _ := schnorrGroup(t)
+	target := g.Exp(base, bi.FromInt(1<<40))
+	opts := ParallelOptions{Workers: 2, MaxSteps: 1 << 14}
+	_, err := ParallelKangaroo[bi.Int](context.Background(), g, base, target, bi.Zero, bi.FromInt(1<<20), opts)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,93 @@
+package dlog
+
+import (
+	"fmt"
+
+	bi "github.com/sukunrt/bigint"
+)
+
+// DefaultJumps returns the jump table Pollard suggests for an interval of the
+// given width: the powers of 2 up to the point where their mean is about
+// sqrt(width)/2
+func DefaultJumps(width bi.Int) []bi.Int {
+	w := width.Sqrt()
+	jumps := []bi.Int{bi.One}
+	sum := bi.One
+	for k := 1; k < 64 && sum.Mul(bi.Two).Cmp(w.Mul(bi.FromInt(k))) < 0; k++ {
+		j := jumps[k-1].Mul(bi.Two)
+		jumps = append(jumps, j)
+		sum = sum.Add(j)
+	}
+	return jumps
+}
+
+// Kangaroo finds x in [a, b] with base^x = target using Pollard's kangaroo
+// method in O(sqrt(b - a)) time and constant memory. jumps are the jump sizes
+// of the kangaroos, one is picked by the hash of the current element. nil
+// means DefaultJumps(b - a)
+func Kangaroo[E any](g Group[E], base, target E, a, b bi.Int, jumps []bi.Int) (bi.Int, error) {
+	_, x, err := KangarooAny(g, base, []E{target}, a, b, jumps)
+	return x, err
+}
+
+// KangarooAny is Kangaroo with several targets. It finds x in [a, b] with
+// base^x = targets[i] for one of them and returns i and x. The wild
+// kangaroos all run towards the same trap in lockstep, so the tame one only
+// runs once per try and the first target in the interval ends the search
+func KangarooAny[E any](g Group[E], base E, targets []E, a, b bi.Int, jumps []bi.Int) (int, bi.Int, error) {
+	if b.Cmp(a) < 0 {
+		return 0, bi.Zero, fmt.Errorf("empty interval [%s, %s]: %w", a, b, ErrNotFound)
+	}
+	if jumps == nil {
+		jumps = DefaultJumps(b.Sub(a))
+	}
+	k := uint64(len(jumps))
+	steps := make([]E, len(jumps))
+	mean := bi.Zero
+	for i, j := range jumps {
+		steps[i] = g.Exp(base, j)
+		mean = mean.Add(j)
+	}
+	mean = mean.Div(bi.FromInt(len(jumps)))
+	tries := 5
+	for try := 0; try < tries; try++ {
+		// each try shifts which jump goes with which hash
+		f := func(e E) uint64 {
+			return (hash(g, e) + uint64(try)) % k
+		}
+		// the tame kangaroo starts at b and sets a trap where it stops
+		n := mean.Mul(bi.Four)
+		trap := g.Exp(base, b)
+		xT := bi.Zero
+		for i := bi.Zero; i.Cmp(n) < 0; i = i.Add(bi.One) {
+			j := f(trap)
+			xT = xT.Add(jumps[j])
+			trap = g.Mul(trap, steps[j])
+		}
+		// the wild kangaroos start at the targets and either land in the trap
+		// or pass it
+		limit := b.Sub(a).Add(xT)
+		wild := make([]E, len(targets))
+		copy(wild, targets)
+		xW := make([]bi.Int, len(targets))
+		for i := range xW {
+			xW[i] = bi.Zero
+		}
+		for running := len(wild); running > 0; {
+			running = 0
+			for i := range wild {
+				if xW[i].Cmp(limit) > 0 {
+					continue
+				}
+				if g.Equal(wild[i], trap) {
+					return i, b.Add(xT).Sub(xW[i]), nil
+				}
+				j := f(wild[i])
+				xW[i] = xW[i].Add(jumps[j])
+				wild[i] = g.Mul(wild[i], steps[j])
+				running++
+			}
+		}
+	}
+	return 0, bi.Zero, fmt.Errorf("kangaroo missed the trap %d times: %w", tries, ErrNotFound)
+}
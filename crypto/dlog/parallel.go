@@ -0,0 +1,229 @@
+package dlog
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	bi "github.com/sukunrt/bigint"
+	"github.com/sukunrt/cryptopals/crypto"
+)
+
+// Progress is a snapshot of a running ParallelKangaroo search
+type Progress struct {
+	// Steps is the number of jumps made by all kangaroos
+	Steps uint64
+	// Distinguished is the number of distinguished points stored
+	Distinguished int
+	// Respawns is the number of kangaroos restarted after landing on the
+	// trail of a kangaroo of the same kind
+	Respawns uint64
+	Elapsed  time.Duration
+}
+
+// ParallelOptions configures ParallelKangaroo. The zero value is usable
+type ParallelOptions struct {
+	// Workers is the number of goroutines, each runs one tame and one wild
+	// kangaroo. runtime.NumCPU() when zero
+	Workers int
+	// DistinguishedBits is the number of low hash bits that are zero at a
+	// distinguished point. When zero it is picked so that each kangaroo
+	// reports a point about every 2^-4 of its expected path
+	DistinguishedBits int
+	// Progress, if set, is called from its own goroutine every
+	// ProgressInterval while the search runs and once more when it ends
+	Progress         func(Progress)
+	ProgressInterval time.Duration
+	// MaxSteps, when not zero, ends the search with ErrNotFound once all
+	// kangaroos together have made that many jumps
+	MaxSteps uint64
+}
+
+// dpRecord is what a kangaroo reports at a distinguished point. For a tame
+// kangaroo dist is the log of the point, for a wild one the point is
+// target * base^dist
+type dpRecord struct {
+	dist bi.Int
+	tame bool
+}
+
+// ParallelKangaroo finds x in [a, b] with base^x = target using van Oorschot
+// and Wiener's parallel version of Pollard's kangaroo method. Tame kangaroos
+// start at random known logs in the interval and wild ones at random known
+// offsets from target. All of them jump by powers of 2 picked by the hash of
+// the current element and report distinguished points to a shared table.
+// Once a tame and a wild kangaroo hit the same point their paths coincide
+// from there, and the first distinguished point after that gives x. With m
+// kangaroos it takes about 2*sqrt(b-a)/m jumps per kangaroo.
+//
+// The search only stops early when ctx is done, in which case ctx.Err() is
+// returned, or after opts.MaxSteps jumps. A target outside the interval keeps
+// it running until then
+func ParallelKangaroo[E any](ctx context.Context, g Group[E], base, target E, a, b bi.Int, opts ParallelOptions) (bi.Int, error) {
+	if b.Cmp(a) < 0 {
+		return bi.Zero, fmt.Errorf("empty interval [%s, %s]: %w", a, b, ErrNotFound)
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	if opts.ProgressInterval <= 0 {
+		opts.ProgressInterval = time.Second
+	}
+	width := b.Sub(a)
+	m := 2 * opts.Workers
+	// the mean jump should be m*sqrt(width)/4
+	target2 := width.Sqrt().Mul(bi.FromInt(m)).Div(bi.Four)
+	jumps := []bi.Int{bi.One}
+	sum := bi.One
+	for k := 1; k < 64 && sum.Cmp(target2.Mul(bi.FromInt(k))) < 0; k++ {
+		j := jumps[k-1].Mul(bi.Two)
+		jumps = append(jumps, j)
+		sum = sum.Add(j)
+	}
+	steps := make([]E, len(jumps))
+	for i, j := range jumps {
+		steps[i] = g.Exp(base, j)
+	}
+	if opts.DistinguishedBits == 0 {
+		// each kangaroo makes about sqrt(width)/m jumps
+		perKangaroo := width.Sqrt().Div(bi.FromInt(m))
+		opts.DistinguishedBits = perKangaroo.BitLen() - 4
+		if opts.DistinguishedBits < 0 {
+			opts.DistinguishedBits = 0
+		}
+	}
+	mask := uint64(1)<<opts.DistinguishedBits - 1
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	start := time.Now()
+	var (
+		mu       sync.Mutex
+		table    = make(map[string]dpRecord)
+		found    bool
+		result   bi.Int
+		stepCnt  atomic.Uint64
+		respawns atomic.Uint64
+		gaveUp   atomic.Bool
+	)
+	// report stores a distinguished point and returns whether the kangaroo
+	// that found it should be respawned
+	report := func(e E, rec dpRecord) bool {
+		k := g.Key(e)
+		mu.Lock()
+		defer mu.Unlock()
+		prev, ok := table[k]
+		if !ok {
+			table[k] = rec
+			return false
+		}
+		if prev.tame == rec.tame {
+			return true
+		}
+		tame, wild := prev, rec
+		if rec.tame {
+			tame, wild = rec, prev
+		}
+		x := tame.dist.Sub(wild.dist)
+		if !found && x.Cmp(a) >= 0 && x.Cmp(b) <= 0 && g.Equal(g.Exp(base, x), target) {
+			found, result = true, x
+			cancel()
+		}
+		return true
+	}
+
+	type kangaroo struct {
+		pos  E
+		dist bi.Int
+		tame bool
+	}
+	spawn := func(tame bool) kangaroo {
+		if tame {
+			d := a.Add(crypto.RandInt(width.Add(bi.One)))
+			return kangaroo{pos: g.Exp(base, d), dist: d, tame: true}
+		}
+		d := crypto.RandInt(width.Div(bi.Two).Add(bi.One))
+		return kangaroo{pos: g.Mul(target, g.Exp(base, d)), dist: d}
+	}
+
+	snapshot := func() Progress {
+		mu.Lock()
+		n := len(table)
+		mu.Unlock()
+		return Progress{
+			Steps:         stepCnt.Load(),
+			Distinguished: n,
+			Respawns:      respawns.Load(),
+			Elapsed:       time.Since(start),
+		}
+	}
+	var reporter sync.WaitGroup
+	if opts.Progress != nil {
+		reporter.Add(1)
+		go func() {
+			defer reporter.Done()
+			t := time.NewTicker(opts.ProgressInterval)
+			defer t.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-t.C:
+					opts.Progress(snapshot())
+				}
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ks := []kangaroo{spawn(true), spawn(false)}
+			for n := uint64(1); ; n++ {
+				if n%1024 == 0 {
+					if opts.MaxSteps > 0 && stepCnt.Load() >= opts.MaxSteps {
+						gaveUp.Store(true)
+						cancel()
+					}
+					if ctx.Err() != nil {
+						return
+					}
+				}
+				for i := range ks {
+					h := hash(g, ks[i].pos)
+					if h&mask == 0 && report(ks[i].pos, dpRecord{dist: ks[i].dist, tame: ks[i].tame}) {
+						if ctx.Err() != nil {
+							return
+						}
+						respawns.Add(1)
+						ks[i] = spawn(ks[i].tame)
+						continue
+					}
+					// the bits above the distinguished ones pick the jump
+					j := (h >> opts.DistinguishedBits) % uint64(len(jumps))
+					ks[i].pos = g.Mul(ks[i].pos, steps[j])
+					ks[i].dist = ks[i].dist.Add(jumps[j])
+				}
+				stepCnt.Add(uint64(len(ks)))
+			}
+		}()
+	}
+	wg.Wait()
+	cancel()
+	reporter.Wait()
+	if opts.Progress != nil {
+		opts.Progress(snapshot())
+	}
+	if found {
+		return result, nil
+	}
+	if gaveUp.Load() {
+		return bi.Zero, fmt.Errorf("no collision in %d jumps: %w", opts.MaxSteps, ErrNotFound)
+	}
+	return bi.Zero, ctx.Err()
+}
@@ -0,0 +1,77 @@
+package dlog
+
+import (
+	"fmt"
+
+	bi "github.com/sukunrt/bigint"
+	"github.com/sukunrt/cryptopals/crypto"
+)
+
+// PrimePower is a prime factor P of a number along with its exponent E
+type PrimePower struct {
+	P bi.Int
+	E int
+}
+
+// Factor returns the prime factorisation of n > 0 in increasing order of the
+// primes
+func Factor(n bi.Int) []PrimePower {
+	f := crypto.Factor(n)
+	var res []PrimePower
+	for _, p := range f.Primes() {
+		res = append(res, PrimePower{P: p, E: f.Mult(p)})
+	}
+	return res
+}
+
+// bsgsLimit is the largest prime subgroup order PohligHellman solves with
+// BSGS, Rho is used above it to keep the memory down
+var bsgsLimit = bi.FromInt(1 << 40)
+
+// PohligHellman finds x in [0, order) with base^x = target where base has the
+// given order. The order is factored and the logarithm is found in each prime
+// power subgroup one digit at a time, so the work is dominated by the
+// square root of the largest prime factor. The residues are combined with the
+// Chinese remainder theorem
+func PohligHellman[E any](g Group[E], base, target E, order bi.Int) (bi.Int, error) {
+	var xs, ms []bi.Int
+	for _, pp := range Factor(order) {
+		x, err := primePowerLog(g, base, target, order, pp)
+		if err != nil {
+			return bi.Zero, err
+		}
+		xs = append(xs, x)
+		ms = append(ms, bi.Exp(pp.P, bi.FromInt(pp.E), bi.Zero))
+	}
+	x := crypto.CRT(xs, ms)
+	if !g.Equal(g.Exp(base, x), target) {
+		return bi.Zero, fmt.Errorf("target is not a power of base: %w", ErrNotFound)
+	}
+	return x, nil
+}
+
+// primePowerLog returns x mod p^e. With x = x0 + x1 p + ... the digit xk is
+// the log of (target * base^-(x0 + ... + x(k-1) p^(k-1)))^(order/p^(k+1)) to
+// the base base^(order/p), which has order p
+func primePowerLog[E any](g Group[E], base, target E, order bi.Int, pp PrimePower) (bi.Int, error) {
+	gamma := g.Exp(base, order.Div(pp.P))
+	x := bi.Zero
+	pk := bi.One // p^k
+	for k := 0; k < pp.E; k++ {
+		h := g.Mul(target, g.Inverse(g.Exp(base, x)))
+		h = g.Exp(h, order.Div(pk.Mul(pp.P)))
+		var d bi.Int
+		var err error
+		if pp.P.Cmp(bsgsLimit) <= 0 {
+			d, err = BSGS(g, gamma, h, pp.P)
+		} else {
+			d, err = Rho(g, gamma, h, pp.P, RhoOptions{CycleFinding: Brent})
+		}
+		if err != nil {
+			return bi.Zero, fmt.Errorf("subgroup of order %s: %w", pp.P, err)
+		}
+		x = x.Add(d.Mul(pk))
+		pk = pk.Mul(pp.P)
+	}
+	return x, nil
+}
@@ -0,0 +1,181 @@
+package dlog
+
+import (
+	"fmt"
+
+	bi "github.com/sukunrt/bigint"
+	"github.com/sukunrt/cryptopals/crypto"
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+// CycleFinding selects how Rho notices that its walk has collided
+type CycleFinding int
+
+const (
+	// Floyd runs a second walk at twice the speed until they meet
+	Floyd CycleFinding = iota
+	// Brent compares the walk against a saved point that is moved up at
+	// every power of two, with fewer group operations than Floyd
+	Brent
+	// DistinguishedPoints stores the points whose hash has its low bits zero
+	// and restarts the walk at a random point after each one, until a
+	// point is stored twice
+	DistinguishedPoints
+)
+
+// RhoOptions configures Rho. The zero value uses Floyd cycle finding
+type RhoOptions struct {
+	CycleFinding CycleFinding
+	// Partitions is the number of multipliers in the r-adding walk, 20 when
+	// zero
+	Partitions int
+	// DistinguishedBits is the number of low hash bits that have to be zero
+	// for a distinguished point. When zero it is a quarter of the bits of the
+	// order, at most 20
+	DistinguishedBits int
+	// Tries is the number of fresh walks to start before giving up, 8 when
+	// zero
+	Tries int
+}
+
+// state is a point x = base^a * target^b of a rho walk
+type state[E any] struct {
+	x    E
+	a, b bi.Int
+}
+
+// walk is an r-adding walk, each step multiplies by one of the precomputed
+// base^a_i * target^b_i picked by the hash of the current point
+type walk[E any] struct {
+	g     Group[E]
+	order bi.Int
+	ms    []state[E]
+}
+
+func newWalk[E any](g Group[E], base, target E, order bi.Int, r int) walk[E] {
+	w := walk[E]{g: g, order: order, ms: make([]state[E], r)}
+	for i := range w.ms {
+		w.ms[i] = w.random(base, target)
+	}
+	return w
+}
+
+// random returns a random point of the walk
+func (w walk[E]) random(base, target E) state[E] {
+	a, b := crypto.RandInt(w.order), crypto.RandInt(w.order)
+	return state[E]{x: w.g.Mul(w.g.Exp(base, a), w.g.Exp(target, b)), a: a, b: b}
+}
+
+func (w walk[E]) step(s state[E]) state[E] {
+	m := w.ms[hash(w.g, s.x)%uint64(len(w.ms))]
+	return state[E]{
+		x: w.g.Mul(s.x, m.x),
+		a: s.a.Add(m.a).Mod(w.order),
+		b: s.b.Add(m.b).Mod(w.order),
+	}
+}
+
+// solve returns x with base^x = target from two representations of the same
+// point. a1 + x*b1 = a2 + x*b2 mod order
+func (w walk[E]) solve(s1, s2 state[E]) (bi.Int, bool) {
+	db := s1.b.Sub(s2.b).Mod(w.order)
+	if db.Equal(bi.Zero) {
+		return bi.Zero, false
+	}
+	return s2.a.Sub(s1.a).Mul(crypto.ModInv(db, w.order)).Mod(w.order), true
+}
+
+// Rho finds x with base^x = target using Pollard's rho method, where base has
+// prime order. It runs in O(sqrt(order)) time and constant memory, except
+// with distinguished points which store about sqrt(order)/2^bits of them
+func Rho[E any](g Group[E], base, target E, order bi.Int, opts RhoOptions) (bi.Int, error) {
+	if opts.Partitions == 0 {
+		opts.Partitions = 20
+	}
+	if opts.Tries == 0 {
+		opts.Tries = 8
+	}
+	if opts.DistinguishedBits == 0 {
+		opts.DistinguishedBits = utils.MinInt(order.BitLen()/4, 20)
+	}
+	if g.Equal(target, g.Identity()) {
+		return bi.Zero, nil
+	}
+	// a walk that hasn't collided after this many steps is unlucky
+	limit := order.Sqrt().Mul(bi.FromInt(16)).Add(bi.FromInt(1 << 10))
+	for try := 0; try < opts.Tries; try++ {
+		w := newWalk(g, base, target, order, opts.Partitions)
+		var s1, s2 state[E]
+		var ok bool
+		switch opts.CycleFinding {
+		case Floyd:
+			s1, s2, ok = w.floyd(w.random(base, target), limit)
+		case Brent:
+			s1, s2, ok = w.brent(w.random(base, target), limit)
+		case DistinguishedPoints:
+			s1, s2, ok = w.distinguished(base, target, opts.DistinguishedBits, limit)
+		default:
+			return bi.Zero, fmt.Errorf("dlog: unknown cycle finding %d", opts.CycleFinding)
+		}
+		if !ok {
+			continue
+		}
+		if x, ok := w.solve(s1, s2); ok && g.Equal(g.Exp(base, x), target) {
+			return x, nil
+		}
+	}
+	return bi.Zero, fmt.Errorf("rho gave up after %d walks: %w", opts.Tries, ErrNotFound)
+}
+
+func (w walk[E]) floyd(start state[E], limit bi.Int) (state[E], state[E], bool) {
+	tortoise, hare := w.step(start), w.step(w.step(start))
+	for i := bi.Zero; i.Cmp(limit) < 0; i = i.Add(bi.One) {
+		if w.g.Equal(tortoise.x, hare.x) {
+			return tortoise, hare, true
+		}
+		tortoise, hare = w.step(tortoise), w.step(w.step(hare))
+	}
+	return tortoise, hare, false
+}
+
+func (w walk[E]) brent(start state[E], limit bi.Int) (state[E], state[E], bool) {
+	saved, cur := start, w.step(start)
+	power, lam := 1, 1
+	for i := bi.Zero; i.Cmp(limit) < 0; i = i.Add(bi.One) {
+		if w.g.Equal(saved.x, cur.x) {
+			return saved, cur, true
+		}
+		if power == lam {
+			saved = cur
+			power *= 2
+			lam = 0
+		}
+		cur = w.step(cur)
+		lam++
+	}
+	return saved, cur, false
+}
+
+func (w walk[E]) distinguished(base, target E, bits int, limit bi.Int) (state[E], state[E], bool) {
+	mask := uint64(1)<<bits - 1
+	// a single walk longer than this is probably stuck in a cycle without a
+	// distinguished point
+	maxLen := 20 << bits
+	seen := make(map[string]state[E])
+	for total := bi.Zero; total.Cmp(limit) < 0; {
+		s := w.random(base, target)
+		for i := 0; i < maxLen; i++ {
+			if hash(w.g, s.x)&mask == 0 {
+				k := w.g.Key(s.x)
+				if prev, ok := seen[k]; ok && !prev.b.Equal(s.b) {
+					return prev, s, true
+				}
+				seen[k] = s
+				break
+			}
+			s = w.step(s)
+			total = total.Add(bi.One)
+		}
+	}
+	return state[E]{}, state[E]{}, false
+}
@@ -1,14 +1,21 @@
 package crypto
 
 import (
+	"crypto/sha1"
+	"crypto/sha256"
 	"errors"
+	"fmt"
 	"hash"
 
 	bi "github.com/sukunrt/bigint"
 	"github.com/sukunrt/cryptopals/utils"
 )
 
-var ErrInvalidLen = errors.New("invalid bit length")
+var (
+	ErrInvalidLen       = errors.New("invalid bit length")
+	ErrDSAInvalidParams = errors.New("dsa: invalid parameters")
+	ErrDSAInvalidKey    = errors.New("dsa: invalid key")
+)
 
 type DSAParams struct {
 	P, Q, G bi.Int
@@ -21,17 +28,67 @@ type DSAPerUserParams struct {
 	H hash.Hash
 }
 
+// Validate checks that P and Q are primes with Q dividing P-1 and that G
+// generates the subgroup of order Q
+func (d DSAParams) Validate() error {
+	if !MillerRabin(d.P) || !MillerRabin(d.Q) {
+		return fmt.Errorf("p or q is not prime: %w", ErrDSAInvalidParams)
+	}
+	if !d.P.Sub(bi.One).Mod(d.Q).Equal(bi.Zero) {
+		return fmt.Errorf("q doesn't divide p-1: %w", ErrDSAInvalidParams)
+	}
+	if d.G.Cmp(bi.One) <= 0 || d.G.Cmp(d.P) >= 0 || !bi.Exp(d.G, d.Q, d.P).Equal(bi.One) {
+		return fmt.Errorf("g doesn't have order q: %w", ErrDSAInvalidParams)
+	}
+	return nil
+}
+
+// Validate checks the parameters, that Y is in the subgroup and, unless X is
+// zero as it is for a public key, that Y = G^X
+func (d DSAPerUserParams) Validate() error {
+	if err := d.DSAParams.Validate(); err != nil {
+		return err
+	}
+	if d.Y.Cmp(bi.One) <= 0 || d.Y.Cmp(d.P) >= 0 || !bi.Exp(d.Y, d.Q, d.P).Equal(bi.One) {
+		return fmt.Errorf("y is not in the subgroup: %w", ErrDSAInvalidKey)
+	}
+	if d.X.Equal(bi.Zero) {
+		return nil
+	}
+	if d.X.Cmp(d.Q) >= 0 || !bi.Exp(d.G, d.X, d.P).Equal(d.Y) {
+		return fmt.Errorf("y != g^x: %w", ErrDSAInvalidKey)
+	}
+	return nil
+}
+
+// DefaultHash returns the hash FIPS 186 pairs with a Q of this size, SHA-1
+// for 160 bits, SHA-224 for 224 bits and SHA-256 otherwise
+func (d DSAParams) DefaultHash() hash.Hash {
+	switch n := d.Q.BitLen(); {
+	case n <= 160:
+		return sha1.New()
+	case n <= 224:
+		return sha256.New224()
+	}
+	return sha256.New()
+}
+
+// GenKey returns a random key pair signing hashes made with hash, the
+// DefaultHash if it is nil
 func (d DSAParams) GenKey(hash hash.Hash) DSAPerUserParams {
-	x := bi.RandInt(d.Q.Sub(bi.One))
+	if hash == nil {
+		hash = d.DefaultHash()
+	}
+	x := RandInt(d.Q.Sub(bi.One))
 	y := bi.Exp(d.G, x, d.P)
 	return DSAPerUserParams{DSAParams: d, X: x, Y: y, H: hash}
 }
 
 func (d DSAPerUserParams) Sign(b []byte) (bi.Int, bi.Int) {
-	hi := d.getHash(b)
+	hi := d.Digest(b)
 	var r, s bi.Int
 	for {
-		k := bi.RandInt(d.Q.Sub(bi.One))
+		k := RandInt(d.Q.Sub(bi.One))
 		r = bi.Exp(d.G, k, d.P).Mod(d.Q)
 		if r.Equal(bi.Zero) {
 			continue
@@ -46,7 +103,7 @@ func (d DSAPerUserParams) Sign(b []byte) (bi.Int, bi.Int) {
 }
 
 func (d DSAPerUserParams) SignWithK(b []byte, k bi.Int) (bi.Int, bi.Int) {
-	hi := d.getHash(b)
+	hi := d.Digest(b)
 	var r, s bi.Int
 	r = bi.Exp(d.G, k, d.P).Mod(d.Q)
 	s = ModInv(k, d.Q).Mul(hi.Add(d.X.Mul(r))).Mod(d.Q)
@@ -54,18 +111,33 @@ func (d DSAPerUserParams) SignWithK(b []byte, k bi.Int) (bi.Int, bi.Int) {
 }
 
 func (d DSAPerUserParams) Verify(b []byte, r, s bi.Int) bool {
-	hi := d.getHash(b)
+	hi := d.Digest(b)
 	w := ModInv(s, d.Q)
 	u1 := hi.Mul(w).Mod(d.Q)
 	u2 := r.Mul(w).Mod(d.Q)
-	v := bi.Exp(d.G, u1, d.P).Mul(bi.Exp(d.Y, u2, d.P)).Mod(d.Q)
+	v := bi.Exp(d.G, u1, d.P).Mul(bi.Exp(d.Y, u2, d.P)).Mod(d.P).Mod(d.Q)
 	return v.Equal(r)
 }
 
-func (d DSAPerUserParams) getHash(b []byte) bi.Int {
+// Digest returns the hash of b as an integer, the h that goes into a
+// signature. It is the leftmost bits of the hash, as many as Q has
+func (d DSAPerUserParams) Digest(b []byte) bi.Int {
 	d.H.Reset()
 	d.H.Write(b)
-	return bi.FromBytes(d.H.Sum(nil))
+	return HashToInt(d.H.Sum(nil), d.Q)
+}
+
+// HashToInt returns the leftmost n.BitLen() bits of h as an integer, the way
+// DSA and ECDSA shorten hashes longer than the group order
+func HashToInt(h []byte, n bi.Int) bi.Int {
+	if nb := (n.BitLen() + 7) / 8; len(h) > nb {
+		h = h[:nb]
+	}
+	hi := bi.FromBytes(h)
+	if extra := 8*len(h) - n.BitLen(); extra > 0 {
+		hi = hi.Div(bi.Exp(bi.Two, bi.FromInt(extra), bi.Zero))
+	}
+	return hi
 }
 
 // GenerateDSAParams generates p, q and g for DSA with SHA256 as the has function
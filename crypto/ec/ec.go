@@ -0,0 +1,332 @@
+// Package ec implements elliptic curve arithmetic on short Weierstrass and
+// Montgomery curves, ECDH and ECDSA on top of it and the set 8 attacks on
+// them
+package ec
+
+import (
+	"errors"
+
+	bi "github.com/sukunrt/bigint"
+	"github.com/sukunrt/cryptopals/crypto"
+)
+
+var (
+	ErrNotOnCurve        = errors.New("point is not on the curve")
+	ErrPointAtInfinity   = errors.New("point is the point at infinity")
+	ErrPointOutOfRange   = errors.New("point coordinates are not reduced mod p")
+	ErrInvalidPointOrder = errors.New("point is not in the subgroup generated by the base point")
+)
+
+// Point is an affine point on a Weierstrass curve. The point at infinity
+// has Inf set and no coordinates
+type Point struct {
+	X, Y bi.Int
+	Inf  bool
+}
+
+// Infinity is the identity of the curve group
+var Infinity = Point{Inf: true}
+
+// Equal reports whether p and q are the same point
+func (p Point) Equal(q Point) bool {
+	if p.Inf || q.Inf {
+		return p.Inf == q.Inf
+	}
+	return p.X.Equal(q.X) && p.Y.Equal(q.Y)
+}
+
+func (p Point) String() string {
+	if p.Inf {
+		return "(inf)"
+	}
+	return "(" + p.X.String() + ", " + p.Y.String() + ")"
+}
+
+// JacobianPoint is a point in Jacobian projective coordinates, it stands for
+// the affine point (X/Z^2, Y/Z^3). Z is zero for the point at infinity
+type JacobianPoint struct {
+	X, Y, Z bi.Int
+}
+
+// Curve is the short Weierstrass curve y^2 = x^3 + ax + b over GF(P) with a
+// base point G of prime order N. The curve has N*H points
+type Curve struct {
+	Name    string
+	P, A, B bi.Int
+	G       Point
+	N       bi.Int
+	H       bi.Int
+}
+
+// Order returns the number of points on the curve
+func (c Curve) Order() bi.Int {
+	return c.N.Mul(c.H)
+}
+
+// WithB returns the curve with b replaced. Addition never looks at b so
+// points of the new curve can be fed to code expecting c
+func (c Curve) WithB(b bi.Int) Curve {
+	c.B = b.Mod(c.P)
+	return c
+}
+
+// rhs returns x^3 + ax + b
+func (c Curve) rhs(x bi.Int) bi.Int {
+	return x.Mul(x).Mul(x).Add(c.A.Mul(x)).Add(c.B).Mod(c.P)
+}
+
+// IsOnCurve reports whether p satisfies the curve equation
+func (c Curve) IsOnCurve(p Point) bool {
+	if p.Inf {
+		return true
+	}
+	return p.Y.Mul(p.Y).Mod(c.P).Equal(c.rhs(p.X))
+}
+
+// Validate checks that p is a usable public key: a finite point on the curve,
+// with reduced coordinates, in the subgroup generated by G
+func (c Curve) Validate(p Point) error {
+	if p.Inf {
+		return ErrPointAtInfinity
+	}
+	if p.X.Cmp(bi.Zero) < 0 || p.X.Cmp(c.P) >= 0 || p.Y.Cmp(bi.Zero) < 0 || p.Y.Cmp(c.P) >= 0 {
+		return ErrPointOutOfRange
+	}
+	if !c.IsOnCurve(p) {
+		return ErrNotOnCurve
+	}
+	if !c.ScalarMult(p, c.N).Inf {
+		return ErrInvalidPointOrder
+	}
+	return nil
+}
+
+// YFromX returns a y with (x, y) on the curve and whether there is one
+func (c Curve) YFromX(x bi.Int) (bi.Int, bool) {
+	return crypto.ModSqrt(c.rhs(x), c.P)
+}
+
+// RandomPoint returns a random finite point on the curve
+func (c Curve) RandomPoint() Point {
+	for {
+		x := crypto.RandInt(c.P)
+		if y, ok := c.YFromX(x); ok {
+			return Point{X: x, Y: y}
+		}
+	}
+}
+
+// Neg returns -p
+func (c Curve) Neg(p Point) Point {
+	if p.Inf {
+		return p
+	}
+	return Point{X: p.X, Y: c.P.Sub(p.Y).Mod(c.P)}
+}
+
+// Add returns p + q using affine coordinates
+func (c Curve) Add(p, q Point) Point {
+	if p.Inf {
+		return q
+	}
+	if q.Inf {
+		return p
+	}
+	var m bi.Int
+	if p.X.Equal(q.X) {
+		if p.Y.Add(q.Y).Mod(c.P).Equal(bi.Zero) {
+			return Infinity
+		}
+		// tangent at p
+		num := bi.Three.Mul(p.X).Mul(p.X).Add(c.A)
+		m = num.Mul(crypto.ModInv(bi.Two.Mul(p.Y), c.P)).Mod(c.P)
+	} else {
+		num := q.Y.Sub(p.Y).Mod(c.P)
+		m = num.Mul(crypto.ModInv(q.X.Sub(p.X).Mod(c.P), c.P)).Mod(c.P)
+	}
+	x := m.Mul(m).Sub(p.X).Sub(q.X).Mod(c.P)
+	y := m.Mul(p.X.Sub(x)).Sub(p.Y).Mod(c.P)
+	return Point{X: x, Y: y}
+}
+
+// Double returns 2p using affine coordinates
+func (c Curve) Double(p Point) Point {
+	return c.Add(p, p)
+}
+
+// ToJacobian returns p in Jacobian coordinates
+func (c Curve) ToJacobian(p Point) JacobianPoint {
+	if p.Inf {
+		return JacobianPoint{X: bi.One, Y: bi.One, Z: bi.Zero}
+	}
+	return JacobianPoint{X: p.X, Y: p.Y, Z: bi.One}
+}
+
+// FromJacobian returns the affine point for p
+func (c Curve) FromJacobian(p JacobianPoint) Point {
+	if p.Z.Equal(bi.Zero) {
+		return Infinity
+	}
+	zi := crypto.ModInv(p.Z, c.P)
+	zi2 := zi.Mul(zi).Mod(c.P)
+	return Point{
+		X: p.X.Mul(zi2).Mod(c.P),
+		Y: p.Y.Mul(zi2).Mul(zi).Mod(c.P),
+	}
+}
+
+// JacobianDouble returns 2p without any inversions
+func (c Curve) JacobianDouble(p JacobianPoint) JacobianPoint {
+	if p.Z.Equal(bi.Zero) || p.Y.Equal(bi.Zero) {
+		return JacobianPoint{X: bi.One, Y: bi.One, Z: bi.Zero}
+	}
+	y2 := p.Y.Mul(p.Y).Mod(c.P)
+	s := bi.Four.Mul(p.X).Mul(y2).Mod(c.P)
+	z2 := p.Z.Mul(p.Z).Mod(c.P)
+	m := bi.Three.Mul(p.X).Mul(p.X).Add(c.A.Mul(z2).Mul(z2)).Mod(c.P)
+	x := m.Mul(m).Sub(bi.Two.Mul(s)).Mod(c.P)
+	y := m.Mul(s.Sub(x)).Sub(bi.Eight.Mul(y2).Mul(y2)).Mod(c.P)
+	z := bi.Two.Mul(p.Y).Mul(p.Z).Mod(c.P)
+	return JacobianPoint{X: x, Y: y, Z: z}
+}
+
+// JacobianAdd returns p + q without any inversions
+func (c Curve) JacobianAdd(p, q JacobianPoint) JacobianPoint {
+	if p.Z.Equal(bi.Zero) {
+		return q
+	}
+	if q.Z.Equal(bi.Zero) {
+		return p
+	}
+	pz2 := p.Z.Mul(p.Z).Mod(c.P)
+	qz2 := q.Z.Mul(q.Z).Mod(c.P)
+	u1 := p.X.Mul(qz2).Mod(c.P)
+	u2 := q.X.Mul(pz2).Mod(c.P)
+	s1 := p.Y.Mul(qz2).Mul(q.Z).Mod(c.P)
+	s2 := q.Y.Mul(pz2).Mul(p.Z).Mod(c.P)
+	if u1.Equal(u2) {
+		if !s1.Equal(s2) {
+			return JacobianPoint{X: bi.One, Y: bi.One, Z: bi.Zero}
+		}
+		return c.JacobianDouble(p)
+	}
+	h := u2.Sub(u1).Mod(c.P)
+	r := s2.Sub(s1).Mod(c.P)
+	h2 := h.Mul(h).Mod(c.P)
+	h3 := h2.Mul(h).Mod(c.P)
+	x := r.Mul(r).Sub(h3).Sub(bi.Two.Mul(u1).Mul(h2)).Mod(c.P)
+	y := r.Mul(u1.Mul(h2).Sub(x)).Sub(s1.Mul(h3)).Mod(c.P)
+	z := h.Mul(p.Z).Mul(q.Z).Mod(c.P)
+	return JacobianPoint{X: x, Y: y, Z: z}
+}
+
+// ScalarMult returns k*p for k >= 0. It works in Jacobian coordinates and
+// converts back to affine once at the end
+func (c Curve) ScalarMult(p Point, k bi.Int) Point {
+	res := c.ToJacobian(Infinity)
+	jp := c.ToJacobian(p)
+	for _, b := range k.Text(2) {
+		res = c.JacobianDouble(res)
+		if b == '1' {
+			res = c.JacobianAdd(res, jp)
+		}
+	}
+	return c.FromJacobian(res)
+}
+
+// ScalarBaseMult returns k*G
+func (c Curve) ScalarBaseMult(k bi.Int) Point {
+	return c.ScalarMult(c.G, k)
+}
+
+// GenKey returns a random private key and its public key
+func (c Curve) GenKey() (bi.Int, Point) {
+	d := crypto.RandInt(c.N.Sub(bi.One)).Add(bi.One)
+	return d, c.ScalarBaseMult(d)
+}
+
+// Group is the group of points of C, written multiplicatively so the
+// algorithms in dlog can work in it
+type Group struct {
+	C Curve
+}
+
+func (g Group) Identity() Point {
+	return Infinity
+}
+
+func (g Group) Mul(a, b Point) Point {
+	return g.C.Add(a, b)
+}
+
+func (g Group) Exp(a Point, k bi.Int) Point {
+	if k.Cmp(bi.Zero) < 0 {
+		return g.C.ScalarMult(g.C.Neg(a), bi.Zero.Sub(k))
+	}
+	return g.C.ScalarMult(a, k)
+}
+
+func (g Group) Inverse(a Point) Point {
+	return g.C.Neg(a)
+}
+
+func (g Group) Equal(a, b Point) bool {
+	return a.Equal(b)
+}
+
+// Key returns the coordinates padded to the size of P, or nothing for the
+// point at infinity
+func (g Group) Key(a Point) string {
+	if a.Inf {
+		return ""
+	}
+	n := (g.C.P.BitLen() + 7) / 8
+	b := make([]byte, 2*n)
+	x, y := a.X.Bytes(), a.Y.Bytes()
+	copy(b[n-len(x):n], x)
+	copy(b[2*n-len(y):], y)
+	return string(b)
+}
+
+func mustInt(s string, base int) bi.Int {
+	x, ok := bi.FromString(s, base)
+	if !ok {
+		panic("invalid constant " + s)
+	}
+	return x
+}
+
+// P256 returns the NIST P-256 curve
+func P256() Curve {
+	p := mustInt("ffffffff00000001000000000000000000000000ffffffffffffffffffffffff", 16)
+	return Curve{
+		Name: "P-256",
+		P:    p,
+		A:    p.Sub(bi.Three),
+		B:    mustInt("5ac635d8aa3a93e7b3ebbd55769886bc651d06b0cc53b0f63bce3c3e27d2604b", 16),
+		G: Point{
+			X: mustInt("6b17d1f2e12c4247f8bce6e563a440f277037d812deb33a0f4a13945d898c296", 16),
+			Y: mustInt("4fe342e2fe1a7f9b8ee7eb4a7c0f9e162bce33576b315ececbb6406837bf51f5", 16),
+		},
+		N: mustInt("ffffffff00000000ffffffffffffffffbce6faada7179e84f3b9cac2fc632551", 16),
+		H: bi.One,
+	}
+}
+
+// ToyCurve returns the curve y^2 = x^3 - 95051x + 11279326 used in the set 8
+// challenges
+func ToyCurve() Curve {
+	p := mustInt("233970423115425145524320034830162017933", 10)
+	return Curve{
+		Name: "cryptopals",
+		P:    p,
+		A:    p.Sub(bi.FromInt(95051)),
+		B:    bi.FromInt(11279326),
+		G: Point{
+			X: bi.FromInt(182),
+			Y: mustInt("85518893674295321206118380980485522083", 10),
+		},
+		N: mustInt("29246302889428143187362802287225875743", 10),
+		H: bi.Eight,
+	}
+}
@@ -0,0 +1,167 @@
+package ec
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"errors"
+	"testing"
+
+	bi "github.com/sukunrt/bigint"
+	"github.com/sukunrt/cryptopals/crypto"
+	"github.com/sukunrt/cryptopals/crypto/dlog"
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+func TestP256MatchesStdlib(t *testing.T) {
+	c := P256()
+	for i := 0; i < 5; i++ {
+		d, pub := c.GenKey()
+		key := make([]byte, 32)
+		copy(key[32-len(d.Bytes()):], d.Bytes())
+		priv, err := ecdh.P256().NewPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := priv.PublicKey().Bytes()
+		got := make([]byte, 65)
+		got[0] = 4
+		copy(got[33-len(pub.X.Bytes()):33], pub.X.Bytes())
+		copy(got[65-len(pub.Y.Bytes()):], pub.Y.Bytes())
+		if !bytes.Equal(got, want) {
+			t.Fatalf("d*G = %x, want %x", got, want)
+		}
+	}
+}
+
+func TestCurveOrder(t *testing.T) {
+	for _, c := range []Curve{P256(), ToyCurve(), ToyMontgomeryCurve().Weierstrass()} {
+		if !c.IsOnCurve(c.G) {
+			t.Fatalf("%s: base point is not on the curve", c.Name)
+		}
+		if !c.ScalarBaseMult(c.N).Inf {
+			t.Fatalf("%s: N*G is not the point at infinity", c.Name)
+		}
+		if err := c.Validate(c.G); err != nil {
+			t.Fatalf("%s: base point doesn't validate: %v", c.Name, err)
+		}
+	}
+}
+
+func TestAffineAndJacobianAgree(t *testing.T) {
+	c := ToyCurve()
+	p := c.G
+	for k := 1; k < 50; k++ {
+		if got := c.ScalarBaseMult(bi.FromInt(k)); !got.Equal(p) {
+			t.Fatalf("%d*G = %s, want %s", k, got, p)
+		}
+		p = c.Add(p, c.G)
+	}
+	a, b := crypto.RandInt(c.N), crypto.RandInt(c.N)
+	lhs := c.Add(c.ScalarBaseMult(a), c.ScalarBaseMult(b))
+	if rhs := c.ScalarBaseMult(a.Add(b)); !lhs.Equal(rhs) {
+		t.Fatalf("aG + bG != (a+b)G")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := ToyCurve()
+	if err := c.Validate(Point{X: c.G.X, Y: c.G.Y.Add(bi.One)}); !errors.Is(err, ErrNotOnCurve) {
+		t.Fatalf("expected ErrNotOnCurve, got %v", err)
+	}
+	if err := c.Validate(Infinity); !errors.Is(err, ErrPointAtInfinity) {
+		t.Fatalf("expected ErrPointAtInfinity, got %v", err)
+	}
+	// the cofactor part of a random point has order dividing 8
+	for {
+		p := c.ScalarMult(c.RandomPoint(), c.N)
+		if p.Inf {
+			continue
+		}
+		if err := c.Validate(p); !errors.Is(err, ErrInvalidPointOrder) {
+			t.Fatalf("expected ErrInvalidPointOrder, got %v", err)
+		}
+		break
+	}
+}
+
+func TestCurve25519MatchesStdlib(t *testing.T) {
+	c := Curve25519()
+	reverse := func(b []byte) []byte {
+		res := make([]byte, len(b))
+		for i := range b {
+			res[len(b)-1-i] = b[i]
+		}
+		return res
+	}
+	for i := 0; i < 5; i++ {
+		priv, err := ecdh.X25519().NewPrivateKey(utils.RandBytes(32))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// X25519 clamps the little endian scalar before the ladder
+		k := reverse(priv.Bytes())
+		k[0] = k[0]&127 | 64
+		k[31] &= 248
+		got := c.Ladder(c.U, bi.FromBytes(k))
+		want := bi.FromBytes(reverse(priv.PublicKey().Bytes()))
+		if !got.Equal(want) {
+			t.Fatalf("ladder = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestToyMontgomeryMatchesWeierstrass(t *testing.T) {
+	mc := ToyMontgomeryCurve()
+	c := ToyCurve()
+	w := mc.Weierstrass()
+	if !w.A.Equal(c.A) || !w.B.Equal(c.B) || !w.G.X.Equal(c.G.X) {
+		t.Fatalf("weierstrass form of the montgomery curve is not the toy curve")
+	}
+	k := crypto.RandInt(c.N)
+	p := c.ScalarBaseMult(k)
+	u, _ := mc.FromWeierstrass(p)
+	if got := mc.Ladder(mc.U, k); !got.Equal(u) {
+		t.Fatalf("ladder = %s, want %s", got, u)
+	}
+	if err := mc.Validate(mc.U); err != nil {
+		t.Fatalf("base point doesn't validate: %v", err)
+	}
+	if mc.TwistOrder().String() != "233970423115425145549737651362517029924" {
+		t.Fatalf("twist order = %s", mc.TwistOrder())
+	}
+}
+
+func TestTwist(t *testing.T) {
+	mc := ToyMontgomeryCurve()
+	tc := mc.Twist()
+	tw := tc.Weierstrass()
+	for i := 0; i < 5; i++ {
+		p := tw.RandomPoint()
+		u, _ := tc.FromWeierstrass(p)
+		if !errors.Is(mc.Validate(u), ErrOnTwist) {
+			t.Fatalf("twist point %s validates on the curve", u)
+		}
+		if !mc.Ladder(u, tc.N).Equal(bi.Zero) {
+			t.Fatalf("twist order doesn't kill %s", u)
+		}
+		k := crypto.RandInt(tc.N)
+		if got, want := mc.Ladder(u, k), tc.uOf(tw.ScalarMult(p, k)); !got.Equal(want) {
+			t.Fatalf("ladder on the twist = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestGroupKangaroo(t *testing.T) {
+	c := ToyCurve()
+	a := crypto.RandInt(c.N)
+	b := a.Add(bi.FromInt(1 << 24))
+	k := a.Add(crypto.RandInt(bi.FromInt(1 << 24)))
+	decoy := c.ScalarBaseMult(crypto.RandInt(c.N))
+	i, kk, err := dlog.KangarooAny[Point](Group{C: c}, c.G, []Point{decoy, c.ScalarBaseMult(k)}, a, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i != 1 || !kk.Equal(k) {
+		t.Fatalf("found log %s of target %d, want %s of target 1", kk, i, k)
+	}
+}
@@ -0,0 +1,233 @@
+package ec
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	bi "github.com/sukunrt/bigint"
+	"github.com/sukunrt/cryptopals/crypto"
+	"github.com/sukunrt/cryptopals/crypto/dlog"
+)
+
+// HandshakeMsg is what an ECDH peer sends back after receiving our public
+// key: a message authenticated with the shared secret, and its public key
+type HandshakeMsg struct {
+	Msg string
+	Mac []byte
+	PK  Point
+}
+
+// ECDHMac returns the mac of msg under the shared point k. Both coordinates
+// are hashed so that k and -k give different macs
+func ECDHMac(k Point, msg string) []byte {
+	h := sha256.New()
+	if !k.Inf {
+		h.Write(k.X.Bytes())
+		h.Write(k.Y.Bytes())
+	}
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
+
+// InvalidCurve is a curve that differs from the real one only in b, along
+// with the number of points on it
+type InvalidCurve struct {
+	B     bi.Int
+	Order bi.Int
+}
+
+// InvalidCurveAttack recovers the private key of the peer behind
+// handshake, which doesn't check that the public keys it gets are on c. Point
+// addition never uses b, so points of small prime order r on the invalid
+// curves make the shared secret one of r points and the mac tells us which.
+// It returns the key and the modulus it was recovered under
+func InvalidCurveAttack(c Curve, invalid []InvalidCurve, handshake func(Point) HandshakeMsg) (bi.Int, bi.Int, error) {
+	var rs []bi.Int
+	var ks []bi.Int // d = k mod r
+	rp := bi.One
+	for _, ic := range invalid {
+		ec := c.WithB(ic.B)
+	FACTORS:
+		for _, r := range smallPrimeFactors(ic.Order, 1<<16) {
+			for _, rr := range rs {
+				if rr.Equal(r) {
+					continue FACTORS
+				}
+			}
+			h := ec.pointOfOrder(ic.Order, r)
+			hm := handshake(h)
+			found := false
+			k := Infinity
+			for i := bi.Zero; i.Cmp(r) < 0; i = i.Add(bi.One) {
+				if bytes.Equal(ECDHMac(k, hm.Msg), hm.Mac) {
+					rs = append(rs, r)
+					ks = append(ks, i)
+					rp = rp.Mul(r)
+					found = true
+					break
+				}
+				k = ec.Add(k, h)
+			}
+			if !found {
+				return bi.Zero, bi.Zero, fmt.Errorf("no mac match in subgroup of order %s: %w", r, crypto.ErrAttackFailed)
+			}
+			if rp.Cmp(c.N) > 0 {
+				return crypto.CRT(ks, rs), rp, nil
+			}
+		}
+	}
+	return crypto.CRT(ks, rs), rp, fmt.Errorf("invalid curves only cover %s: %w", rp, crypto.ErrAttackFailed)
+}
+
+// smallPrimeFactors returns the distinct primes below bound that divide n
+func smallPrimeFactors(n bi.Int, bound int) []bi.Int {
+	f, _ := crypto.TrialDivision(n, bound)
+	return f.Primes()
+}
+
+// pointOfOrder returns a point of prime order r on c, which has order points.
+// The r part of the group need not be cyclic so a random point times order/r
+// can be infinity for every choice of point. Instead the whole power of r is
+// removed from the order and the result multiplied by r until it has order r
+func (c Curve) pointOfOrder(order, r bi.Int) Point {
+	q := order
+	for q.Mod(r).Equal(bi.Zero) {
+		q = q.Div(r)
+	}
+	for {
+		h := c.ScalarMult(c.RandomPoint(), q)
+		if h.Inf {
+			continue
+		}
+		for hr := c.ScalarMult(h, r); !hr.Inf; hr = c.ScalarMult(h, r) {
+			h = hr
+		}
+		return h
+	}
+}
+
+// ToyInvalidCurves returns the curves from challenge 59 that share everything
+// but b with ToyCurve
+func ToyInvalidCurves() []InvalidCurve {
+	return []InvalidCurve{
+		{B: bi.FromInt(210), Order: mustInt("233970423115425145550826547352470124412", 10)},
+		{B: bi.FromInt(504), Order: mustInt("233970423115425145544350131142039591210", 10)},
+		{B: bi.FromInt(727), Order: mustInt("233970423115425145545378039958152057148", 10)},
+	}
+}
+
+// MontgomeryMac returns the mac of msg under the shared u coordinate k, the
+// way x-only ECDH peers compute it
+func MontgomeryMac(k bi.Int, msg string) []byte {
+	h := sha256.New()
+	h.Write(k.Bytes())
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
+
+// uOf returns the u coordinate of a point on the Weierstrass form of c. The
+// point at infinity is 0, like in Ladder
+func (c MontgomeryCurve) uOf(pt Point) bi.Int {
+	if pt.Inf {
+		return bi.Zero
+	}
+	u, _ := c.FromWeierstrass(pt)
+	return u
+}
+
+// matchMontgomeryMac returns the i in [0, r/2] with u(i*h) matching the mac in
+// hm. u(i*h) = u(-i*h) so that's all of them up to sign
+func matchMontgomeryMac(c MontgomeryCurve, w Curve, h Point, r bi.Int, hm crypto.HandshakeMsg) (bi.Int, bool) {
+	k := Infinity
+	for i := bi.Zero; i.Cmp(r.Div(bi.Two)) <= 0; i = i.Add(bi.One) {
+		if bytes.Equal(MontgomeryMac(c.uOf(k), hm.Msg), hm.Mac) {
+			return i, true
+		}
+		k = w.Add(k, h)
+	}
+	return bi.Zero, false
+}
+
+// MontgomeryTwistAttack recovers the private key of the x-only ECDH peer
+// behind handshake, which runs Ladder on any u it gets. u coordinates that
+// aren't on c are on its twist, which has subgroups of every prime order r
+// below bound that divides its order.
+//
+// The u coordinate can't tell k from -k so a single subgroup only gives the key
+// up to sign. To line the signs up a point of the order of all the subgroups
+// so far is sent too, and only one of the two ways of combining the residues
+// matches its mac. It returns x and the modulus m with d = ±x mod m
+func MontgomeryTwistAttack(c MontgomeryCurve, bound int, handshake func(bi.Int) crypto.HandshakeMsg) (bi.Int, bi.Int, error) {
+	tc := c.Twist()
+	tw := tc.Weierstrass()
+	x, rp := bi.Zero, bi.One
+	hp := Infinity // has order rp
+	for _, r := range smallPrimeFactors(tc.N, bound) {
+		if r.Equal(bi.Two) {
+			// the point of order 2 has u = 0, the same as the point at infinity
+			continue
+		}
+		h := tw.pointOfOrder(tc.N, r)
+		k, ok := matchMontgomeryMac(tc, tw, h, r, handshake(tc.uOf(h)))
+		if !ok {
+			return bi.Zero, bi.Zero, fmt.Errorf("no mac match in subgroup of order %s: %w", r, crypto.ErrAttackFailed)
+		}
+		if rp.Equal(bi.One) {
+			x, rp, hp = k, r, h
+			continue
+		}
+		hp = tw.Add(hp, h)
+		hm := handshake(tc.uOf(hp))
+		found := false
+		for _, kk := range []bi.Int{k, r.Sub(k).Mod(r)} {
+			y := crypto.CRT([]bi.Int{x, kk}, []bi.Int{rp, r})
+			if bytes.Equal(MontgomeryMac(tc.uOf(tw.ScalarMult(hp, y)), hm.Msg), hm.Mac) {
+				x, found = y, true
+				break
+			}
+		}
+		if !found {
+			return bi.Zero, bi.Zero, fmt.Errorf("no sign of the residue mod %s matches: %w", r, crypto.ErrAttackFailed)
+		}
+		rp = rp.Mul(r)
+	}
+	if rp.Equal(bi.One) {
+		return bi.Zero, bi.Zero, fmt.Errorf("twist has no subgroups of order below %d: %w", bound, crypto.ErrAttackFailed)
+	}
+	return x, rp, nil
+}
+
+// MontgomeryTwistWithKangarooAttack recovers the private key of the x-only
+// ECDH peer behind handshake. The twist gives the key up to sign mod the
+// product of its small subgroup orders and the rest comes from Pollard's
+// kangaroo on c. d and N - d have the same public key and shared secrets, so
+// the result is either of them
+func MontgomeryTwistWithKangarooAttack(c MontgomeryCurve, handshake func(bi.Int) crypto.HandshakeMsg) (bi.Int, error) {
+	x, rp, err := MontgomeryTwistAttack(c, 1<<24, handshake)
+	if err != nil {
+		return bi.Zero, err
+	}
+	pk := handshake(c.U).PK
+	v, ok := c.VFromU(pk)
+	if !ok {
+		return bi.Zero, fmt.Errorf("public key %s: %w", pk, ErrOnTwist)
+	}
+	w := c.Weierstrass()
+	// the lift of pk is e*G for e = d or N - d, and d = ±x mod rp, so e is
+	// one of these mod rp
+	y := c.ToWeierstrass(pk, v)
+	residues := []bi.Int{x, rp.Sub(x), c.N.Sub(x), c.N.Add(x)}
+	targets := make([]Point, len(residues))
+	for i, r := range residues {
+		residues[i] = r.Mod(rp)
+		// y - r*G = m*rp*G with 0 <= m <= N/rp
+		targets[i] = w.Add(y, w.Neg(w.ScalarBaseMult(residues[i])))
+	}
+	g := w.ScalarMult(w.G, rp)
+	i, m, err := dlog.KangarooAny[Point](Group{C: w}, g, targets, bi.Zero, c.N.Div(rp), nil)
+	if err != nil {
+		return bi.Zero, err
+	}
+	return residues[i].Add(m.Mul(rp)), nil
+}
@@ -0,0 +1,59 @@
+package ec
+
+import (
+	"testing"
+
+	bi "github.com/sukunrt/bigint"
+	"github.com/sukunrt/cryptopals/crypto"
+)
+
+func TestToyInvalidCurveOrders(t *testing.T) {
+	c := ToyCurve()
+	for _, ic := range ToyInvalidCurves() {
+		ec := c.WithB(ic.B)
+		if !ec.ScalarMult(ec.RandomPoint(), ic.Order).Inf {
+			t.Fatalf("b = %s: order*P is not the point at infinity", ic.B)
+		}
+	}
+}
+
+func TestInvalidCurveAttack(t *testing.T) {
+	c := ToyCurve()
+	d, _ := c.GenKey()
+	handshake := func(pk Point) HandshakeMsg {
+		msg := "crazy flamboyant for the rap enjoyment"
+		return HandshakeMsg{Msg: msg, Mac: ECDHMac(c.ScalarMult(pk, d), msg)}
+	}
+	dd, m, err := InvalidCurveAttack(c, ToyInvalidCurves(), handshake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Cmp(c.N) <= 0 {
+		t.Fatalf("recovered the key mod %s, want a modulus larger than %s", m, c.N)
+	}
+	if !dd.Equal(d) {
+		t.Fatalf("recovered %s, want %s", dd, d)
+	}
+	if !c.ScalarBaseMult(dd).Equal(c.ScalarBaseMult(d)) {
+		t.Fatal("public keys differ")
+	}
+}
+
+func TestMontgomeryTwistAttack(t *testing.T) {
+	c := ToyMontgomeryCurve()
+	d := crypto.RandInt(c.N)
+	handshake := func(u bi.Int) crypto.HandshakeMsg {
+		msg := "crazy flamboyant for the rap enjoyment"
+		return crypto.HandshakeMsg{Msg: msg, Mac: MontgomeryMac(c.Ladder(u, d), msg), PK: c.Ladder(c.U, d)}
+	}
+	x, m, err := MontgomeryTwistAttack(c, 1<<12, handshake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.String() != "375859649" {
+		t.Fatalf("recovered the key mod %s, want 11*107*197*1621", m)
+	}
+	if r := d.Mod(m); !r.Equal(x) && !r.Equal(m.Sub(x)) {
+		t.Fatalf("d = %s mod %s, want ±%s", r, m, x)
+	}
+}
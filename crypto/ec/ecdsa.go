@@ -0,0 +1,86 @@
+package ec
+
+import (
+	"hash"
+
+	bi "github.com/sukunrt/bigint"
+	"github.com/sukunrt/cryptopals/crypto"
+)
+
+// ECDSAPerUserParams is an ECDSA key pair on C
+type ECDSAPerUserParams struct {
+	C Curve
+	D bi.Int // private key
+	Q Point  // public key
+	H hash.Hash
+}
+
+// GenECDSAKey returns a random ECDSA key pair on c that signs hashes made
+// with hash
+func (c Curve) GenECDSAKey(hash hash.Hash) ECDSAPerUserParams {
+	d, q := c.GenKey()
+	return ECDSAPerUserParams{C: c, D: d, Q: q, H: hash}
+}
+
+func (e ECDSAPerUserParams) Sign(b []byte) (bi.Int, bi.Int) {
+	for {
+		k := crypto.RandInt(e.C.N.Sub(bi.One)).Add(bi.One)
+		r, s := e.SignWithK(b, k)
+		if r.Equal(bi.Zero) || s.Equal(bi.Zero) {
+			continue
+		}
+		return r, s
+	}
+}
+
+func (e ECDSAPerUserParams) SignWithK(b []byte, k bi.Int) (bi.Int, bi.Int) {
+	hi := e.Digest(b)
+	r := e.C.ScalarBaseMult(k).X.Mod(e.C.N)
+	s := crypto.ModInv(k, e.C.N).Mul(hi.Add(e.D.Mul(r))).Mod(e.C.N)
+	return r, s
+}
+
+func (e ECDSAPerUserParams) Verify(b []byte, r, s bi.Int) bool {
+	n := e.C.N
+	if r.Cmp(bi.One) < 0 || r.Cmp(n) >= 0 || s.Cmp(bi.One) < 0 || s.Cmp(n) >= 0 {
+		return false
+	}
+	u1, u2 := e.verifyScalars(b, r, s)
+	p := e.C.Add(e.C.ScalarBaseMult(u1), e.C.ScalarMult(e.Q, u2))
+	return !p.Inf && p.X.Mod(n).Equal(r)
+}
+
+// verifyScalars returns u1 = H(b)/s and u2 = r/s, the signature checks out when
+// u1*G + u2*Q has x coordinate r
+func (e ECDSAPerUserParams) verifyScalars(b []byte, r, s bi.Int) (bi.Int, bi.Int) {
+	w := crypto.ModInv(s, e.C.N)
+	return e.Digest(b).Mul(w).Mod(e.C.N), r.Mul(w).Mod(e.C.N)
+}
+
+// Digest returns the hash of b as an integer truncated to the bit length of
+// N, the h that goes into a signature
+func (e ECDSAPerUserParams) Digest(b []byte) bi.Int {
+	e.H.Reset()
+	e.H.Write(b)
+	return crypto.HashToInt(e.H.Sum(nil), e.C.N)
+}
+
+// ECDSAKeySelectionAttack returns a key pair, on the same curve with a
+// different base point, under which the signature (r, s) by pk on b also
+// verifies. This is the duplicate signature key selection attack: verification
+// only checks that u1*G + u2*Q = R, so picking a private key d' and
+// G' = R / (u1 + u2*d') makes u1*G' + u2*d'*G' = R too
+func ECDSAKeySelectionAttack(pk ECDSAPerUserParams, b []byte, r, s bi.Int) ECDSAPerUserParams {
+	c := pk.C
+	u1, u2 := pk.verifyScalars(b, r, s)
+	R := c.Add(c.ScalarBaseMult(u1), c.ScalarMult(pk.Q, u2))
+	for {
+		d := crypto.RandInt(c.N.Sub(bi.One)).Add(bi.One)
+		t := u1.Add(u2.Mul(d)).Mod(c.N)
+		if t.Equal(bi.Zero) {
+			continue
+		}
+		c.G = c.ScalarMult(R, crypto.ModInv(t, c.N))
+		return ECDSAPerUserParams{C: c, D: d, Q: c.ScalarBaseMult(d), H: pk.H}
+	}
+}
@@ -0,0 +1,54 @@
+package ec
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	bi "github.com/sukunrt/bigint"
+)
+
+func TestECDSAMatchesStdlib(t *testing.T) {
+	key := P256().GenECDSAKey(sha256.New())
+	pub := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(key.Q.X.Bytes()),
+		Y:     new(big.Int).SetBytes(key.Q.Y.Bytes()),
+	}
+	msg := []byte("hello world")
+	digest := sha256.Sum256(msg)
+	r, s := key.Sign(msg)
+	if !ecdsa.Verify(pub, digest[:], new(big.Int).SetBytes(r.Bytes()), new(big.Int).SetBytes(s.Bytes())) {
+		t.Fatal("stdlib rejects our signature")
+	}
+	priv := &ecdsa.PrivateKey{PublicKey: *pub, D: new(big.Int).SetBytes(key.D.Bytes())}
+	sr, ss, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Verify(msg, bi.FromBigInt(sr), bi.FromBigInt(ss)) {
+		t.Fatal("stdlib signature rejected")
+	}
+	if key.Verify([]byte("hello world!"), r, s) {
+		t.Fatal("signature verifies for a different message")
+	}
+}
+
+func TestECDSAKeySelectionAttack(t *testing.T) {
+	key := ToyCurve().GenECDSAKey(sha256.New())
+	msg := []byte("hello world")
+	r, s := key.Sign(msg)
+	evil := ECDSAKeySelectionAttack(key, msg, r, s)
+	if evil.Q.Equal(key.Q) {
+		t.Fatal("attack returned the original key")
+	}
+	if !evil.Verify(msg, r, s) {
+		t.Fatal("signature doesn't verify under the chosen key")
+	}
+	if !evil.C.ScalarBaseMult(evil.D).Equal(evil.Q) {
+		t.Fatal("chosen private key doesn't match its public key")
+	}
+}
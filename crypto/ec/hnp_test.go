@@ -0,0 +1,40 @@
+package ec
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	bi "github.com/sukunrt/bigint"
+	"github.com/sukunrt/cryptopals/crypto"
+)
+
+func TestBiasedNonceAttackECDSA(t *testing.T) {
+	c := ToyCurve()
+	key := c.GenECDSAKey(sha256.New())
+	l := 8
+	scale := bi.Exp(bi.Two, bi.FromInt(l), bi.Zero)
+	var sigs []crypto.SignatureSample
+	for i := 0; i < 20; i++ {
+		msg := []byte(fmt.Sprintf("message %d", i))
+		k := crypto.RandInt(c.N.Div(scale).Sub(bi.One)).Add(bi.One).Mul(scale)
+		r, s := key.SignWithK(msg, k)
+		if !key.Verify(msg, r, s) {
+			t.Fatal("biased signature doesn't verify")
+		}
+		sigs = append(sigs, crypto.SignatureSample{H: key.Digest(msg), R: r, S: s})
+	}
+	isKey := func(d bi.Int) bool {
+		return c.ScalarBaseMult(d).Equal(key.Q)
+	}
+	d, err := crypto.BiasedNonceAttack(sigs, c.N, l, isKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Equal(key.D) {
+		t.Fatalf("recovered %s, want %s", d, key.D)
+	}
+	if _, err := crypto.BiasedNonceAttack(sigs[:2], c.N, l, isKey); err == nil {
+		t.Fatal("recovered the key from 2 signatures")
+	}
+}
@@ -0,0 +1,181 @@
+package ec
+
+import (
+	"errors"
+
+	bi "github.com/sukunrt/bigint"
+	"github.com/sukunrt/cryptopals/crypto"
+)
+
+// ErrOnTwist is returned when a u coordinate belongs to the quadratic twist
+// of a Montgomery curve instead of the curve itself
+var ErrOnTwist = errors.New("u coordinate is on the twist")
+
+// MontgomeryCurve is the curve Bv^2 = u^3 + Au^2 + u over GF(P). Points are
+// handled by their u coordinate alone. U is the base point, of prime order N,
+// and the curve has N*H points
+type MontgomeryCurve struct {
+	Name    string
+	P, A, B bi.Int
+	U       bi.Int
+	N       bi.Int
+	H       bi.Int
+}
+
+// Order returns the number of points on the curve
+func (c MontgomeryCurve) Order() bi.Int {
+	return c.N.Mul(c.H)
+}
+
+// TwistOrder returns the number of points on the quadratic twist. A curve and
+// its twist have 2p + 2 points between them
+func (c MontgomeryCurve) TwistOrder() bi.Int {
+	return c.P.Mul(bi.Two).Add(bi.Two).Sub(c.Order())
+}
+
+// Twist returns the quadratic twist dBv^2 = u^3 + Au^2 + u of c, where d is
+// the smallest non square mod P. Its points are the u coordinates that fail
+// Validate on c and Ladder works on them unchanged. The twist has no large
+// subgroup of prime order, so U is the point (0, 0) of order 2 and N is the
+// whole order
+func (c MontgomeryCurve) Twist() MontgomeryCurve {
+	d := bi.Two
+	for {
+		if _, ok := crypto.ModSqrt(d, c.P); !ok {
+			break
+		}
+		d = d.Add(bi.One)
+	}
+	return MontgomeryCurve{
+		Name: c.Name + " (twist)",
+		P:    c.P,
+		A:    c.A,
+		B:    c.B.Mul(d).Mod(c.P),
+		U:    bi.Zero,
+		N:    c.TwistOrder(),
+		H:    bi.One,
+	}
+}
+
+// rhs returns (u^3 + Au^2 + u) / B
+func (c MontgomeryCurve) rhs(u bi.Int) bi.Int {
+	r := u.Mul(u).Mul(u).Add(c.A.Mul(u).Mul(u)).Add(u).Mod(c.P)
+	return r.Mul(crypto.ModInv(c.B, c.P)).Mod(c.P)
+}
+
+// IsOnCurve reports whether (u, v) satisfies the curve equation
+func (c MontgomeryCurve) IsOnCurve(u, v bi.Int) bool {
+	return v.Mul(v).Mod(c.P).Equal(c.rhs(u))
+}
+
+// VFromU returns a v with (u, v) on the curve and whether there is one. When
+// there isn't, u is a point on the twist
+func (c MontgomeryCurve) VFromU(u bi.Int) (bi.Int, bool) {
+	return crypto.ModSqrt(c.rhs(u), c.P)
+}
+
+// Validate checks that u is the u coordinate of a point on the curve and not
+// on its twist. x-only arithmetic can't tell the two apart by itself
+func (c MontgomeryCurve) Validate(u bi.Int) error {
+	if u.Cmp(bi.Zero) < 0 || u.Cmp(c.P) >= 0 {
+		return ErrPointOutOfRange
+	}
+	if _, ok := c.VFromU(u); !ok {
+		return ErrOnTwist
+	}
+	return nil
+}
+
+// Ladder returns the u coordinate of k*(u, v) with the Montgomery ladder. It
+// never looks at v, so it happily computes on the twist when u is not on the
+// curve. The point at infinity comes out as 0
+func (c MontgomeryCurve) Ladder(u, k bi.Int) bi.Int {
+	p := c.P
+	u2, w2 := bi.One, bi.Zero
+	u3, w3 := u, bi.One
+	kbits := k.Text(2)
+	for i := 0; i < p.BitLen(); i++ {
+		// walk the bits of k from the top, padded to the size of p
+		b := byte('0')
+		if j := i - (p.BitLen() - len(kbits)); j >= 0 {
+			b = kbits[j]
+		}
+		if b == '1' {
+			u2, u3 = u3, u2
+			w2, w3 = w3, w2
+		}
+		t1 := u2.Mul(u3).Sub(w2.Mul(w3)).Mod(p)
+		t2 := u2.Mul(w3).Sub(w2.Mul(u3)).Mod(p)
+		u3, w3 = t1.Mul(t1).Mod(p), u.Mul(t2).Mul(t2).Mod(p)
+		uu, ww, uw := u2.Mul(u2), w2.Mul(w2), u2.Mul(w2)
+		t3 := uu.Sub(ww).Mod(p)
+		u2, w2 = t3.Mul(t3).Mod(p), bi.Four.Mul(uw).Mul(uu.Add(c.A.Mul(uw)).Add(ww)).Mod(p)
+		if b == '1' {
+			u2, u3 = u3, u2
+			w2, w3 = w3, w2
+		}
+	}
+	return u2.Mul(bi.Exp(w2, p.Sub(bi.Two), p)).Mod(p)
+}
+
+// Weierstrass returns the short Weierstrass curve isomorphic to c. Use
+// ToWeierstrass and FromWeierstrass to move points between the two
+func (c MontgomeryCurve) Weierstrass() Curve {
+	p := c.P
+	a2 := c.A.Mul(c.A).Mod(p)
+	b2 := c.B.Mul(c.B).Mod(p)
+	// a = (3 - A^2) / 3B^2, b = (2A^3 - 9A) / 27B^3
+	a := bi.Three.Sub(a2).Mod(p).Mul(crypto.ModInv(bi.Three.Mul(b2), p)).Mod(p)
+	b := bi.Two.Mul(a2).Mul(c.A).Sub(bi.Nine.Mul(c.A)).Mod(p)
+	b = b.Mul(crypto.ModInv(bi.FromInt(27).Mul(b2).Mul(c.B), p)).Mod(p)
+	w := Curve{Name: c.Name + " (weierstrass)", P: p, A: a, B: b, N: c.N, H: c.H}
+	v, _ := c.VFromU(c.U)
+	w.G = c.ToWeierstrass(c.U, v)
+	return w
+}
+
+// ToWeierstrass maps (u, v) to the isomorphic Weierstrass curve
+func (c MontgomeryCurve) ToWeierstrass(u, v bi.Int) Point {
+	p := c.P
+	bi3 := crypto.ModInv(bi.Three.Mul(c.B), p)
+	binv := crypto.ModInv(c.B, p)
+	// x = u/B + A/3B, y = v/B
+	x := u.Mul(binv).Add(c.A.Mul(bi3)).Mod(p)
+	return Point{X: x, Y: v.Mul(binv).Mod(p)}
+}
+
+// FromWeierstrass maps a point of the isomorphic Weierstrass curve back to
+// (u, v)
+func (c MontgomeryCurve) FromWeierstrass(pt Point) (bi.Int, bi.Int) {
+	p := c.P
+	// u = Bx - A/3, v = By
+	u := c.B.Mul(pt.X).Sub(c.A.Mul(crypto.ModInv(bi.Three, p))).Mod(p)
+	return u, c.B.Mul(pt.Y).Mod(p)
+}
+
+// Curve25519 returns the curve v^2 = u^3 + 486662u^2 + u over 2^255 - 19
+func Curve25519() MontgomeryCurve {
+	return MontgomeryCurve{
+		Name: "Curve25519",
+		P:    mustInt("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffed", 16),
+		A:    bi.FromInt(486662),
+		B:    bi.One,
+		U:    bi.Nine,
+		N:    mustInt("1000000000000000000000000000000014def9dea2f79cd65812631a5cf5d3ed", 16),
+		H:    bi.Eight,
+	}
+}
+
+// ToyMontgomeryCurve returns v^2 = u^3 + 534u^2 + u, the Montgomery form of
+// ToyCurve. u = x - 178
+func ToyMontgomeryCurve() MontgomeryCurve {
+	return MontgomeryCurve{
+		Name: "cryptopals",
+		P:    mustInt("233970423115425145524320034830162017933", 10),
+		A:    bi.FromInt(534),
+		B:    bi.One,
+		U:    bi.Four,
+		N:    mustInt("29246302889428143187362802287225875743", 10),
+		H:    bi.Eight,
+	}
+}
@@ -0,0 +1,7 @@
+package crypto
+
+import "errors"
+
+// ErrAttackFailed is returned by an attack that ran to completion without
+// recovering what it was after
+var ErrAttackFailed = errors.New("attack failed")
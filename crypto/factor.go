@@ -0,0 +1,232 @@
+package crypto
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	bi "github.com/sukunrt/bigint"
+)
+
+// ErrNoFactor is returned by a factoring method that gave up without finding
+// a nontrivial factor
+var ErrNoFactor = errors.New("no factor found")
+
+const (
+	// factorTrialBound is the bound Factor uses for trial division
+	factorTrialBound = 1 << 16
+	// factorFermatSteps is how long Factor tries Fermat's method
+	factorFermatSteps = 1 << 10
+	// factorPMinus1Bound is the smoothness bound Factor uses for Pollard's p-1
+	factorPMinus1Bound = 1 << 16
+	// rhoTries is the number of random starting points PollardRho tries
+	rhoTries = 8
+	// rhoBatch is the number of steps of PollardRho between two gcds
+	rhoBatch = 128
+)
+
+// Factorization maps every prime factor of a number, in decimal, to its
+// multiplicity
+type Factorization map[string]int
+
+func (f Factorization) add(p bi.Int, e int) {
+	f[p.String()] += e
+}
+
+// Mult returns the multiplicity of p in f, 0 if p is not a factor
+func (f Factorization) Mult(p bi.Int) int {
+	return f[p.String()]
+}
+
+// Primes returns the distinct prime factors in increasing order
+func (f Factorization) Primes() []bi.Int {
+	res := make([]bi.Int, 0, len(f))
+	for s := range f {
+		p, _ := bi.FromString(s, 10)
+		res = append(res, p)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Cmp(res[j]) < 0 })
+	return res
+}
+
+// Product returns the number f is the factorisation of
+func (f Factorization) Product() bi.Int {
+	res := bi.One
+	for _, p := range f.Primes() {
+		res = res.Mul(bi.Exp(p, bi.FromInt(f.Mult(p)), bi.Zero))
+	}
+	return res
+}
+
+var (
+	sieveMu    sync.Mutex
+	sieve      []int // the primes below sieveBound
+	sieveBound int
+)
+
+// primesBelow returns the primes less than n. The sieve is cached and only
+// redone for a larger n, the result must not be modified
+func primesBelow(n int) []int {
+	sieveMu.Lock()
+	defer sieveMu.Unlock()
+	if n > sieveBound {
+		// a new slice since the old one is shared with earlier callers
+		composite := make([]bool, n)
+		var primes []int
+		for i := 2; i < n; i++ {
+			if composite[i] {
+				continue
+			}
+			primes = append(primes, i)
+			for j := i * i; j < n; j += i {
+				composite[j] = true
+			}
+		}
+		sieve, sieveBound = primes, n
+	}
+	i := sort.SearchInts(sieve, n)
+	return sieve[:i:i]
+}
+
+// TrialDivision divides n by the primes below bound. It returns the primes it
+// found with their multiplicities and the cofactor left, which has no prime
+// factors below bound
+func TrialDivision(n bi.Int, bound int) (Factorization, bi.Int) {
+	f := make(Factorization)
+	for _, p := range primesBelow(bound) {
+		bp := bi.FromInt(p)
+		if bp.Mul(bp).Cmp(n) > 0 {
+			// n is 1 or a prime
+			if !n.Equal(bi.One) && n.Cmp(bi.FromInt(bound)) < 0 {
+				f.add(n, 1)
+				n = bi.One
+			}
+			break
+		}
+		for n.Mod(bp).Equal(bi.Zero) {
+			f.add(bp, 1)
+			n = n.Div(bp)
+		}
+	}
+	return f, n
+}
+
+// PollardRho returns a nontrivial factor of the composite n with Brent's
+// variant of Pollard's rho. The differences are multiplied together so that
+// one gcd covers rhoBatch steps, and a batch that overshoots to n is replayed
+// one step at a time
+func PollardRho(n bi.Int) (bi.Int, error) {
+	if n.Mod(bi.Two).Equal(bi.Zero) {
+		return bi.Two, nil
+	}
+	for i := 0; i < rhoTries; i++ {
+		c := RandInt(n.Sub(bi.One)).Add(bi.One)
+		f := func(x bi.Int) bi.Int {
+			return x.Mul(x).Add(c).Mod(n)
+		}
+		y := RandInt(n)
+		x, ys := y, y
+		g, q := bi.One, bi.One
+		for r := 1; g.Equal(bi.One); r *= 2 {
+			x = y
+			for j := 0; j < r; j++ {
+				y = f(y)
+			}
+			for k := 0; k < r && g.Equal(bi.One); k += rhoBatch {
+				ys = y
+				for j := 0; j < rhoBatch && j < r-k; j++ {
+					y = f(y)
+					q = q.Mul(x.Sub(y)).Mod(n)
+				}
+				g = gcd(q, n)
+			}
+		}
+		if g.Equal(n) {
+			for g = bi.One; g.Equal(bi.One); {
+				ys = f(ys)
+				g = gcd(x.Sub(ys).Mod(n), n)
+			}
+		}
+		if !g.Equal(n) {
+			return g, nil
+		}
+	}
+	return bi.Zero, ErrNoFactor
+}
+
+// PollardPMinus1 returns a nontrivial factor of n if n has a prime factor p
+// with p-1 bound-smooth, i.e. every prime power dividing p-1 is at most
+// bound. It fails when that holds for every prime factor of n
+func PollardPMinus1(n bi.Int, bound int) (bi.Int, error) {
+	a := bi.Two
+	for _, p := range primesBelow(bound + 1) {
+		pk := p
+		for pk <= bound/p {
+			pk *= p
+		}
+		a = bi.Exp(a, bi.FromInt(pk), n)
+	}
+	d := gcd(a.Sub(bi.One).Mod(n), n)
+	if d.Equal(bi.One) || d.Equal(n) {
+		return bi.Zero, ErrNoFactor
+	}
+	return d, nil
+}
+
+// FermatFactor writes the odd n as a^2 - b^2 = (a-b)(a+b), trying a from
+// ceil(sqrt(n)) upwards, and returns a-b. The number of steps grows with the
+// square of the distance between the factors, so it gives up after steps tries
+func FermatFactor(n bi.Int, steps int) (bi.Int, error) {
+	a := n.Sqrt()
+	if a.Mul(a).Cmp(n) < 0 {
+		a = a.Add(bi.One)
+	}
+	for i := 0; i < steps; i++ {
+		b2 := a.Mul(a).Sub(n)
+		if b := b2.Sqrt(); b.Mul(b).Equal(b2) {
+			if d := a.Sub(b); !d.Equal(bi.One) {
+				return d, nil
+			}
+			return bi.Zero, ErrNoFactor
+		}
+		a = a.Add(bi.One)
+	}
+	return bi.Zero, ErrNoFactor
+}
+
+// Factor returns the prime factorisation of n > 0. Trial division removes the
+// primes below 2^16, then every composite left is split by Fermat's method,
+// Pollard's p-1 or Brent's rho, the first one that succeeds
+func Factor(n bi.Int) Factorization {
+	f, rest := TrialDivision(n, factorTrialBound)
+	stack := []bi.Int{rest}
+	for len(stack) > 0 {
+		m := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch {
+		case m.Equal(bi.One):
+		case MillerRabin(m):
+			f.add(m, 1)
+		default:
+			d := split(m)
+			stack = append(stack, d, m.Div(d))
+		}
+	}
+	return f
+}
+
+// split returns a nontrivial factor of the composite n with no factors below
+// factorTrialBound
+func split(n bi.Int) bi.Int {
+	if d, err := FermatFactor(n, factorFermatSteps); err == nil {
+		return d
+	}
+	if d, err := PollardPMinus1(n, factorPMinus1Bound); err == nil {
+		return d
+	}
+	for {
+		if d, err := PollardRho(n); err == nil {
+			return d
+		}
+	}
+}
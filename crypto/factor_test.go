@@ -0,0 +1,84 @@
+package crypto
+
+import (
+	"testing"
+
+	bi "github.com/sukunrt/bigint"
+)
+
+// nextPrime returns the smallest prime greater than n
+func nextPrime(n bi.Int) bi.Int {
+	for n = n.Add(bi.One); !MillerRabin(n); n = n.Add(bi.One) {
+	}
+	return n
+}
+
+func checkFactor(t *testing.T, n, d bi.Int, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Equal(bi.One) || d.Equal(n) || !n.Mod(d).Equal(bi.Zero) {
+		t.Fatalf("%s is not a nontrivial factor of %s", d, n)
+	}
+}
+
+func TestTrialDivision(t *testing.T) {
+	cof := RandPrimeN(8)
+	n := bi.FromInt(8 * 9 * 65521).Mul(cof)
+	f, rest := TrialDivision(n, 1<<16)
+	if f.Mult(bi.Two) != 3 || f.Mult(bi.Three) != 2 || f.Mult(bi.FromInt(65521)) != 1 || len(f) != 3 {
+		t.Fatalf("got %v", f)
+	}
+	if !rest.Equal(cof) {
+		t.Fatalf("cofactor %s, want %s", rest, cof)
+	}
+}
+
+func TestPollardRho(t *testing.T) {
+	p, q := RandPrimeN(4), RandPrimeN(4)
+	n := p.Mul(q)
+	d, err := PollardRho(n)
+	checkFactor(t, n, d, err)
+}
+
+func TestPollardPMinus1(t *testing.T) {
+	p, _ := smoothPrime(128, nil)
+	n := p.Mul(RandPrimeN(16))
+	d, err := PollardPMinus1(n, 1<<12)
+	checkFactor(t, n, d, err)
+	if !d.Equal(p) {
+		t.Fatalf("got %s, want %s", d, p)
+	}
+}
+
+func TestFermatFactor(t *testing.T) {
+	p := RandPrimeN(64)
+	q := nextPrime(p.Add(RandInt(bi.FromInt(1 << 20))))
+	n := p.Mul(q)
+	d, err := FermatFactor(n, 16)
+	checkFactor(t, n, d, err)
+	if _, err := FermatFactor(RandPrimeN(16).Mul(RandPrimeN(16)), 16); err == nil {
+		t.Fatal("factored primes far apart")
+	}
+}
+
+func TestFactor(t *testing.T) {
+	// 2^3 * 65537^2 * two 32 bit primes * a 64 bit prime
+	n := bi.FromInt(8 * 65537 * 65537)
+	for _, p := range []bi.Int{RandPrimeN(4), RandPrimeN(4), RandPrimeN(8)} {
+		n = n.Mul(p)
+	}
+	f := Factor(n)
+	for _, p := range f.Primes() {
+		if !MillerRabin(p) {
+			t.Fatalf("factor %s is not prime", p)
+		}
+	}
+	if f.Mult(bi.FromInt(65537)) != 2 {
+		t.Fatalf("65537 has multiplicity %d, want 2", f.Mult(bi.FromInt(65537)))
+	}
+	if !f.Product().Equal(n) {
+		t.Fatalf("factors multiply to %s, want %s", f.Product(), n)
+	}
+}
@@ -0,0 +1,328 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/sukunrt/cryptopals/crypto/gf128"
+	"github.com/sukunrt/cryptopals/crypto/gf2"
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+const GCM Mode = "GCM"
+
+const (
+	// GCMNonceSize is the nonce size that doesn't need hashing to derive the
+	// first counter block
+	GCMNonceSize = 12
+	// GCMTagSize is the size of an untruncated tag
+	GCMTagSize = 16
+	// GCMMinTagSize is the smallest tag NewAESInGCMCipherWithTagSize accepts
+	GCMMinTagSize = 4
+)
+
+var (
+	// ErrGCMAuth is returned by Open when the tag doesn't authenticate the ciphertext
+	ErrGCMAuth = errors.New("gcm: message authentication failed")
+	// ErrGCMTagSize is returned for a tag size outside GCMMinTagSize to GCMTagSize
+	ErrGCMTagSize = errors.New("invalid tag size")
+)
+
+// AESInGCMCipher encrypts and authenticates bytes with AES in GCM mode
+type AESInGCMCipher struct {
+	cipher  cipher.Block
+	h       gf128.Element
+	ghash   *gf128.Table
+	tagSize int
+}
+
+// NewAESInGCMCipher returns a GCM cipher with full 16 byte tags
+func NewAESInGCMCipher(key []byte) (AESInGCMCipher, error) {
+	return NewAESInGCMCipherWithTagSize(key, GCMTagSize)
+}
+
+// NewAESInGCMCipherWithTagSize returns a GCM cipher that truncates its tags
+// to tagSize bytes
+func NewAESInGCMCipherWithTagSize(key []byte, tagSize int) (AESInGCMCipher, error) {
+	if tagSize < GCMMinTagSize || tagSize > GCMTagSize {
+		return AESInGCMCipher{}, fmt.Errorf("gcm: tag size %d: %w", tagSize, ErrGCMTagSize)
+	}
+	return newAESInGCMCipher(key, tagSize)
+}
+
+// newAESInGCMCipher returns a GCM cipher with tags of any size up to
+// GCMTagSize, even ones too short to authenticate anything
+func newAESInGCMCipher(key []byte, tagSize int) (AESInGCMCipher, error) {
+	c, err := newAESCipher(key)
+	if err != nil {
+		return AESInGCMCipher{}, fmt.Errorf("gcm: %w", err)
+	}
+	h := make([]byte, AESBlockSize)
+	c.Encrypt(h, h)
+	return AESInGCMCipher{
+		cipher:  c,
+		h:       gf128.FromBytes(h),
+		ghash:   gf128.NewTable(gf128.FromBytes(h)),
+		tagSize: tagSize,
+	}, nil
+}
+
+// TagSize returns the size of the tags appended by Seal
+func (gc AESInGCMCipher) TagSize() int {
+	return gc.tagSize
+}
+
+// counterBlock returns the first counter block J0 for nonce. Nonces of any
+// length but 0 are allowed
+func (gc AESInGCMCipher) counterBlock(nonce []byte) ([]byte, error) {
+	if len(nonce) == 0 {
+		return nil, fmt.Errorf("gcm: empty nonce: %w", ErrIVSize)
+	}
+	if len(nonce) == GCMNonceSize {
+		j0 := make([]byte, AESBlockSize)
+		copy(j0, nonce)
+		j0[AESBlockSize-1] = 1
+		return j0, nil
+	}
+	return gc.ghash.GHASH(nil, nonce).Bytes(), nil
+}
+
+// ctr xors b with the keystream starting at the block after j0
+func (gc AESInGCMCipher) ctr(j0, b []byte) []byte {
+	res := make([]byte, len(b))
+	ctr := make([]byte, AESBlockSize)
+	copy(ctr, j0)
+	ks := make([]byte, AESBlockSize)
+	for i := 0; i < len(b); i += AESBlockSize {
+		inc32(ctr)
+		gc.cipher.Encrypt(ks, ctr)
+		end := utils.MinInt(i+AESBlockSize, len(b))
+		copy(res[i:end], utils.XorBytes(b[i:end], ks[:end-i]))
+	}
+	return res
+}
+
+func inc32(ctr []byte) {
+	c := ctr[len(ctr)-4:]
+	binary.BigEndian.PutUint32(c, binary.BigEndian.Uint32(c)+1)
+}
+
+// tag returns the tag of ct and aad truncated to the tag size
+func (gc AESInGCMCipher) tag(j0, ct, aad []byte) []byte {
+	s := make([]byte, AESBlockSize)
+	gc.cipher.Encrypt(s, j0)
+	t := gc.ghash.GHASH(aad, ct).Add(gf128.FromBytes(s))
+	return t.Bytes()[:gc.tagSize]
+}
+
+// Seal encrypts b and authenticates it along with aad. It returns the
+// ciphertext followed by the tag, or an error wrapping ErrIVSize for an empty
+// nonce
+func (gc AESInGCMCipher) Seal(b, nonce, aad []byte) ([]byte, error) {
+	j0, err := gc.counterBlock(nonce)
+	if err != nil {
+		return nil, err
+	}
+	ct := gc.ctr(j0, b)
+	return utils.ConcatBytes(ct, gc.tag(j0, ct, aad)), nil
+}
+
+// Open checks the tag at the end of b and decrypts the ciphertext before it.
+// It returns ErrGCMAuth if the tag doesn't match and an error wrapping
+// ErrIVSize for an empty nonce
+func (gc AESInGCMCipher) Open(b, nonce, aad []byte) ([]byte, error) {
+	j0, err := gc.counterBlock(nonce)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < gc.tagSize {
+		return nil, ErrGCMAuth
+	}
+	ct, tag := b[:len(b)-gc.tagSize], b[len(b)-gc.tagSize:]
+	if subtle.ConstantTimeCompare(tag, gc.tag(j0, ct, aad)) != 1 {
+		return nil, ErrGCMAuth
+	}
+	return gc.ctr(j0, ct), nil
+}
+
+// GCMSample is a ciphertext and its tag along with the aad they authenticate
+type GCMSample struct {
+	AAD        []byte
+	Ciphertext []byte
+	Tag        []byte
+}
+
+// gcmTagPoly returns the polynomial in h whose value at the authentication key
+// is the mask block of the nonce s was sealed with
+func gcmTagPoly(s GCMSample) gf128.Poly {
+	return gf128.GHASHPoly(s.AAD, s.Ciphertext).Add(gf128.NewPoly(gf128.FromBytes(s.Tag)))
+}
+
+// RecoverGCMAuthKey returns the candidates for the authentication key H of
+// samples sealed under the same key and nonce. The mask block is the same for
+// all of them so the difference of any two tag polynomials has H as a root.
+// Every additional sample narrows the candidates down further
+func RecoverGCMAuthKey(samples []GCMSample) ([]gf128.Element, error) {
+	if len(samples) < 2 {
+		return nil, fmt.Errorf("need at least 2 samples, got %d: %w", len(samples), ErrAttackFailed)
+	}
+	for _, s := range samples {
+		if len(s.Tag) != GCMTagSize {
+			return nil, fmt.Errorf("tag of %d bytes is truncated: %w", len(s.Tag), ErrAttackFailed)
+		}
+	}
+	p0 := gcmTagPoly(samples[0])
+	var candidates []gf128.Element
+	for i, s := range samples[1:] {
+		f := p0.Add(gcmTagPoly(s))
+		if i == 0 {
+			candidates = gf128.Roots(f)
+			continue
+		}
+		var left []gf128.Element
+		for _, h := range candidates {
+			if f.Eval(h).IsZero() {
+				left = append(left, h)
+			}
+		}
+		candidates = left
+		if len(candidates) <= 1 {
+			break
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, ErrAttackFailed
+	}
+	return candidates, nil
+}
+
+// ForgeGCMTag returns the tag of ct and aad under the authentication key h for
+// the nonce known was sealed with. The mask block of that nonce is recovered
+// from known
+func ForgeGCMTag(h gf128.Element, known GCMSample, ct, aad []byte) []byte {
+	s := gf128.GHASH(h, known.AAD, known.Ciphertext).Add(gf128.FromBytes(known.Tag))
+	return gf128.GHASH(h, aad, ct).Add(s).Bytes()[:len(known.Tag)]
+}
+
+// GCMErrorMatrix returns A_d, the matrix that maps the authentication key to
+// the tag difference caused by adding ds[i] to the ciphertext block that gets
+// multiplied by h^(2^(i+1)). Squaring is linear so A_d is the sum of
+// M_ds[i] * S^(i+1) and the error in the tag is A_d * h
+func GCMErrorMatrix(ds []gf128.Element) gf2.Matrix {
+	s := gf128.SquareMatrix()
+	si := s
+	ad := gf2.NewMatrix(128, 128)
+	for _, d := range ds {
+		ad = ad.Add(d.Matrix().Mul(si))
+		si = si.Mul(s)
+	}
+	return ad
+}
+
+// gcmForgeryConstraints returns the matrix whose kernel holds the block
+// differences d that zero the first z rows of A_d * X, where the columns of X
+// span the space the authentication key is known to lie in. Column
+// (i-1)*128+b is the coefficient of x^b in the difference for h^(2^i), row
+// r*k+c is entry (r, c) of A_d * X
+func gcmForgeryConstraints(basis []gf128.Element, n, z int) gf2.Matrix {
+	k := len(basis)
+	t := gf2.NewMatrix(z*k, n*128)
+	for c, xc := range basis {
+		v := xc
+		for i := 1; i <= n; i++ {
+			// x^b * xc^(2^i) is the column of A_d * X added by bit b of d_i
+			v = v.Square()
+			e := v
+			for b := 0; b < 128; b++ {
+				for r := 0; r < z; r++ {
+					if e.Bit(r) == 1 {
+						t.Set(r*k+c, (i-1)*128+b, 1)
+					}
+				}
+				e = e.MulX()
+			}
+		}
+	}
+	return t
+}
+
+// BreakGCMTruncatedMAC recovers the authentication key of a GCM oracle that
+// truncates its tags. ct is a ciphertext of at least 4 full blocks with no aad
+// and tag its valid truncated tag. oracle reports whether a ciphertext is
+// accepted with tag under the same nonce.
+//
+// Only the blocks multiplied by h^(2^i) are changed so the tag error is the
+// linear function A_d * h. Differences that zero the first rows of A_d for
+// every key left are forged until one is accepted by chance, and then the
+// remaining rows give new linear equations on h
+func BreakGCMTruncatedMAC(ct, tag []byte, oracle func(ct, tag []byte) bool) (gf128.Element, error) {
+	m := len(ct) / AESBlockSize
+	if len(ct)%AESBlockSize != 0 || m < 4 {
+		return gf128.Zero, fmt.Errorf("need a ciphertext of at least 4 full blocks: %w", ErrAttackFailed)
+	}
+	tagBits := 8 * len(tag)
+	n := bits.Len(uint(m)) - 1
+	basis := make([]gf128.Element, 128)
+	for i := range basis {
+		basis[i] = gf128.Zero.SetBit(i, 1)
+	}
+	var eqs []gf2.Vector
+	forged := make([]byte, len(ct))
+	for len(basis) > 1 {
+		z := utils.MinInt(tagBits-1, (n*128-1)/len(basis))
+		ker := gcmForgeryConstraints(basis, n, z).Kernel()
+		if len(ker) == 0 {
+			return gf128.Zero, ErrAttackFailed
+		}
+		// a forgery is accepted with probability 2^-(tagBits-z), give up
+		// after 32 times the expected number of tries
+		if tagBits-z > 30 {
+			return gf128.Zero, fmt.Errorf("%d bit tag needs about 2^%d forgeries: %w", tagBits, tagBits-z, ErrAttackFailed)
+		}
+		tries := 32 << (tagBits - z)
+		for try := 0; ; try++ {
+			if try == tries {
+				return gf128.Zero, fmt.Errorf("no forgery accepted in %d tries: %w", tries, ErrAttackFailed)
+			}
+			d := gf2.NewVector(n * 128)
+			for _, v := range ker {
+				if utils.RandIntn(2) == 1 {
+					d.XorInPlace(v)
+				}
+			}
+			if d.IsZero() {
+				continue
+			}
+			ds := make([]gf128.Element, n)
+			copy(forged, ct)
+			for i := 1; i <= n; i++ {
+				for b := 0; b < 128; b++ {
+					ds[i-1] = ds[i-1].SetBit(b, d.Get((i-1)*128+b))
+				}
+				// the block before the length block is multiplied by h^2
+				pos := (m + 1 - 1<<i) * AESBlockSize
+				copy(forged[pos:], utils.XorBytes(ct[pos:pos+AESBlockSize], ds[i-1].Bytes()))
+			}
+			if oracle(forged, tag) {
+				ad := GCMErrorMatrix(ds)
+				for r := z; r < tagBits; r++ {
+					eqs = append(eqs, ad.Row(r).Clone())
+				}
+				break
+			}
+		}
+		ker = gf2.FromRows(128, eqs...).Kernel()
+		basis = basis[:0]
+		for _, v := range ker {
+			basis = append(basis, gf128.FromVector(v))
+		}
+	}
+	if len(basis) == 0 {
+		return gf128.Zero, ErrAttackFailed
+	}
+	return basis[0], nil
+}
@@ -0,0 +1,160 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"testing"
+
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+func TestAESInGCMMatchesStdlib(t *testing.T) {
+	// the standard library can't change both the nonce size and the tag size
+	cases := []struct{ nonceSize, tagSize int }{
+		{GCMNonceSize, GCMTagSize},
+		{8, GCMTagSize},
+		{60, GCMTagSize},
+		{GCMNonceSize, 12},
+		{GCMNonceSize, 13},
+	}
+	for _, tc := range cases {
+		for i := 0; i < 20; i++ {
+			key := RandAESKey()
+			nonce := utils.RandBytes(tc.nonceSize)
+			msg := utils.RandBytes(utils.RandIntn(100))
+			aad := utils.RandBytes(utils.RandIntn(50))
+
+			block, _ := aes.NewCipher(key)
+			var std cipher.AEAD
+			var err error
+			if tc.tagSize == GCMTagSize {
+				std, err = cipher.NewGCMWithNonceSize(block, tc.nonceSize)
+			} else {
+				std, err = cipher.NewGCMWithTagSize(block, tc.tagSize)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := std.Seal(nil, nonce, msg, aad)
+
+			gc := must(NewAESInGCMCipherWithTagSize(key, tc.tagSize))
+			got, err := gc.Seal(msg, nonce, aad)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("nonce %d tag %d: got %x want %x", tc.nonceSize, tc.tagSize, got, want)
+			}
+			pt, err := gc.Open(got, nonce, aad)
+			if err != nil || !bytes.Equal(pt, msg) {
+				t.Fatalf("nonce %d tag %d: open failed: %v", tc.nonceSize, tc.tagSize, err)
+			}
+		}
+	}
+}
+
+func TestAESInGCMTruncatedTag(t *testing.T) {
+	key := RandAESKey()
+	nonce := utils.RandBytes(GCMNonceSize)
+	msg := []byte("attack at dawn")
+	full := must(must(NewAESInGCMCipher(key)).Seal(msg, nonce, nil))
+	short := must(must(NewAESInGCMCipherWithTagSize(key, GCMMinTagSize)).Seal(msg, nonce, nil))
+	if !bytes.Equal(short, full[:len(msg)+GCMMinTagSize]) {
+		t.Fatalf("truncated tag is not a prefix of the full tag")
+	}
+}
+
+func TestAESInGCMOpenRejectsTampering(t *testing.T) {
+	gc := must(NewAESInGCMCipher(RandAESKey()))
+	nonce := utils.RandBytes(GCMNonceSize)
+	sealed := must(gc.Seal([]byte("attack at dawn"), nonce, []byte("header")))
+	sealed[0] ^= 1
+	if _, err := gc.Open(sealed, nonce, []byte("header")); !errors.Is(err, ErrGCMAuth) {
+		t.Fatalf("expected ErrGCMAuth for modified ciphertext, got %v", err)
+	}
+	sealed[0] ^= 1
+	if _, err := gc.Open(sealed, nonce, []byte("footer")); !errors.Is(err, ErrGCMAuth) {
+		t.Fatalf("expected ErrGCMAuth for modified aad, got %v", err)
+	}
+}
+
+func TestAESInGCMEmptyNonce(t *testing.T) {
+	gc := must(NewAESInGCMCipher(RandAESKey()))
+	if _, err := gc.Seal([]byte("attack at dawn"), nil, nil); !errors.Is(err, ErrIVSize) {
+		t.Fatalf("expected ErrIVSize from Seal, got %v", err)
+	}
+	if _, err := gc.Open(make([]byte, GCMTagSize), nil, nil); !errors.Is(err, ErrIVSize) {
+		t.Fatalf("expected ErrIVSize from Open, got %v", err)
+	}
+}
+
+func TestRecoverGCMAuthKey(t *testing.T) {
+	key := RandAESKey()
+	gc := must(NewAESInGCMCipher(key))
+	nonce := utils.RandBytes(GCMNonceSize)
+	var samples []GCMSample
+	for _, n := range []int{20, 35, 5} {
+		b := must(gc.Seal(utils.RandBytes(n), nonce, nil))
+		samples = append(samples, GCMSample{Ciphertext: b[:n], Tag: b[n:]})
+	}
+	candidates, err := RecoverGCMAuthKey(samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 1 || !bytes.Equal(candidates[0].Bytes(), gc.h.Bytes()) {
+		t.Fatalf("recovered %v, want %s", candidates, gc.h)
+	}
+	ct := utils.RandBytes(40)
+	tag := ForgeGCMTag(candidates[0], samples[0], ct, []byte("aad"))
+	if _, err := gc.Open(utils.ConcatBytes(ct, tag), nonce, []byte("aad")); err != nil {
+		t.Fatalf("forged tag rejected: %v", err)
+	}
+}
+
+func TestBreakGCMTruncatedMAC(t *testing.T) {
+	// 4 byte tags, the shortest NewAESInGCMCipherWithTagSize allows, need
+	// minutes of forgeries whatever the length, 2 byte tags keep it quick
+	const tagSize = 2
+	gc := must(newAESInGCMCipher(RandAESKey(), tagSize))
+	nonce := utils.RandBytes(GCMNonceSize)
+	msg := utils.RandBytes(512 * AESBlockSize)
+	sealed := must(gc.Seal(msg, nonce, nil))
+	ct, tag := sealed[:len(msg)], sealed[len(msg):]
+	queries := 0
+	oracle := func(ct, tag []byte) bool {
+		queries++
+		_, err := gc.Open(utils.ConcatBytes(ct, tag), nonce, nil)
+		return err == nil
+	}
+	h, err := BreakGCMTruncatedMAC(ct, tag, oracle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.Equal(gc.h) {
+		t.Fatalf("recovered %s, want %s", h, gc.h)
+	}
+	t.Logf("recovered h in %d queries", queries)
+}
+
+func TestBreakGCMTruncatedMACGivesUp(t *testing.T) {
+	gc := must(newAESInGCMCipher(RandAESKey(), 2))
+	msg := utils.RandBytes(64 * AESBlockSize)
+	sealed := must(gc.Seal(msg, utils.RandBytes(GCMNonceSize), nil))
+	// the oracle checks tags under a different nonce and never accepts
+	wrongNonce := utils.RandBytes(GCMNonceSize)
+	oracle := func(ct, tag []byte) bool {
+		_, err := gc.Open(utils.ConcatBytes(ct, tag), wrongNonce, nil)
+		return err == nil
+	}
+	if _, err := BreakGCMTruncatedMAC(sealed[:len(msg)], sealed[len(msg):], oracle); !errors.Is(err, ErrAttackFailed) {
+		t.Fatalf("expected ErrAttackFailed from an oracle that never accepts, got %v", err)
+	}
+
+	full := must(must(NewAESInGCMCipher(RandAESKey())).Seal(msg, utils.RandBytes(GCMNonceSize), nil))
+	never := func(ct, tag []byte) bool { return false }
+	if _, err := BreakGCMTruncatedMAC(full[:len(msg)], full[len(msg):], never); !errors.Is(err, ErrAttackFailed) {
+		t.Fatalf("expected ErrAttackFailed for a full length tag, got %v", err)
+	}
+}
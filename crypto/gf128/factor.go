@@ -0,0 +1,154 @@
+package gf128
+
+import "github.com/sukunrt/cryptopals/utils"
+
+// Factor is a factor of a polynomial with its multiplicity, or in the case of
+// DistinctDegree the product of all the factors of one degree
+type Factor struct {
+	Poly   Poly
+	Degree int // degree of the irreducible factors making up Poly
+	Mult   int
+}
+
+// x is the polynomial x
+var x = NewPoly(Zero, One)
+
+// sqrt returns the square root of a, a^(2^127)
+func (a Element) sqrt() Element {
+	return a.Exp2k(127)
+}
+
+// SquareFree splits the monic polynomial f into square free factors. The
+// product of Poly^Mult over the result is f
+func SquareFree(f Poly) []Factor {
+	f = f.Monic()
+	if f.Degree() < 1 {
+		return nil
+	}
+	var res []Factor
+	// c holds the factors with multiplicity divisible by 2, w the rest
+	c := GCD(f, f.Derivative())
+	w, _ := f.DivMod(c)
+	for i := 1; w.Degree() > 0; i++ {
+		y := GCD(w, c)
+		fac, _ := w.DivMod(y)
+		if fac.Degree() > 0 {
+			res = append(res, Factor{Poly: fac, Mult: i})
+		}
+		w = y
+		c, _ = c.DivMod(y)
+	}
+	if c.Degree() > 0 {
+		// c is a perfect square, take the square root of every coefficient
+		// and factor that
+		root := make(Poly, c.Degree()/2+1)
+		for i := range root {
+			root[i] = c[2*i].sqrt()
+		}
+		for _, fac := range SquareFree(root) {
+			fac.Mult *= 2
+			res = append(res, fac)
+		}
+	}
+	return res
+}
+
+// frobenius returns p^(2^128) mod f
+func frobenius(p, f Poly) Poly {
+	for i := 0; i < 128; i++ {
+		p = p.MulMod(p, f)
+	}
+	return p
+}
+
+// DistinctDegree splits the monic square free polynomial f into the products
+// of its irreducible factors of each degree. It stops once maxDegree is
+// reached, use a negative maxDegree for all of them
+func DistinctDegree(f Poly, maxDegree int) []Factor {
+	f = f.Monic()
+	var res []Factor
+	h := x.Mod(f)
+	for d := 1; f.Degree() >= 2*d; d++ {
+		if maxDegree >= 0 && d > maxDegree {
+			return res
+		}
+		// h = x^(q^d) mod f, its gcd with x^(q^d) - x is the product of the
+		// factors with degree dividing d
+		h = frobenius(h, f)
+		g := GCD(f, h.Add(x))
+		if g.Degree() > 0 {
+			res = append(res, Factor{Poly: g, Degree: d, Mult: 1})
+			f, _ = f.DivMod(g)
+			h = h.Mod(f)
+		}
+	}
+	if f.Degree() > 0 && (maxDegree < 0 || f.Degree() <= maxDegree) {
+		res = append(res, Factor{Poly: f, Degree: f.Degree(), Mult: 1})
+	}
+	return res
+}
+
+// randPoly returns a random polynomial of degree less than n
+func randPoly(n int) Poly {
+	p := make(Poly, n)
+	for i := range p {
+		p[i] = FromBytes(utils.RandBytes(BlockSize))
+	}
+	return p.trim()
+}
+
+// EqualDegree splits f, a product of distinct irreducible polynomials of
+// degree d, into those polynomials with the Cantor–Zassenhaus algorithm. In
+// characteristic 2 the trace map a + a^2 + ... + a^(2^(128d-1)) takes the
+// place of a^((q^d-1)/2) and is 0 or 1 mod every factor
+func EqualDegree(f Poly, d int) []Poly {
+	f = f.Monic()
+	n := f.Degree()
+	if n <= d {
+		return []Poly{f}
+	}
+	for {
+		a := randPoly(n)
+		t := a
+		sq := a
+		for i := 1; i < 128*d; i++ {
+			sq = sq.MulMod(sq, f)
+			t = t.Add(sq)
+		}
+		g := GCD(f, t)
+		if g.Degree() > 0 && g.Degree() < n {
+			h, _ := f.DivMod(g)
+			return append(EqualDegree(g, d), EqualDegree(h, d)...)
+		}
+	}
+}
+
+// Factorize returns the monic irreducible factors of f with their multiplicities
+func Factorize(f Poly) []Factor {
+	var res []Factor
+	for _, sf := range SquareFree(f) {
+		for _, dd := range DistinctDegree(sf.Poly, -1) {
+			for _, p := range EqualDegree(dd.Poly, dd.Degree) {
+				res = append(res, Factor{Poly: p, Degree: dd.Degree, Mult: sf.Mult})
+			}
+		}
+	}
+	return res
+}
+
+// Roots returns the distinct roots of f
+func Roots(f Poly) []Element {
+	var res []Element
+	for _, sf := range SquareFree(f) {
+		for _, dd := range DistinctDegree(sf.Poly, 1) {
+			if dd.Degree != 1 {
+				continue
+			}
+			for _, p := range EqualDegree(dd.Poly, 1) {
+				// p is monic x + r
+				res = append(res, p[0])
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,44 @@
+package gf128
+
+import "testing"
+
+func TestRoots(t *testing.T) {
+	a, b, c := randElement(), randElement(), randElement()
+	// (x + a)(x + b)^2(x + c)^4 times a random quadratic
+	f := NewPoly(a, One).
+		Mul(NewPoly(b, One)).Mul(NewPoly(b, One)).
+		Mul(NewPoly(c, One)).Mul(NewPoly(c, One)).Mul(NewPoly(c, One)).Mul(NewPoly(c, One)).
+		Mul(NewPoly(randElement(), randElement(), One))
+	want := map[Element]bool{a: true, b: true, c: true}
+	got := make(map[Element]bool)
+	for _, r := range Roots(f) {
+		if !f.Eval(r).IsZero() {
+			t.Fatalf("%s is not a root", r)
+		}
+		got[r] = true
+	}
+	for r := range want {
+		if !got[r] {
+			t.Fatalf("missing root %s", r)
+		}
+	}
+}
+
+func TestFactorize(t *testing.T) {
+	f := NewPoly(randElement(), One).
+		Mul(NewPoly(randElement(), randElement(), randElement(), One)).
+		Mul(NewPoly(randElement(), One)).Mul(NewPoly(randElement(), One))
+	f = f.Mul(f)
+	prod := NewPoly(One)
+	for _, fac := range Factorize(f) {
+		if fac.Poly.Degree() != fac.Degree {
+			t.Fatalf("factor %v has degree %d, reported %d", fac.Poly, fac.Poly.Degree(), fac.Degree)
+		}
+		for i := 0; i < fac.Mult; i++ {
+			prod = prod.Mul(fac.Poly)
+		}
+	}
+	if !prod.Equal(f.Monic()) {
+		t.Fatalf("product of the factors is not f")
+	}
+}
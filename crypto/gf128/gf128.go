@@ -0,0 +1,142 @@
+// Package gf128 implements arithmetic in GF(2^128) as used by GCM along with
+// polynomials over that field.
+//
+// Elements use the GCM bit order: the first bit of the 16 byte block (the most
+// significant bit of byte 0) is the coefficient of x^0 and the field is reduced
+// by x^128 + x^7 + x^2 + x + 1.
+package gf128
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// BlockSize is the size of an element in bytes
+const BlockSize = 16
+
+// Element is an element of GF(2^128). hi holds the coefficients of x^0..x^63
+// with x^0 in the most significant bit, lo holds x^64..x^127
+type Element struct {
+	hi, lo uint64
+}
+
+var (
+	Zero = Element{}
+	One  = Element{hi: 1 << 63}
+)
+
+// r is x^128 reduced, i.e. x^7 + x^2 + x + 1, shifted to the x^0 end
+const r = 0xe1 << 56
+
+// FromBytes returns the element represented by the 16 byte block b. Shorter
+// blocks are zero padded on the right
+func FromBytes(b []byte) Element {
+	var buf [BlockSize]byte
+	copy(buf[:], b)
+	return Element{
+		hi: binary.BigEndian.Uint64(buf[:8]),
+		lo: binary.BigEndian.Uint64(buf[8:]),
+	}
+}
+
+// Bytes returns the 16 byte block representation of a
+func (a Element) Bytes() []byte {
+	b := make([]byte, BlockSize)
+	binary.BigEndian.PutUint64(b[:8], a.hi)
+	binary.BigEndian.PutUint64(b[8:], a.lo)
+	return b
+}
+
+// Bit returns the coefficient of x^i in a
+func (a Element) Bit(i int) uint {
+	if i < 64 {
+		return uint(a.hi>>(63-i)) & 1
+	}
+	return uint(a.lo>>(127-i)) & 1
+}
+
+// SetBit returns a with the coefficient of x^i set to v
+func (a Element) SetBit(i int, v uint) Element {
+	if i < 64 {
+		a.hi = a.hi&^(1<<(63-i)) | uint64(v&1)<<(63-i)
+	} else {
+		a.lo = a.lo&^(1<<(127-i)) | uint64(v&1)<<(127-i)
+	}
+	return a
+}
+
+// Add returns a + b. Subtraction is the same operation
+func (a Element) Add(b Element) Element {
+	return Element{hi: a.hi ^ b.hi, lo: a.lo ^ b.lo}
+}
+
+// Mul returns a * b
+func (a Element) Mul(b Element) Element {
+	var z Element
+	v := b
+	for i := 0; i < 128; i++ {
+		if a.Bit(i) == 1 {
+			z = z.Add(v)
+		}
+		v = v.MulX()
+	}
+	return z
+}
+
+// MulX returns a * x. It is much cheaper than a.Mul(x)
+func (a Element) MulX() Element {
+	carry := a.lo & 1
+	a.lo = a.lo>>1 | a.hi<<63
+	a.hi >>= 1
+	if carry == 1 {
+		a.hi ^= r
+	}
+	return a
+}
+
+// Square returns a * a
+func (a Element) Square() Element {
+	return a.Mul(a)
+}
+
+// Exp2k returns a^(2^k). It is a linear map since the field has characteristic 2
+func (a Element) Exp2k(k int) Element {
+	for i := 0; i < k; i++ {
+		a = a.Square()
+	}
+	return a
+}
+
+// Inverse returns the multiplicative inverse of a. It panics if a is zero
+func (a Element) Inverse() Element {
+	if a.IsZero() {
+		panic("gf128: inverse of zero")
+	}
+	// a^-1 = a^(2^128 - 2) = a^2 * a^4 * ... * a^(2^127)
+	res := One
+	sq := a
+	for i := 1; i < 128; i++ {
+		sq = sq.Square()
+		res = res.Mul(sq)
+	}
+	return res
+}
+
+// Div returns a / b
+func (a Element) Div(b Element) Element {
+	return a.Mul(b.Inverse())
+}
+
+// IsZero reports whether a is zero
+func (a Element) IsZero() bool {
+	return a == Zero
+}
+
+// Equal reports whether a and b are the same element
+func (a Element) Equal(b Element) bool {
+	return a == b
+}
+
+func (a Element) String() string {
+	return fmt.Sprintf("%016x%016x", a.hi, a.lo)
+}
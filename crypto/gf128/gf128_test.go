@@ -0,0 +1,88 @@
+package gf128
+
+import (
+	"testing"
+
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+func randElement() Element {
+	return FromBytes(utils.RandBytes(BlockSize))
+}
+
+func TestMulDistributesAndInverts(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		a, b, c := randElement(), randElement(), randElement()
+		if !a.Mul(b.Add(c)).Equal(a.Mul(b).Add(a.Mul(c))) {
+			t.Fatalf("a(b+c) != ab+ac for %s %s %s", a, b, c)
+		}
+		if !a.Mul(b).Equal(b.Mul(a)) {
+			t.Fatalf("ab != ba for %s %s", a, b)
+		}
+		if !a.Mul(a.Inverse()).Equal(One) {
+			t.Fatalf("a * a^-1 != 1 for %s", a)
+		}
+	}
+}
+
+func TestMulByX(t *testing.T) {
+	// x^127 * x = x^128 = x^7 + x^2 + x + 1
+	x := Zero.SetBit(1, 1)
+	x127 := Zero.SetBit(127, 1)
+	want := Zero.SetBit(0, 1).SetBit(1, 1).SetBit(2, 1).SetBit(7, 1)
+	if got := x127.Mul(x); !got.Equal(want) {
+		t.Fatalf("x^128 = %s, want %s", got, want)
+	}
+}
+
+func TestPolyDivMod(t *testing.T) {
+	p := NewPoly(randElement(), randElement(), randElement(), randElement(), randElement())
+	q := NewPoly(randElement(), randElement(), One)
+	quo, rem := p.DivMod(q)
+	if rem.Degree() >= q.Degree() {
+		t.Fatalf("remainder degree %d >= divisor degree %d", rem.Degree(), q.Degree())
+	}
+	if !quo.Mul(q).Add(rem).Equal(p) {
+		t.Fatalf("q * quo + rem != p")
+	}
+}
+
+func TestGCDOfRoots(t *testing.T) {
+	a, b, c := randElement(), randElement(), randElement()
+	// (x - a)(x - b) and (x - a)(x - c) share exactly the root a
+	p := NewPoly(a, One).Mul(NewPoly(b, One))
+	q := NewPoly(a, One).Mul(NewPoly(c, One))
+	if g := GCD(p, q); !g.Equal(NewPoly(a, One)) {
+		t.Fatalf("gcd = %v, want x + %s", g, a)
+	}
+}
+
+func TestGHASHPoly(t *testing.T) {
+	h := randElement()
+	aad := utils.RandBytes(20)
+	ct := utils.RandBytes(37)
+	if !GHASHPoly(aad, ct).Eval(h).Equal(GHASH(h, aad, ct)) {
+		t.Fatalf("GHASHPoly(h) != GHASH(h)")
+	}
+}
+
+func TestTableMul(t *testing.T) {
+	h := randElement()
+	tab := NewTable(h)
+	for i := 0; i < 20; i++ {
+		a := randElement()
+		if !tab.Mul(a).Equal(a.Mul(h)) {
+			t.Fatalf("table mul of %s by %s differs from Mul", a, h)
+		}
+	}
+}
+
+func TestMatrices(t *testing.T) {
+	a, b := randElement(), randElement()
+	if got := FromVector(a.Matrix().MulVec(b.Vector())); !got.Equal(a.Mul(b)) {
+		t.Fatalf("M_a * b = %s, want %s", got, a.Mul(b))
+	}
+	if got := FromVector(SquareMatrix().MulVec(b.Vector())); !got.Equal(b.Square()) {
+		t.Fatalf("S * b = %s, want %s", got, b.Square())
+	}
+}
@@ -0,0 +1,70 @@
+package gf128
+
+import "encoding/binary"
+
+// GHASH returns the GCM hash of aad and ct under the hash key h. Both inputs
+// are zero padded to a multiple of the block size and followed by a block
+// holding their lengths in bits
+func GHASH(h Element, aad, ct []byte) Element {
+	return NewTable(h).GHASH(aad, ct)
+}
+
+// GHASH returns the GCM hash of aad and ct under the element the table was
+// built for
+func (t *Table) GHASH(aad, ct []byte) Element {
+	var y Element
+	y = t.ghashUpdate(y, aad)
+	y = t.ghashUpdate(y, ct)
+	lens := make([]byte, BlockSize)
+	binary.BigEndian.PutUint64(lens[:8], uint64(len(aad))*8)
+	binary.BigEndian.PutUint64(lens[8:], uint64(len(ct))*8)
+	return t.Mul(y.Add(FromBytes(lens)))
+}
+
+func (t *Table) ghashUpdate(y Element, b []byte) Element {
+	for len(b) >= BlockSize {
+		y.hi ^= binary.BigEndian.Uint64(b)
+		y.lo ^= binary.BigEndian.Uint64(b[8:])
+		y = t.Mul(y)
+		b = b[BlockSize:]
+	}
+	for i := 0; i < len(b); i += BlockSize {
+		end := i + BlockSize
+		if end > len(b) {
+			end = len(b)
+		}
+		y = t.Mul(y.Add(FromBytes(b[i:end])))
+	}
+	return y
+}
+
+// GHASHPoly returns the polynomial whose value at h is GHASH(h, aad, ct). Its
+// coefficients are the blocks of aad, ct and the length block and its constant
+// term is zero, so adding the mask block to it gives the polynomial of the tag
+func GHASHPoly(aad, ct []byte) Poly {
+	var blocks []Element
+	blocks = appendBlocks(blocks, aad)
+	blocks = appendBlocks(blocks, ct)
+	lens := make([]byte, BlockSize)
+	binary.BigEndian.PutUint64(lens[:8], uint64(len(aad))*8)
+	binary.BigEndian.PutUint64(lens[8:], uint64(len(ct))*8)
+	blocks = append(blocks, FromBytes(lens))
+
+	// the first block gets multiplied by the highest power of h
+	p := make(Poly, len(blocks)+1)
+	for i, b := range blocks {
+		p[len(blocks)-i] = b
+	}
+	return p.trim()
+}
+
+func appendBlocks(blocks []Element, b []byte) []Element {
+	for i := 0; i < len(b); i += BlockSize {
+		end := i + BlockSize
+		if end > len(b) {
+			end = len(b)
+		}
+		blocks = append(blocks, FromBytes(b[i:end]))
+	}
+	return blocks
+}
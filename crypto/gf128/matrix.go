@@ -0,0 +1,45 @@
+package gf128
+
+import "github.com/sukunrt/cryptopals/crypto/gf2"
+
+// Vector returns the coefficients of a as a GF(2) vector. Entry i is the
+// coefficient of x^i
+func (a Element) Vector() gf2.Vector {
+	v := gf2.NewVector(128)
+	for i := 0; i < 128; i++ {
+		v.Set(i, a.Bit(i))
+	}
+	return v
+}
+
+// FromVector returns the element with the coefficients in v
+func FromVector(v gf2.Vector) Element {
+	var a Element
+	for i := 0; i < 128; i++ {
+		a = a.SetBit(i, v.Get(i))
+	}
+	return a
+}
+
+// Matrix returns M_a, the matrix of multiplication by a. For every b,
+// a.Matrix().MulVec(b.Vector()) is a.Mul(b).Vector()
+func (a Element) Matrix() gf2.Matrix {
+	cols := make([]gf2.Vector, 128)
+	xj := a
+	for j := 0; j < 128; j++ {
+		cols[j] = xj.Vector()
+		xj = xj.MulX()
+	}
+	return gf2.FromColumns(128, cols...)
+}
+
+// SquareMatrix returns the matrix of squaring, which is linear in
+// characteristic 2. For every b, SquareMatrix().MulVec(b.Vector()) is
+// b.Square().Vector()
+func SquareMatrix() gf2.Matrix {
+	cols := make([]gf2.Vector, 128)
+	for j := 0; j < 128; j++ {
+		cols[j] = Zero.SetBit(j, 1).Square().Vector()
+	}
+	return gf2.FromColumns(128, cols...)
+}
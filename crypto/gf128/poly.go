@@ -0,0 +1,172 @@
+package gf128
+
+// Poly is a polynomial over GF(2^128). p[i] is the coefficient of x^i.
+// Polynomials returned by the methods here have no leading zero coefficients
+type Poly []Element
+
+// NewPoly returns the polynomial with the coefficients coeffs, lowest degree first
+func NewPoly(coeffs ...Element) Poly {
+	p := make(Poly, len(coeffs))
+	copy(p, coeffs)
+	return p.trim()
+}
+
+// trim drops the leading zero coefficients
+func (p Poly) trim() Poly {
+	n := len(p)
+	for n > 0 && p[n-1].IsZero() {
+		n--
+	}
+	return p[:n]
+}
+
+// Degree returns the degree of p. The zero polynomial has degree -1
+func (p Poly) Degree() int {
+	return len(p.trim()) - 1
+}
+
+// IsZero reports whether p is the zero polynomial
+func (p Poly) IsZero() bool {
+	return p.Degree() < 0
+}
+
+// Lead returns the leading coefficient of p
+func (p Poly) Lead() Element {
+	p = p.trim()
+	if len(p) == 0 {
+		return Zero
+	}
+	return p[len(p)-1]
+}
+
+// Equal reports whether p and q are the same polynomial
+func (p Poly) Equal(q Poly) bool {
+	p, q = p.trim(), q.trim()
+	if len(p) != len(q) {
+		return false
+	}
+	for i := range p {
+		if p[i] != q[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Add returns p + q. Subtraction is the same operation
+func (p Poly) Add(q Poly) Poly {
+	if len(p) < len(q) {
+		p, q = q, p
+	}
+	res := make(Poly, len(p))
+	copy(res, p)
+	for i := range q {
+		res[i] = res[i].Add(q[i])
+	}
+	return res.trim()
+}
+
+// Scale returns c * p
+func (p Poly) Scale(c Element) Poly {
+	res := make(Poly, len(p))
+	for i := range p {
+		res[i] = p[i].Mul(c)
+	}
+	return res.trim()
+}
+
+// Mul returns p * q
+func (p Poly) Mul(q Poly) Poly {
+	p, q = p.trim(), q.trim()
+	if len(p) == 0 || len(q) == 0 {
+		return nil
+	}
+	res := make(Poly, len(p)+len(q)-1)
+	for i := range p {
+		if p[i].IsZero() {
+			continue
+		}
+		for j := range q {
+			res[i+j] = res[i+j].Add(p[i].Mul(q[j]))
+		}
+	}
+	return res.trim()
+}
+
+// DivMod returns the quotient and remainder of p divided by q. It panics if
+// q is zero
+func (p Poly) DivMod(q Poly) (Poly, Poly) {
+	q = q.trim()
+	if len(q) == 0 {
+		panic("gf128: division by the zero polynomial")
+	}
+	rem := make(Poly, len(p))
+	copy(rem, p)
+	rem = rem.trim()
+	if len(rem) < len(q) {
+		return nil, rem
+	}
+	quo := make(Poly, len(rem)-len(q)+1)
+	inv := q[len(q)-1].Inverse()
+	for len(rem) >= len(q) {
+		shift := len(rem) - len(q)
+		c := rem[len(rem)-1].Mul(inv)
+		quo[shift] = c
+		for i := range q {
+			rem[shift+i] = rem[shift+i].Add(c.Mul(q[i]))
+		}
+		rem = rem.trim()
+	}
+	return quo.trim(), rem
+}
+
+// Mod returns p mod q
+func (p Poly) Mod(q Poly) Poly {
+	_, rem := p.DivMod(q)
+	return rem
+}
+
+// MulMod returns p * q mod m
+func (p Poly) MulMod(q, m Poly) Poly {
+	return p.Mul(q).Mod(m)
+}
+
+// Monic returns p divided by its leading coefficient
+func (p Poly) Monic() Poly {
+	p = p.trim()
+	if len(p) == 0 {
+		return p
+	}
+	return p.Scale(p[len(p)-1].Inverse())
+}
+
+// Derivative returns the formal derivative of p. The terms with an even
+// exponent vanish in characteristic 2
+func (p Poly) Derivative() Poly {
+	if len(p) <= 1 {
+		return nil
+	}
+	res := make(Poly, len(p)-1)
+	for i := 1; i < len(p); i += 2 {
+		res[i-1] = p[i]
+	}
+	return res.trim()
+}
+
+// Eval returns the value of p at x
+func (p Poly) Eval(x Element) Element {
+	var y Element
+	for i := len(p) - 1; i >= 0; i-- {
+		y = y.Mul(x).Add(p[i])
+	}
+	return y
+}
+
+// GCD returns the monic greatest common divisor of p and q
+func GCD(p, q Poly) Poly {
+	p, q = p.trim(), q.trim()
+	for !q.IsZero() {
+		p, q = q, p.Mod(q)
+	}
+	return p.Monic()
+}
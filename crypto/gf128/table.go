@@ -0,0 +1,41 @@
+package gf128
+
+import "math/bits"
+
+// Table multiplies by a fixed element with one lookup per byte of the other
+// operand. GHASH multiplies every block by the same hash key so building the
+// table once is much cheaper than calling Mul for every block
+type Table [BlockSize][256]Element
+
+// NewTable returns the multiplication table of h
+func NewTable(h Element) *Table {
+	var t Table
+	// basis[i] = x^i * h
+	var basis [128]Element
+	basis[0] = h
+	for i := 1; i < 128; i++ {
+		basis[i] = basis[i-1].MulX()
+	}
+	for k := 0; k < BlockSize; k++ {
+		for v := 1; v < 256; v++ {
+			// the most significant bit of byte k is the coefficient of x^(8k)
+			bit := 7 - bits.TrailingZeros(uint(v))
+			t[k][v] = t[k][v&(v-1)].Add(basis[8*k+bit])
+		}
+	}
+	return &t
+}
+
+// Mul returns a * h where h is the element the table was built for
+func (t *Table) Mul(a Element) Element {
+	var z Element
+	for k := 0; k < 8; k++ {
+		e := &t[k][byte(a.hi>>(56-8*k))]
+		z.hi ^= e.hi
+		z.lo ^= e.lo
+		e = &t[8+k][byte(a.lo>>(56-8*k))]
+		z.hi ^= e.hi
+		z.lo ^= e.lo
+	}
+	return z
+}
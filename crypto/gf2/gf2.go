@@ -0,0 +1,306 @@
+// Package gf2 implements vectors and matrices over GF(2) packed 64 entries
+// to a word, with the row reduction and kernel computations needed for
+// linear attacks.
+package gf2
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// Vector is a vector over GF(2). The zero value is an empty vector
+type Vector struct {
+	n int
+	w []uint64
+}
+
+// NewVector returns the zero vector of length n
+func NewVector(n int) Vector {
+	return Vector{n: n, w: make([]uint64, (n+63)/64)}
+}
+
+// Len returns the number of entries of v
+func (v Vector) Len() int {
+	return v.n
+}
+
+// Get returns entry i of v
+func (v Vector) Get(i int) uint {
+	return uint(v.w[i/64]>>(i%64)) & 1
+}
+
+// Set sets entry i of v to b
+func (v Vector) Set(i int, b uint) {
+	v.w[i/64] = v.w[i/64]&^(1<<(i%64)) | uint64(b&1)<<(i%64)
+}
+
+// Clone returns a copy of v
+func (v Vector) Clone() Vector {
+	u := Vector{n: v.n, w: make([]uint64, len(v.w))}
+	copy(u.w, v.w)
+	return u
+}
+
+// XorInPlace adds u to v
+func (v Vector) XorInPlace(u Vector) {
+	if v.n != u.n {
+		panic(fmt.Sprintf("gf2: vector lengths %d and %d differ", v.n, u.n))
+	}
+	for i := range v.w {
+		v.w[i] ^= u.w[i]
+	}
+}
+
+// Xor returns v + u
+func (v Vector) Xor(u Vector) Vector {
+	res := v.Clone()
+	res.XorInPlace(u)
+	return res
+}
+
+// Dot returns the inner product of v and u
+func (v Vector) Dot(u Vector) uint {
+	if v.n != u.n {
+		panic(fmt.Sprintf("gf2: vector lengths %d and %d differ", v.n, u.n))
+	}
+	c := 0
+	for i := range v.w {
+		c += bits.OnesCount64(v.w[i] & u.w[i])
+	}
+	return uint(c & 1)
+}
+
+// IsZero reports whether every entry of v is zero
+func (v Vector) IsZero() bool {
+	for _, w := range v.w {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether v and u are the same vector
+func (v Vector) Equal(u Vector) bool {
+	if v.n != u.n {
+		return false
+	}
+	for i := range v.w {
+		if v.w[i] != u.w[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (v Vector) String() string {
+	var sb strings.Builder
+	for i := 0; i < v.n; i++ {
+		sb.WriteByte('0' + byte(v.Get(i)))
+	}
+	return sb.String()
+}
+
+// Matrix is a matrix over GF(2) stored as a slice of row vectors
+type Matrix struct {
+	rows, cols int
+	r          []Vector
+}
+
+// NewMatrix returns the zero matrix with the given dimensions
+func NewMatrix(rows, cols int) Matrix {
+	m := Matrix{rows: rows, cols: cols, r: make([]Vector, rows)}
+	for i := range m.r {
+		m.r[i] = NewVector(cols)
+	}
+	return m
+}
+
+// Identity returns the n x n identity matrix
+func Identity(n int) Matrix {
+	m := NewMatrix(n, n)
+	for i := 0; i < n; i++ {
+		m.Set(i, i, 1)
+	}
+	return m
+}
+
+// FromRows returns the matrix with the given rows. The rows are not copied
+func FromRows(cols int, rows ...Vector) Matrix {
+	for _, r := range rows {
+		if r.Len() != cols {
+			panic(fmt.Sprintf("gf2: row of length %d in a matrix with %d columns", r.Len(), cols))
+		}
+	}
+	return Matrix{rows: len(rows), cols: cols, r: rows}
+}
+
+// FromColumns returns the matrix with the given columns
+func FromColumns(rows int, cols ...Vector) Matrix {
+	m := NewMatrix(rows, len(cols))
+	for j, c := range cols {
+		for i := 0; i < rows; i++ {
+			m.Set(i, j, c.Get(i))
+		}
+	}
+	return m
+}
+
+// Rows returns the number of rows of m
+func (m Matrix) Rows() int {
+	return m.rows
+}
+
+// Cols returns the number of columns of m
+func (m Matrix) Cols() int {
+	return m.cols
+}
+
+// Get returns the entry in row i and column j
+func (m Matrix) Get(i, j int) uint {
+	return m.r[i].Get(j)
+}
+
+// Set sets the entry in row i and column j to b
+func (m Matrix) Set(i, j int, b uint) {
+	m.r[i].Set(j, b)
+}
+
+// Row returns row i of m. It shares storage with m
+func (m Matrix) Row(i int) Vector {
+	return m.r[i]
+}
+
+// Column returns a copy of column j of m
+func (m Matrix) Column(j int) Vector {
+	v := NewVector(m.rows)
+	for i := 0; i < m.rows; i++ {
+		v.Set(i, m.Get(i, j))
+	}
+	return v
+}
+
+// Clone returns a copy of m
+func (m Matrix) Clone() Matrix {
+	res := Matrix{rows: m.rows, cols: m.cols, r: make([]Vector, m.rows)}
+	for i := range m.r {
+		res.r[i] = m.r[i].Clone()
+	}
+	return res
+}
+
+// Transpose returns the transpose of m
+func (m Matrix) Transpose() Matrix {
+	res := NewMatrix(m.cols, m.rows)
+	for i := 0; i < m.rows; i++ {
+		for j := 0; j < m.cols; j++ {
+			if m.Get(i, j) == 1 {
+				res.Set(j, i, 1)
+			}
+		}
+	}
+	return res
+}
+
+// Add returns m + n
+func (m Matrix) Add(n Matrix) Matrix {
+	if m.rows != n.rows || m.cols != n.cols {
+		panic(fmt.Sprintf("gf2: cannot add %dx%d and %dx%d matrices", m.rows, m.cols, n.rows, n.cols))
+	}
+	res := m.Clone()
+	for i := range res.r {
+		res.r[i].XorInPlace(n.r[i])
+	}
+	return res
+}
+
+// Mul returns m * n
+func (m Matrix) Mul(n Matrix) Matrix {
+	if m.cols != n.rows {
+		panic(fmt.Sprintf("gf2: cannot multiply %dx%d and %dx%d matrices", m.rows, m.cols, n.rows, n.cols))
+	}
+	res := NewMatrix(m.rows, n.cols)
+	for i := 0; i < m.rows; i++ {
+		for k := 0; k < m.cols; k++ {
+			if m.Get(i, k) == 1 {
+				res.r[i].XorInPlace(n.r[k])
+			}
+		}
+	}
+	return res
+}
+
+// MulVec returns m * v
+func (m Matrix) MulVec(v Vector) Vector {
+	if m.cols != v.Len() {
+		panic(fmt.Sprintf("gf2: cannot multiply %dx%d matrix and vector of length %d", m.rows, m.cols, v.Len()))
+	}
+	res := NewVector(m.rows)
+	for i := 0; i < m.rows; i++ {
+		res.Set(i, m.r[i].Dot(v))
+	}
+	return res
+}
+
+// AppendRows returns m with rows added below it
+func (m Matrix) AppendRows(rows ...Vector) Matrix {
+	return FromRows(m.cols, append(m.Clone().r, rows...)...)
+}
+
+// RowReduce returns the reduced row echelon form of m and the pivot column of
+// each of its nonzero rows
+func (m Matrix) RowReduce() (Matrix, []int) {
+	res := m.Clone()
+	var pivots []int
+	row := 0
+	for col := 0; col < res.cols && row < res.rows; col++ {
+		p := -1
+		for i := row; i < res.rows; i++ {
+			if res.Get(i, col) == 1 {
+				p = i
+				break
+			}
+		}
+		if p == -1 {
+			continue
+		}
+		res.r[row], res.r[p] = res.r[p], res.r[row]
+		for i := 0; i < res.rows; i++ {
+			if i != row && res.Get(i, col) == 1 {
+				res.r[i].XorInPlace(res.r[row])
+			}
+		}
+		pivots = append(pivots, col)
+		row++
+	}
+	return res, pivots
+}
+
+// Rank returns the rank of m
+func (m Matrix) Rank() int {
+	_, pivots := m.RowReduce()
+	return len(pivots)
+}
+
+// Kernel returns a basis of the vectors v with m * v = 0
+func (m Matrix) Kernel() []Vector {
+	rref, pivots := m.RowReduce()
+	isPivot := make([]bool, m.cols)
+	for _, p := range pivots {
+		isPivot[p] = true
+	}
+	var basis []Vector
+	for free := 0; free < m.cols; free++ {
+		if isPivot[free] {
+			continue
+		}
+		v := NewVector(m.cols)
+		v.Set(free, 1)
+		for i, p := range pivots {
+			v.Set(p, rref.Get(i, free))
+		}
+		basis = append(basis, v)
+	}
+	return basis
+}
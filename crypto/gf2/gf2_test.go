@@ -0,0 +1,57 @@
+package gf2
+
+import (
+	"testing"
+
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+func randMatrix(rows, cols int) Matrix {
+	m := NewMatrix(rows, cols)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			m.Set(i, j, uint(utils.RandIntn(2)))
+		}
+	}
+	return m
+}
+
+func TestKernel(t *testing.T) {
+	m := randMatrix(40, 100)
+	ker := m.Kernel()
+	if len(ker)+m.Rank() != m.Cols() {
+		t.Fatalf("rank %d + nullity %d != %d columns", m.Rank(), len(ker), m.Cols())
+	}
+	for _, v := range ker {
+		if !m.MulVec(v).IsZero() {
+			t.Fatalf("m * %s is not zero", v)
+		}
+	}
+}
+
+func TestRowReduce(t *testing.T) {
+	m := randMatrix(70, 70)
+	rref, pivots := m.RowReduce()
+	for i, p := range pivots {
+		for j := 0; j < rref.Rows(); j++ {
+			want := uint(0)
+			if j == i {
+				want = 1
+			}
+			if rref.Get(j, p) != want {
+				t.Fatalf("pivot column %d is not a unit vector", p)
+			}
+		}
+	}
+}
+
+func TestMulTranspose(t *testing.T) {
+	a, b := randMatrix(10, 20), randMatrix(20, 30)
+	lhs := a.Mul(b).Transpose()
+	rhs := b.Transpose().Mul(a.Transpose())
+	for i := 0; i < lhs.Rows(); i++ {
+		if !lhs.Row(i).Equal(rhs.Row(i)) {
+			t.Fatalf("(ab)^T != b^T a^T")
+		}
+	}
+}
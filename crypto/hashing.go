@@ -2,7 +2,6 @@ package crypto
 
 import (
 	"encoding/binary"
-	"fmt"
 
 	"github.com/sukunrt/cryptopals/hashing"
 	"github.com/sukunrt/cryptopals/hashing/md4"
@@ -87,7 +86,10 @@ func ExtractMD4State(checksum []byte) [4]uint32 {
 	return res
 }
 
-func BreakPrefixSHA1(b, checkSum []byte, validatorF func([]byte, []byte) bool) {
+// BreakPrefixSHA1 extends b with ";admin=true" using a length extension on the
+// secret prefix mac checkSum. It returns the forged message and its mac once
+// validatorF accepts them
+func BreakPrefixSHA1(b, checkSum []byte, validatorF func([]byte, []byte) bool) ([]byte, []byte, error) {
 	suffix := []byte(";admin=true")
 	for i := 0; i < 100; i++ {
 		paddedMsg := AddSHA1Padding(b, len(b)+i)
@@ -96,13 +98,14 @@ func BreakPrefixSHA1(b, checkSum []byte, validatorF func([]byte, []byte) bool) {
 		sh.Write(suffix)
 		ncs := sh.Sum(nil)
 		if validatorF(inputMsg, ncs[:]) {
-			fmt.Println("PWN")
-			break
+			return inputMsg, ncs, nil
 		}
 	}
+	return nil, nil, ErrAttackFailed
 }
 
-func BreakPrefixMD4(b, checkSum []byte, validatorF func([]byte, []byte) bool) {
+// BreakPrefixMD4 is BreakPrefixSHA1 for MD4
+func BreakPrefixMD4(b, checkSum []byte, validatorF func([]byte, []byte) bool) ([]byte, []byte, error) {
 	suffix := []byte(";admin=true")
 	for i := 0; i < 100; i++ {
 		paddedMsg := AddMD4Padding(b, len(b)+i)
@@ -111,8 +114,8 @@ func BreakPrefixMD4(b, checkSum []byte, validatorF func([]byte, []byte) bool) {
 		sh.Write(suffix)
 		ncs := sh.Sum(nil)
 		if validatorF(inputMsg, ncs[:]) {
-			fmt.Println("PWN")
-			break
+			return inputMsg, ncs, nil
 		}
 	}
+	return nil, nil, ErrAttackFailed
 }
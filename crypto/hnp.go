@@ -0,0 +1,94 @@
+package crypto
+
+import (
+	"fmt"
+	"math/big"
+
+	bi "github.com/sukunrt/bigint"
+	"github.com/sukunrt/cryptopals/crypto/lattice"
+)
+
+// HNPSample is an instance of the hidden number problem: b = T*x - U mod q is
+// known to lie in [0, q/2^l)
+type HNPSample struct {
+	T, U bi.Int
+}
+
+// SolveHNP returns the candidates for the hidden number x of samples mod q,
+// whose b values have l bits fewer than q. Shifting U by q/2^(l+1) centres
+// b on zero, and then the lattice spanned by the rows
+//
+//	q  0 ... 0  0    0
+//	0  q ... 0  0    0
+//	      ...
+//	t1 t2 ...  ct   0
+//	u1 u2 ...  0    cu
+//
+// with ct = 1/2^(l+1) and cu = q/2^(l+1) holds (b1, ..., bn, x*ct, -cu),
+// which is short when there are enough samples, so LLL tends to find it.
+// Everything is scaled by 2^(l+1) to keep the entries integers
+func SolveHNP(samples []HNPSample, q bi.Int, l int) []bi.Int {
+	n := len(samples)
+	scale := bi.Exp(bi.Two, bi.FromInt(l+1), bi.Zero)
+	shift := q.Div(scale)
+	row := func() []bi.Int {
+		r := make([]bi.Int, n+2)
+		for i := range r {
+			r[i] = bi.Zero
+		}
+		return r
+	}
+	basis := make([]lattice.Vector, 0, n+2)
+	for i := 0; i < n; i++ {
+		r := row()
+		r[i] = q.Mul(scale)
+		basis = append(basis, lattice.FromInts(r...))
+	}
+	rt, ru := row(), row()
+	for i, s := range samples {
+		rt[i] = s.T.Mod(q).Mul(scale)
+		ru[i] = s.U.Add(shift).Mod(q).Mul(scale)
+	}
+	rt[n], ru[n+1] = bi.One, q
+	basis = append(basis, lattice.FromInts(rt...), lattice.FromInts(ru...))
+
+	var candidates []bi.Int
+	for _, v := range lattice.LLL(basis, big.NewRat(99, 100)) {
+		last, _ := v.Int(n + 1)
+		xct, _ := v.Int(n)
+		switch {
+		case last.Equal(q):
+			candidates = append(candidates, bi.Zero.Sub(xct).Mod(q))
+		case last.Equal(bi.Zero.Sub(q)):
+			candidates = append(candidates, xct.Mod(q))
+		}
+	}
+	return candidates
+}
+
+// SignatureSample is a DSA or ECDSA signature (R, S) of a message with hash H
+type SignatureSample struct {
+	H, R, S bi.Int
+}
+
+// BiasedNonceAttack recovers the private key behind DSA or ECDSA signatures
+// whose nonces k have their low l bits set to zero. q is the order of the
+// group and isKey reports whether a candidate is the private key, checking it
+// against the public key.
+//
+// k = (h + x*r)/s mod q, so with k = 2^l * b the unknown b = x*r/(s*2^l) +
+// h/(s*2^l) mod q is less than q/2^l, an instance of the hidden number problem
+func BiasedNonceAttack(sigs []SignatureSample, q bi.Int, l int, isKey func(bi.Int) bool) (bi.Int, error) {
+	inv := ModInv(bi.Exp(bi.Two, bi.FromInt(l), bi.Zero), q)
+	samples := make([]HNPSample, len(sigs))
+	for i, sig := range sigs {
+		w := ModInv(sig.S, q).Mul(inv).Mod(q)
+		samples[i] = HNPSample{T: sig.R.Mul(w).Mod(q), U: bi.Zero.Sub(sig.H).Mul(w).Mod(q)}
+	}
+	for _, x := range SolveHNP(samples, q, l) {
+		if isKey(x) {
+			return x, nil
+		}
+	}
+	return bi.Zero, fmt.Errorf("no candidate from %d signatures is the key: %w", len(sigs), ErrAttackFailed)
+}
@@ -0,0 +1,168 @@
+// Package lattice implements LLL reduction of lattice bases with exact
+// rational arithmetic.
+package lattice
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	bi "github.com/sukunrt/bigint"
+)
+
+// Vector is a vector with rational entries
+type Vector []*big.Rat
+
+// NewVector returns the zero vector of length n
+func NewVector(n int) Vector {
+	v := make(Vector, n)
+	for i := range v {
+		v[i] = new(big.Rat)
+	}
+	return v
+}
+
+// FromInts returns the vector with the given integer entries
+func FromInts(xs ...bi.Int) Vector {
+	v := make(Vector, len(xs))
+	for i, x := range xs {
+		v[i], _ = new(big.Rat).SetString(x.String())
+	}
+	return v
+}
+
+// Clone returns a copy of v
+func (v Vector) Clone() Vector {
+	u := make(Vector, len(v))
+	for i, x := range v {
+		u[i] = new(big.Rat).Set(x)
+	}
+	return u
+}
+
+// Dot returns the inner product of v and u
+func (v Vector) Dot(u Vector) *big.Rat {
+	if len(v) != len(u) {
+		panic(fmt.Sprintf("lattice: vector lengths %d and %d differ", len(v), len(u)))
+	}
+	res, t := new(big.Rat), new(big.Rat)
+	for i := range v {
+		res.Add(res, t.Mul(v[i], u[i]))
+	}
+	return res
+}
+
+// SubScaled sets v to v - c*u
+func (v Vector) SubScaled(c *big.Rat, u Vector) {
+	t := new(big.Rat)
+	for i := range v {
+		v[i].Sub(v[i], t.Mul(c, u[i]))
+	}
+}
+
+// Int returns entry i of v and whether it is an integer
+func (v Vector) Int(i int) (bi.Int, bool) {
+	if !v[i].IsInt() {
+		return bi.Zero, false
+	}
+	return bi.FromBigInt(v[i].Num()), true
+}
+
+func (v Vector) String() string {
+	s := make([]string, len(v))
+	for i, x := range v {
+		s[i] = x.RatString()
+	}
+	return "[" + strings.Join(s, " ") + "]"
+}
+
+// round returns the integer closest to x
+func round(x *big.Rat) *big.Rat {
+	t := new(big.Rat).Add(x, big.NewRat(1, 2))
+	// big.Int.Div rounds towards -inf for positive divisors
+	f := new(big.Int).Div(t.Num(), t.Denom())
+	return new(big.Rat).SetInt(f)
+}
+
+// DefaultDelta is the usual Lovász constant 3/4
+var DefaultDelta = big.NewRat(3, 4)
+
+// LLL returns an LLL reduced basis of the lattice spanned by the linearly
+// independent vectors in basis, with Lovász constant delta in (1/4, 1). The
+// first vector of the result is within a factor of 2^((n-1)/2) of the
+// shortest vector of the lattice. basis is left unchanged
+func LLL(basis []Vector, delta *big.Rat) []Vector {
+	n := len(basis)
+	b := make([]Vector, n)
+	for i, v := range basis {
+		b[i] = v.Clone()
+	}
+	// bs are the Gram-Schmidt vectors, B their squared lengths and mu the
+	// coefficients with b[i] = bs[i] + sum_j<i mu[i][j] bs[j]
+	bs := make([]Vector, n)
+	B := make([]*big.Rat, n)
+	mu := make([][]*big.Rat, n)
+	for i := range b {
+		mu[i] = make([]*big.Rat, n)
+		bs[i] = b[i].Clone()
+		for j := 0; j < i; j++ {
+			mu[i][j] = new(big.Rat).Quo(b[i].Dot(bs[j]), B[j])
+			bs[i].SubScaled(mu[i][j], bs[j])
+		}
+		B[i] = bs[i].Dot(bs[i])
+		if B[i].Sign() == 0 {
+			panic("lattice: basis vectors are linearly dependent")
+		}
+	}
+	half := big.NewRat(1, 2)
+	// reduce makes |mu[k][l]| <= 1/2 by subtracting a multiple of b[l] from b[k]
+	reduce := func(k, l int) {
+		if new(big.Rat).Abs(mu[k][l]).Cmp(half) <= 0 {
+			return
+		}
+		q := round(mu[k][l])
+		b[k].SubScaled(q, b[l])
+		t := new(big.Rat)
+		for j := 0; j < l; j++ {
+			mu[k][j].Sub(mu[k][j], t.Mul(q, mu[l][j]))
+		}
+		mu[k][l].Sub(mu[k][l], q)
+	}
+	// swap exchanges b[k] and b[k-1] and updates the Gram-Schmidt data
+	swap := func(k int) {
+		b[k], b[k-1] = b[k-1], b[k]
+		for j := 0; j < k-1; j++ {
+			mu[k][j], mu[k-1][j] = mu[k-1][j], mu[k][j]
+		}
+		m := mu[k][k-1]
+		bn := new(big.Rat).Mul(m, m)
+		bn.Mul(bn, B[k-1]).Add(bn, B[k])
+		mu[k][k-1] = new(big.Rat).Quo(new(big.Rat).Mul(m, B[k-1]), bn)
+		B[k] = new(big.Rat).Quo(new(big.Rat).Mul(B[k-1], B[k]), bn)
+		B[k-1] = bn
+		t := new(big.Rat)
+		for i := k + 1; i < n; i++ {
+			u := mu[i][k]
+			mu[i][k] = new(big.Rat).Sub(mu[i][k-1], t.Mul(m, u))
+			mu[i][k-1] = new(big.Rat).Add(u, t.Mul(mu[k][k-1], mu[i][k]))
+		}
+	}
+	for k := 1; k < n; {
+		reduce(k, k-1)
+		// Lovász condition: B[k] >= (delta - mu[k][k-1]^2) B[k-1]
+		c := new(big.Rat).Mul(mu[k][k-1], mu[k][k-1])
+		c.Sub(delta, c).Mul(c, B[k-1])
+		if B[k].Cmp(c) < 0 {
+			swap(k)
+			if k > 1 {
+				k--
+			}
+			continue
+		}
+		for l := k - 2; l >= 0; l-- {
+			reduce(k, l)
+		}
+		k++
+	}
+	return b
+}
@@ -0,0 +1,77 @@
+package lattice
+
+import (
+	"math/big"
+	"testing"
+
+	bi "github.com/sukunrt/bigint"
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+func ints(xs ...int) Vector {
+	v := make([]bi.Int, len(xs))
+	for i, x := range xs {
+		v[i] = bi.FromInt(x)
+	}
+	return FromInts(v...)
+}
+
+func TestLLLKnownBasis(t *testing.T) {
+	basis := []Vector{ints(1, 1, 1), ints(-1, 0, 2), ints(3, 5, 6)}
+	want := []Vector{ints(0, 1, 0), ints(1, 0, 1), ints(-1, 0, 2)}
+	got := LLL(basis, DefaultDelta)
+	for i := range want {
+		if got[i].String() != want[i].String() {
+			t.Fatalf("reduced basis = %v, want %v", got, want)
+		}
+	}
+	if basis[0].String() != "[1 1 1]" {
+		t.Fatal("LLL modified its input")
+	}
+}
+
+// checkReduced checks that b is size reduced and satisfies the Lovász
+// condition for delta
+func checkReduced(t *testing.T, b []Vector, delta *big.Rat) {
+	t.Helper()
+	n := len(b)
+	bs := make([]Vector, n)
+	B := make([]*big.Rat, n)
+	half := big.NewRat(1, 2)
+	for i := range b {
+		bs[i] = b[i].Clone()
+		var mu *big.Rat
+		for j := 0; j < i; j++ {
+			mu = new(big.Rat).Quo(b[i].Dot(bs[j]), B[j])
+			if new(big.Rat).Abs(mu).Cmp(half) > 0 {
+				t.Fatalf("mu[%d][%d] = %s is not size reduced", i, j, mu.RatString())
+			}
+			bs[i].SubScaled(mu, bs[j])
+		}
+		B[i] = bs[i].Dot(bs[i])
+		if i > 0 {
+			c := new(big.Rat).Mul(mu, mu)
+			c.Sub(delta, c).Mul(c, B[i-1])
+			if B[i].Cmp(c) < 0 {
+				t.Fatalf("Lovász condition fails at %d", i)
+			}
+		}
+	}
+}
+
+func TestLLLRandomBasis(t *testing.T) {
+	n := 8
+	basis := make([]Vector, n)
+	for i := range basis {
+		xs := make([]int, n)
+		for j := range xs {
+			xs[j] = utils.RandIntn(1<<20) - 1<<19
+		}
+		// keep the basis independent
+		xs[i] += 1 << 22
+		basis[i] = ints(xs...)
+	}
+	checkReduced(t, LLL(basis, DefaultDelta), DefaultDelta)
+	delta := big.NewRat(99, 100)
+	checkReduced(t, LLL(basis, delta), delta)
+}
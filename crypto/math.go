@@ -3,12 +3,29 @@ package crypto
 import (
 	"errors"
 	"fmt"
-	"math/rand"
+	"math/big"
 
 	bi "github.com/sukunrt/bigint"
 	"github.com/sukunrt/cryptopals/utils"
 )
 
+// RandInt returns a uniform random integer in [0, n) drawn from the module's
+// randomness source. It panics if n <= 0
+func RandInt(n bi.Int) bi.Int {
+	if n.Cmp(bi.Zero) <= 0 {
+		panic("RandInt: n must be positive")
+	}
+	nb := len(n.Bytes())
+	mask := byte(0xff >> (8*nb - n.BitLen()))
+	for {
+		b := utils.RandBytes(nb)
+		b[0] &= mask
+		if x := bi.FromBytes(b); x.Cmp(n) < 0 {
+			return x
+		}
+	}
+}
+
 func RandPrime() bi.Int {
 	for {
 		x := utils.RandBytes(128)
@@ -49,7 +66,14 @@ func egcd(a, b bi.Int) (bi.Int, bi.Int) {
 	}
 }
 
+// ModInv returns the inverse of a mod n. math/big handles the common case
+// where the inverse exists, it is an order of magnitude faster than egcd and
+// the curve arithmetic spends most of its time here
 func ModInv(a, n bi.Int) bi.Int {
+	nb := new(big.Int).SetBytes(n.Bytes())
+	if r := new(big.Int).ModInverse(new(big.Int).SetBytes(a.Mod(n).Bytes()), nb); r != nil {
+		return bi.FromBigInt(r)
+	}
 	x, _ := egcd(a, n)
 	x = x.Mod(n)
 	for x.Cmp(bi.Zero) < 0 {
@@ -58,6 +82,42 @@ func ModInv(a, n bi.Int) bi.Int {
 	return x
 }
 
+// SmoothDiscreteLog finds x such that g^x = h mod the prime p with the
+// Pohlig-Hellman algorithm. factors are the distinct primes whose product is
+// p-1, they need to be small since each one is searched exhaustively
+func SmoothDiscreteLog(h, g, p bi.Int, factors []bi.Int) (bi.Int, error) {
+	p1 := p.Sub(bi.One)
+	xs := make([]bi.Int, len(factors))
+	for i, r := range factors {
+		e := p1.Div(r)
+		gi, hi := bi.Exp(g, e, p), bi.Exp(h, e, p)
+		found := false
+		y := bi.One
+		for x := bi.Zero; x.Cmp(r) < 0; x = x.Add(bi.One) {
+			if y.Equal(hi) {
+				xs[i], found = x, true
+				break
+			}
+			y = y.Mul(gi).Mod(p)
+		}
+		if !found {
+			return bi.Zero, fmt.Errorf("%s is not a power of %s mod %s: %w", h, g, p, ErrAttackFailed)
+		}
+	}
+	return CRT(xs, factors), nil
+}
+
+// ModSqrt returns a square root of a mod the odd prime p and whether a is a
+// quadratic residue
+func ModSqrt(a, p bi.Int) (bi.Int, bool) {
+	pb := new(big.Int).SetBytes(p.Bytes())
+	r := new(big.Int).ModSqrt(new(big.Int).SetBytes(a.Mod(p).Bytes()), pb)
+	if r == nil {
+		return bi.Zero, false
+	}
+	return bi.FromBigInt(r), true
+}
+
 func CRT(c, n []bi.Int) bi.Int {
 	N := bi.FromInt(1)
 	for _, nn := range n {
@@ -102,7 +162,7 @@ func MillerRabinRounds(n bi.Int, rounds int) bool {
 	}
 outer:
 	for i := 0; i < rounds; i++ {
-		a := bi.FromInt(rand.Intn(mx) + 2)
+		a := bi.FromInt(utils.RandIntn(mx) + 2)
 		st := bi.Exp(a, d, n)
 		if st.Equal(n.Sub(bi.One)) || st.Equal(bi.One) {
 			continue
@@ -131,7 +191,6 @@ func MillerRabin(n bi.Int) bool {
 func PollardKangarooDiscreteLog(target, a, b, g, p bi.Int) (bi.Int, error) {
 	tries := 5
 	for i := 0; i < tries; i++ {
-		fmt.Println(i)
 		cache := make(map[string]bi.Int)
 		rv := func(y, k, m bi.Int) bi.Int {
 			s := fmt.Sprintf("%s|%s", y.Mod(k), m)
@@ -147,7 +206,6 @@ func PollardKangarooDiscreteLog(target, a, b, g, p bi.Int) (bi.Int, error) {
 		N := jump.Mul(bi.FromInt(4))
 		y := bi.Exp(g, b, p)
 		x := bi.Zero
-		fmt.Println(N)
 		for i := bi.Zero; i.Cmp(N) < 0; i = i.Add(bi.One) {
 			j := rv(y, magic, jump)
 			x = x.Add(j)
@@ -155,7 +213,6 @@ func PollardKangarooDiscreteLog(target, a, b, g, p bi.Int) (bi.Int, error) {
 		}
 		ty := target
 		tx := bi.Zero
-		fmt.Println(N)
 		for {
 			j := rv(ty, magic, jump)
 			if tx.Add(j).Cmp(b.Sub(a).Add(x)) > 0 || ty.Equal(y) {
@@ -46,3 +46,40 @@ func TestPollardKangarooDiscreteLogBig(t *testing.T) {
 		t.Fatalf("didn't get expected y")
 	}
 }
+
+func TestSmoothDiscreteLog(t *testing.T) {
+	// 2*3*5*7*11*13*17*19*23*31 + 1 is prime and 7 generates its group
+	p := bi.FromInt(6915878971)
+	var factors []bi.Int
+	for _, r := range []int{2, 3, 5, 7, 11, 13, 17, 19, 23, 31} {
+		factors = append(factors, bi.FromInt(r))
+	}
+	g := bi.FromInt(7)
+	x := RandInt(p.Sub(bi.One))
+	got, err := SmoothDiscreteLog(bi.Exp(g, x, p), g, p, factors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(x) {
+		t.Fatalf("got %s, want %s", got, x)
+	}
+}
+
+func TestRandInt(t *testing.T) {
+	n := bi.FromInt(1000)
+	for i := 0; i < 100; i++ {
+		if x := RandInt(n); x.Cmp(bi.Zero) < 0 || x.Cmp(n) >= 0 {
+			t.Fatalf("RandInt(1000) returned %s", x.String())
+		}
+	}
+	for _, n := range []bi.Int{bi.Zero, bi.FromInt(-5)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("RandInt(%s) didn't panic", n.String())
+				}
+			}()
+			RandInt(n)
+		}()
+	}
+}
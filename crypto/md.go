@@ -28,14 +28,21 @@ func (m *MD) Copy() *MD {
 	}
 }
 
+// compress encrypts block with the padded state as the key and keeps the
+// first Hsz bytes as the next state
+func (m *MD) compress(block []byte) {
+	c, _ := newAESCipher(utils.PadBytes(m.H, AESBlockSize)[:AESBlockSize])
+	h := make([]byte, AESBlockSize)
+	c.Encrypt(h, block)
+	m.H = h[:m.Hsz]
+}
+
 func (m *MD) Hash(b []byte, initH []byte) []byte {
 	m.H = make([]byte, m.Hsz)
 	copy(m.H, initH)
-	cipher := NewAESInCBCCipher(utils.PadBytes(m.H, AESBlockSize))
 	b = utils.PadBytes(b, AESBlockSize)
 	for i := 0; i < len(b); i += AESBlockSize {
-		m.H = cipher.Encrypt(b[i:i+AESBlockSize], make([]byte, AESBlockSize))[:m.Hsz]
-		cipher = NewAESInCBCCipher(utils.PadBytes(m.H, AESBlockSize))
+		m.compress(b[i : i+AESBlockSize])
 	}
 	return m.H
 }
@@ -44,10 +51,8 @@ func (m *MD) WriteBlock(b []byte) ([]byte, error) {
 	if len(b)%AESBlockSize != 0 {
 		return nil, errors.New("invalid message size")
 	}
-	cipher := NewAESInCBCCipher(utils.PadBytes(m.H, AESBlockSize))
 	for i := 0; i < len(b); i += AESBlockSize {
-		m.H = cipher.Encrypt(b[i:i+AESBlockSize], make([]byte, AESBlockSize))[:m.Hsz]
-		cipher = NewAESInCBCCipher(utils.PadBytes(m.H, AESBlockSize))
+		m.compress(b[i : i+AESBlockSize])
 	}
 	return m.H, nil
 }
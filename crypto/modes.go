@@ -0,0 +1,138 @@
+package crypto
+
+import (
+	"crypto/cipher"
+
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+// checkBlocks panics the way the crypto/cipher modes do when src isn't whole
+// blocks or dst can't hold it
+func checkBlocks(src, dst []byte) {
+	if len(src)%AESBlockSize != 0 {
+		panic("crypto: input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("crypto: output smaller than input")
+	}
+}
+
+// xorInto sets dst to a ^ b over the length of b
+func xorInto(dst, a, b []byte) {
+	for i := range b {
+		dst[i] = a[i] ^ b[i]
+	}
+}
+
+// ecbMode is AESInECBCipher as a cipher.BlockMode
+type ecbMode struct {
+	b       cipher.Block
+	decrypt bool
+}
+
+func (m ecbMode) BlockSize() int { return AESBlockSize }
+
+func (m ecbMode) CryptBlocks(dst, src []byte) {
+	checkBlocks(src, dst)
+	for i := 0; i < len(src); i += AESBlockSize {
+		if m.decrypt {
+			m.b.Decrypt(dst[i:i+AESBlockSize], src[i:i+AESBlockSize])
+		} else {
+			m.b.Encrypt(dst[i:i+AESBlockSize], src[i:i+AESBlockSize])
+		}
+	}
+}
+
+// Encrypter returns the cipher.BlockMode encrypting with c
+func (c AESInECBCipher) Encrypter() cipher.BlockMode {
+	return ecbMode{b: c.cipher}
+}
+
+// Decrypter returns the cipher.BlockMode decrypting with c
+func (c AESInECBCipher) Decrypter() cipher.BlockMode {
+	return ecbMode{b: c.cipher, decrypt: true}
+}
+
+// cbcMode is AESInCBCCipher as a cipher.BlockMode. iv is the last ciphertext
+// block seen so consecutive calls chain
+type cbcMode struct {
+	b       cipher.Block
+	iv, tmp []byte
+	decrypt bool
+}
+
+func newCBCMode(b cipher.Block, iv []byte, decrypt bool) (*cbcMode, error) {
+	if err := checkIV(iv, AESBlockSize); err != nil {
+		return nil, err
+	}
+	return &cbcMode{
+		b:       b,
+		iv:      append([]byte{}, iv...),
+		tmp:     make([]byte, AESBlockSize),
+		decrypt: decrypt,
+	}, nil
+}
+
+func (m *cbcMode) BlockSize() int { return AESBlockSize }
+
+func (m *cbcMode) CryptBlocks(dst, src []byte) {
+	checkBlocks(src, dst)
+	for i := 0; i < len(src); i += AESBlockSize {
+		in, out := src[i:i+AESBlockSize], dst[i:i+AESBlockSize]
+		if m.decrypt {
+			// in and out may be the same block, keep the ciphertext for the next iv
+			copy(m.tmp, in)
+			m.b.Decrypt(out, in)
+			xorInto(out, out, m.iv)
+			m.iv, m.tmp = m.tmp, m.iv
+		} else {
+			xorInto(m.tmp, in, m.iv)
+			m.b.Encrypt(out, m.tmp)
+			copy(m.iv, out)
+		}
+	}
+}
+
+// Encrypter returns the cipher.BlockMode encrypting with ac from iv
+func (ac AESInCBCCipher) Encrypter(iv []byte) (cipher.BlockMode, error) {
+	return newCBCMode(ac.cipher, iv, false)
+}
+
+// Decrypter returns the cipher.BlockMode decrypting with ac from iv
+func (ac AESInCBCCipher) Decrypter(iv []byte) (cipher.BlockMode, error) {
+	return newCBCMode(ac.cipher, iv, true)
+}
+
+// ctrStream is AESInCTRCipher as a cipher.Stream. ks is what is left of the
+// keystream block for round-1
+type ctrStream struct {
+	c     AESInCTRCipher
+	round int
+	ks    []byte
+}
+
+func (s *ctrStream) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("crypto: output smaller than input")
+	}
+	for len(src) > 0 {
+		if len(s.ks) == 0 {
+			s.ks = s.c.getKey(s.round)
+			s.round++
+		}
+		n := utils.MinInt(len(s.ks), len(src))
+		xorInto(dst, s.ks, src[:n])
+		dst, src, s.ks = dst[n:], src[n:], s.ks[n:]
+	}
+}
+
+// Stream returns the keystream of ac as a cipher.Stream starting offset bytes in.
+// Encryption and decryption are the same
+func (ac AESInCTRCipher) Stream(offset int) cipher.Stream {
+	s := &ctrStream{c: ac, round: offset / AESBlockSize}
+	if skip := offset % AESBlockSize; skip != 0 {
+		s.ks = ac.getKey(s.round)[skip:]
+		s.round++
+	}
+	return s
+}
@@ -0,0 +1,143 @@
+package crypto
+
+import (
+	stdcrypto "crypto"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+// ErrRSADecryption is the only error the padding checks of a decryption
+// return, telling the caller more than that is what padding oracles feed on
+var ErrRSADecryption = errors.New("rsa: decryption error")
+
+// OAEPOptions configures RSAES-OAEP. The zero value uses SHA-256 for both the
+// label hash and MGF1 and an empty label
+type OAEPOptions struct {
+	Hash stdcrypto.Hash
+	// MGFHash is the hash MGF1 is built on, Hash if zero
+	MGFHash stdcrypto.Hash
+	Label   []byte
+}
+
+func (o OAEPOptions) hashes() (h, mgf stdcrypto.Hash, err error) {
+	h, mgf = o.Hash, o.MGFHash
+	if h == 0 {
+		h = stdcrypto.SHA256
+	}
+	if mgf == 0 {
+		mgf = h
+	}
+	for _, x := range []stdcrypto.Hash{h, mgf} {
+		if !x.Available() {
+			return 0, 0, fmt.Errorf("%v: %w", x, ErrUnsupportedHash)
+		}
+	}
+	return h, mgf, nil
+}
+
+// MGF1 returns n bytes of the mask generation function from RFC 8017 B.2.1,
+// the hashes of seed followed by a 4 byte counter
+func MGF1(h stdcrypto.Hash, seed []byte, n int) []byte {
+	res := make([]byte, 0, n+h.Size())
+	hf := h.New()
+	for c := uint32(0); len(res) < n; c++ {
+		hf.Reset()
+		hf.Write(seed)
+		hf.Write([]byte{byte(c >> 24), byte(c >> 16), byte(c >> 8), byte(c)})
+		res = hf.Sum(res)
+	}
+	return res[:n]
+}
+
+// EncodeOAEP returns the EME-OAEP encoding of msg in emLen bytes,
+// 00 || seed ^ MGF(db) || db ^ MGF(seed) with db = lHash || 00 .. 00 || 01 || msg
+func EncodeOAEP(msg []byte, emLen int, opts OAEPOptions) ([]byte, error) {
+	h, mgf, err := opts.hashes()
+	if err != nil {
+		return nil, err
+	}
+	hLen := h.Size()
+	if len(msg) > emLen-2*hLen-2 {
+		return nil, ErrRSAMsgTooLong
+	}
+	hf := h.New()
+	hf.Write(opts.Label)
+	db := make([]byte, emLen-hLen-1)
+	copy(db, hf.Sum(nil))
+	db[len(db)-len(msg)-1] = 1
+	copy(db[len(db)-len(msg):], msg)
+	seed := utils.RandBytes(hLen)
+
+	maskedDB := utils.XorBytes(db, MGF1(mgf, seed, len(db)))
+	maskedSeed := utils.XorBytes(seed, MGF1(mgf, maskedDB, hLen))
+	return utils.ConcatBytes([]byte{0}, maskedSeed, maskedDB), nil
+}
+
+// DecodeOAEP undoes EncodeOAEP. Every check is made whether or not an
+// earlier one failed and they all end in the same ErrRSADecryption, so
+// neither the error nor the time taken says which one it was
+func DecodeOAEP(em []byte, opts OAEPOptions) ([]byte, error) {
+	h, mgf, err := opts.hashes()
+	if err != nil {
+		return nil, err
+	}
+	hLen := h.Size()
+	if len(em) < 2*hLen+2 {
+		return nil, ErrRSADecryption
+	}
+	hf := h.New()
+	hf.Write(opts.Label)
+	lHash := hf.Sum(nil)
+
+	maskedSeed, maskedDB := em[1:1+hLen], em[1+hLen:]
+	seed := utils.XorBytes(maskedSeed, MGF1(mgf, maskedDB, hLen))
+	db := utils.XorBytes(maskedDB, MGF1(mgf, seed, len(maskedDB)))
+
+	good := subtle.ConstantTimeByteEq(em[0], 0)
+	good &= subtle.ConstantTimeCompare(db[:hLen], lHash)
+	// find the 01 after the zeros without branching on where it is
+	lookingFor01, index := 1, 0
+	for i := hLen; i < len(db); i++ {
+		is0, is1 := subtle.ConstantTimeByteEq(db[i], 0), subtle.ConstantTimeByteEq(db[i], 1)
+		index = subtle.ConstantTimeSelect(lookingFor01&is1, i, index)
+		lookingFor01 = subtle.ConstantTimeSelect(is1, 0, lookingFor01)
+		good &= subtle.ConstantTimeSelect(lookingFor01, is0, 1)
+	}
+	good &= 1 ^ lookingFor01
+	if good != 1 {
+		return nil, ErrRSADecryption
+	}
+	return db[index+1:], nil
+}
+
+// EncryptOAEP encrypts msg with RSAES-OAEP
+func (k RSAKey) EncryptOAEP(msg []byte, opts OAEPOptions) ([]byte, error) {
+	em, err := EncodeOAEP(msg, k.Sz, opts)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptRSA(em, k.E, k.N, k.Sz), nil
+}
+
+// DecryptOAEP decrypts the RSAES-OAEP ciphertext c
+func (r RSA) DecryptOAEP(c []byte, opts OAEPOptions) ([]byte, error) {
+	if len(c) != r.Sz {
+		return nil, ErrRSADecryption
+	}
+	em := r.privateBytes(c)
+	if em == nil {
+		return nil, ErrRSADecryption
+	}
+	return DecodeOAEP(em, opts)
+}
+
+// OAEPLeadingZero reports whether c decrypts to a block starting with a zero
+// byte. A server that returns early when it doesn't, the first check OAEP
+// decoding makes, leaks this and gives Manger's attack its oracle
+func OAEPLeadingZero(c []byte, r RSA) bool {
+	em := r.privateBytes(c)
+	return em != nil && em[0] == 0
+}
@@ -0,0 +1,129 @@
+package crypto
+
+import (
+	"bytes"
+	stdcrypto "crypto"
+	"crypto/rand"
+	stdrsa "crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// stdPrivateKey converts r to a crypto/rsa key
+func stdPrivateKey(r RSA) *stdrsa.PrivateKey {
+	k := &stdrsa.PrivateKey{
+		PublicKey: stdrsa.PublicKey{N: toBig(r.N), E: int(r.E.Int())},
+		D:         toBig(r.D),
+		Primes:    []*big.Int{toBig(r.P), toBig(r.Q)},
+	}
+	k.Precompute()
+	return k
+}
+
+func TestOAEP(t *testing.T) {
+	r, err := NewRSA(RSAOptions{Bits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	std := stdPrivateKey(r)
+	msg := []byte("hello world")
+	label := []byte("label")
+
+	c, err := r.PubKey().EncryptOAEP(msg, OAEPOptions{Label: label})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := stdrsa.DecryptOAEP(sha256.New(), rand.Reader, std, c, label); err != nil || !bytes.Equal(got, msg) {
+		t.Fatalf("crypto/rsa decrypted to %q, %v", got, err)
+	}
+	c, err = stdrsa.EncryptOAEP(sha1.New(), rand.Reader, &std.PublicKey, msg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := r.DecryptOAEP(c, OAEPOptions{Hash: stdcrypto.SHA1}); err != nil || !bytes.Equal(got, msg) {
+		t.Fatalf("decrypted to %q, %v", got, err)
+	}
+
+	opts := OAEPOptions{Hash: stdcrypto.SHA384, MGFHash: stdcrypto.SHA1, Label: label}
+	for _, m := range [][]byte{{}, msg, bytes.Repeat([]byte{1}, r.Sz-2*48-2)} {
+		c, err := r.PubKey().EncryptOAEP(m, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := r.DecryptOAEP(c, opts); err != nil || !bytes.Equal(got, m) {
+			t.Fatalf("decrypted to %q, %v", got, err)
+		}
+		for _, wrong := range []OAEPOptions{
+			{Hash: stdcrypto.SHA384, MGFHash: stdcrypto.SHA1},
+			{Hash: stdcrypto.SHA384, Label: label},
+		} {
+			if _, err := r.DecryptOAEP(c, wrong); !errors.Is(err, ErrRSADecryption) {
+				t.Fatalf("%+v: err = %v", wrong, err)
+			}
+		}
+	}
+	if _, err := r.PubKey().EncryptOAEP(make([]byte, r.Sz-2*48-1), opts); !errors.Is(err, ErrRSAMsgTooLong) {
+		t.Fatalf("long message: err = %v", err)
+	}
+}
+
+// TestPaddingOracleQueries runs Bleichenbacher's and Manger's attacks on the
+// same key. Manger's oracle leaks less often but says more when it does
+func TestPaddingOracleQueries(t *testing.T) {
+	r, err := NewRSA(RSAOptions{Bits: 512})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("kick it, CC")
+
+	pkcsQueries := 0
+	m := BreakRSAWithPaddingOracle(EncryptRSAWithPadding(msg, r), func(b []byte) bool {
+		pkcsQueries++
+		return ValidPadding(b, r)
+	}, r)
+	if got, err := RemovePadding(m); err != nil || !bytes.Equal(got, msg) {
+		t.Fatalf("Bleichenbacher recovered %q, %v", got, err)
+	}
+
+	mangerQueries := 0
+	// SHA-256 leaves no room for msg in 512 bits
+	opts := OAEPOptions{Hash: stdcrypto.SHA1}
+	c, err := r.PubKey().EncryptOAEP(msg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	em, err := BreakRSAWithMangerOracle(c, func(b []byte) bool {
+		mangerQueries++
+		return OAEPLeadingZero(b, r)
+	}, r.PubKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := DecodeOAEP(em, opts); err != nil || !bytes.Equal(got, msg) {
+		t.Fatalf("Manger recovered %q, %v", got, err)
+	}
+	t.Logf("Bleichenbacher: %d queries, Manger: %d queries", pkcsQueries, mangerQueries)
+	if mangerQueries > 2*r.N.BitLen() {
+		t.Fatalf("Manger's attack took %d queries on a %d bit key", mangerQueries, r.N.BitLen())
+	}
+}
+
+func TestRemovePadding(t *testing.T) {
+	r := NewRSAN(32)
+	msg := []byte("hello")
+	if got, err := RemovePadding(PadBlock(msg, r)); err != nil || !bytes.Equal(got, msg) {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	for _, b := range [][]byte{
+		append([]byte{0, 1}, bytes.Repeat([]byte{0xff}, 20)...),
+		append([]byte{0, 2, 1, 2, 3, 0}, bytes.Repeat([]byte{1}, 20)...),
+		append([]byte{0, 2}, bytes.Repeat([]byte{1}, 20)...),
+	} {
+		if _, err := RemovePadding(b); !errors.Is(err, ErrRSADecryption) {
+			t.Fatalf("%x: err = %v", b, err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package crypto
+
+import (
+	"fmt"
+
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+// PaddingScheme pads messages for a block mode and checks and removes the
+// padding again. The pad byte of the schemes that have one is the length of
+// the padding, so blockSize can't be more than 255
+type PaddingScheme interface {
+	// Pad pads b to a multiple of blockSize bytes
+	Pad(b []byte, blockSize int) []byte
+	// Unpad checks and removes the padding of b, returning an error wrapping
+	// ErrInvalidPadding if it is invalid
+	Unpad(b []byte, blockSize int) ([]byte, error)
+	// Suffix returns the last k bytes of a validly padded block, for k of 2
+	// or more, that stay valid whatever comes before them and become invalid
+	// if the first of them changes. It is nil if there are none, when the
+	// validity of the padding doesn't depend on that byte
+	Suffix(k, blockSize int) []byte
+}
+
+var (
+	// PKCS7Padding pads with n bytes of value n
+	PKCS7Padding PaddingScheme = pkcs7{}
+	// ANSIX923Padding pads with zeros and a last byte of the padding length
+	ANSIX923Padding PaddingScheme = ansiX923{}
+	// ISO7816Padding pads with 0x80 and then zeros
+	ISO7816Padding PaddingScheme = iso7816{}
+	// ISO10126Padding pads with random bytes and a last byte of the padding
+	// length. Only the last byte is checked, so it only leaks the last byte
+	// of every block to a padding oracle
+	ISO10126Padding PaddingScheme = iso10126{}
+)
+
+type pkcs7 struct{}
+
+func (pkcs7) Pad(b []byte, blockSize int) []byte {
+	return utils.PadBytes(b, blockSize)
+}
+
+func (pkcs7) Unpad(b []byte, blockSize int) ([]byte, error) {
+	return utils.Unpad(b, blockSize)
+}
+
+func (pkcs7) Suffix(k, blockSize int) []byte {
+	return utils.RepBytes(byte(k), k)
+}
+
+// padLength returns how many bytes of padding the schemes that always pad
+// add to n bytes
+func padLength(n, blockSize int) int {
+	return blockSize - n%blockSize
+}
+
+// lastBytePad returns the padding length from the last byte of b, checking
+// the length of b and that the pad byte is in range
+func lastBytePad(b []byte, blockSize int) (int, error) {
+	if len(b) == 0 || len(b)%blockSize != 0 {
+		return 0, fmt.Errorf("%d bytes: %w", len(b), utils.ErrPaddedLength)
+	}
+	n := int(b[len(b)-1])
+	if n == 0 || n > blockSize {
+		return 0, fmt.Errorf("pad byte %d: %w", n, utils.ErrPadValue)
+	}
+	return n, nil
+}
+
+type ansiX923 struct{}
+
+func (ansiX923) Pad(b []byte, blockSize int) []byte {
+	n := padLength(len(b), blockSize)
+	res := make([]byte, len(b)+n)
+	copy(res, b)
+	res[len(res)-1] = byte(n)
+	return res
+}
+
+func (ansiX923) Unpad(b []byte, blockSize int) ([]byte, error) {
+	n, err := lastBytePad(b, blockSize)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range b[len(b)-n : len(b)-1] {
+		if c != 0 {
+			return nil, fmt.Errorf("pad byte %d, want 0: %w", c, utils.ErrPadBytes)
+		}
+	}
+	return b[:len(b)-n], nil
+}
+
+func (ansiX923) Suffix(k, blockSize int) []byte {
+	res := make([]byte, k)
+	res[k-1] = byte(k)
+	return res
+}
+
+type iso7816 struct{}
+
+func (iso7816) Pad(b []byte, blockSize int) []byte {
+	res := make([]byte, len(b)+padLength(len(b), blockSize))
+	copy(res, b)
+	res[len(b)] = 0x80
+	return res
+}
+
+func (iso7816) Unpad(b []byte, blockSize int) ([]byte, error) {
+	if len(b) == 0 || len(b)%blockSize != 0 {
+		return nil, fmt.Errorf("%d bytes: %w", len(b), utils.ErrPaddedLength)
+	}
+	// the padding is within the last block
+	for i := len(b) - 1; i >= len(b)-blockSize; i-- {
+		if b[i] == 0x80 {
+			return b[:i], nil
+		}
+		if b[i] != 0 {
+			return nil, fmt.Errorf("pad byte %d, want 0 or 0x80: %w", b[i], utils.ErrPadBytes)
+		}
+	}
+	return nil, fmt.Errorf("no 0x80 in the last block: %w", utils.ErrPadBytes)
+}
+
+func (iso7816) Suffix(k, blockSize int) []byte {
+	res := make([]byte, k)
+	res[0] = 0x80
+	return res
+}
+
+type iso10126 struct{}
+
+func (iso10126) Pad(b []byte, blockSize int) []byte {
+	n := padLength(len(b), blockSize)
+	res := utils.ConcatBytes(b, utils.RandBytes(n))
+	res[len(res)-1] = byte(n)
+	return res
+}
+
+func (iso10126) Unpad(b []byte, blockSize int) ([]byte, error) {
+	n, err := lastBytePad(b, blockSize)
+	if err != nil {
+		return nil, err
+	}
+	return b[:len(b)-n], nil
+}
+
+func (iso10126) Suffix(k, blockSize int) []byte {
+	return nil
+}
@@ -0,0 +1,58 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+func TestPaddingSchemes(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		scheme PaddingScheme
+		// the padding of 13 bytes to 16, nil where it's random
+		want []byte
+	}{
+		{"PKCS7", PKCS7Padding, []byte{3, 3, 3}},
+		{"ANSI X.923", ANSIX923Padding, []byte{0, 0, 3}},
+		{"ISO 7816-4", ISO7816Padding, []byte{0x80, 0, 0}},
+		{"ISO 10126", ISO10126Padding, nil},
+	} {
+		for _, n := range []int{0, 1, 13, 15, 16, 17, 40} {
+			msg := utils.RandBytes(n)
+			padded := c.scheme.Pad(msg, 16)
+			if len(padded)%16 != 0 || len(padded) <= n || !bytes.Equal(padded[:n], msg) {
+				t.Fatalf("%s: %d bytes padded to %x", c.name, n, padded)
+			}
+			if got, err := c.scheme.Unpad(padded, 16); err != nil || !bytes.Equal(got, msg) {
+				t.Fatalf("%s: %d bytes unpadded to %x, %v", c.name, n, got, err)
+			}
+		}
+		if padded := c.scheme.Pad(make([]byte, 13), 16); c.want != nil && !bytes.Equal(padded[13:], c.want) {
+			t.Fatalf("%s: padding is %x", c.name, padded[13:])
+		}
+		if _, err := c.scheme.Unpad(make([]byte, 15), 16); !errors.Is(err, utils.ErrPaddedLength) {
+			t.Fatalf("%s: partial block: err = %v", c.name, err)
+		}
+		// a block of zeros is never valid padding
+		if _, err := c.scheme.Unpad(make([]byte, 16), 16); !errors.Is(err, ErrInvalidPadding) {
+			t.Fatalf("%s: zeros: err = %v", c.name, err)
+		}
+	}
+
+	for _, c := range []struct {
+		name   string
+		scheme PaddingScheme
+		b      []byte
+	}{
+		{"ANSI X.923", ANSIX923Padding, []byte{1, 2, 0, 1, 3}},
+		{"ISO 7816-4", ISO7816Padding, []byte{1, 2, 0x80, 1, 0}},
+		{"ISO 10126", ISO10126Padding, []byte{1, 2, 3, 4, 6}},
+	} {
+		if _, err := c.scheme.Unpad(c.b, 5); !errors.Is(err, ErrInvalidPadding) {
+			t.Fatalf("%s: %x: err = %v", c.name, c.b, err)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package crypto
+
+import (
+	"fmt"
+
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+// PaddingOracleAttack decrypts CBC ciphertexts and encrypts chosen plaintexts
+// with an oracle that reports whether a ciphertext and iv decrypt to validly
+// padded plaintext. Every block is attacked on its own, sent to the oracle as
+// the whole ciphertext after a forged iv
+type PaddingOracleAttack struct {
+	Oracle    func(cipherText, iv []byte) bool
+	BlockSize int
+	Scheme    PaddingScheme
+	// Queries is the number of times the oracle has been asked
+	Queries int
+}
+
+// NewPaddingOracleAttack returns an attack on a CBC mode cipher with
+// blockSize byte blocks, padded with scheme
+func NewPaddingOracleAttack(oracle func([]byte, []byte) bool, blockSize int, scheme PaddingScheme) *PaddingOracleAttack {
+	return &PaddingOracleAttack{Oracle: oracle, BlockSize: blockSize, Scheme: scheme}
+}
+
+func (a *PaddingOracleAttack) query(block, iv []byte) bool {
+	a.Queries++
+	return a.Oracle(block, iv)
+}
+
+// loneLastBytes returns the last bytes that make valid padding whatever the
+// bytes before them are
+func (a *PaddingOracleAttack) loneLastBytes() []byte {
+	var res []byte
+	block := make([]byte, a.BlockSize)
+	for v := 0; v < 1<<8; v++ {
+		valid := true
+		for _, fill := range []byte{0, 0xff} {
+			for i := range block {
+				block[i] = fill
+			}
+			block[len(block)-1] = byte(v)
+			if _, err := a.Scheme.Unpad(block, a.BlockSize); err != nil {
+				valid = false
+			}
+		}
+		if valid {
+			res = append(res, byte(v))
+		}
+	}
+	return res
+}
+
+// lastIntermediate finds the last byte of the decryption of block, before it
+// is xored with the iv. A valid guess can be a false positive, longer padding
+// that depends on the byte before, so each one is checked again with that
+// byte changed. Schemes that accept more than one last byte on its own, like
+// ISO 10126, need a valid guess for each of them to pin it down
+func (a *PaddingOracleAttack) lastIntermediate(block, iv []byte) (byte, error) {
+	n := a.BlockSize
+	lone := a.loneLastBytes()
+	var hits []byte
+	for g := 0; g < 1<<8 && len(hits) < len(lone); g++ {
+		iv[n-1] = byte(g)
+		if !a.query(block, iv) {
+			continue
+		}
+		iv[n-2] ^= 1
+		ok := a.query(block, iv)
+		iv[n-2] ^= 1
+		if ok {
+			hits = append(hits, byte(g))
+		}
+	}
+	var res []byte
+	for x := 0; x < 1<<8; x++ {
+		matches := len(hits) == len(lone)
+		for _, g := range hits {
+			if !bytesContain(lone, byte(x)^g) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			res = append(res, byte(x))
+		}
+	}
+	if len(res) != 1 {
+		return 0, fmt.Errorf("%d candidates for the last byte: %w", len(res), ErrAttackFailed)
+	}
+	return res[0], nil
+}
+
+func bytesContain(b []byte, c byte) bool {
+	for _, v := range b {
+		if v == c {
+			return true
+		}
+	}
+	return false
+}
+
+// intermediate returns the decryption of block before it is xored with the
+// iv. The bytes before the last are found one at a time from the end by
+// making the bytes after them the scheme's suffix
+func (a *PaddingOracleAttack) intermediate(block []byte) ([]byte, error) {
+	n := a.BlockSize
+	iv := utils.RandBytes(n)
+	res := make([]byte, n)
+	last, err := a.lastIntermediate(block, iv)
+	if err != nil {
+		return nil, err
+	}
+	res[n-1] = last
+	for k := 2; k <= n; k++ {
+		pos := n - k
+		suffix := a.Scheme.Suffix(k, n)
+		if suffix == nil {
+			return nil, fmt.Errorf("padding doesn't depend on byte %d of a block: %w", pos, ErrAttackFailed)
+		}
+		copy(iv[pos+1:], utils.XorBytes(res[pos+1:], suffix[1:]))
+		found := false
+		for g := 0; g < 1<<8 && !found; g++ {
+			iv[pos] = byte(g)
+			if !a.query(block, iv) {
+				continue
+			}
+			// ISO 7816 padding is also valid with a 0 here and 0x80 before it
+			if pos > 0 {
+				iv[pos-1] ^= 1
+				ok := a.query(block, iv)
+				iv[pos-1] ^= 1
+				if !ok {
+					continue
+				}
+			}
+			res[pos] = byte(g) ^ suffix[0]
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("could not decode byte %d of a block: %w", pos, ErrAttackFailed)
+		}
+	}
+	return res, nil
+}
+
+// checkCipherText returns an error unless cipherText is whole blocks and iv
+// a block
+func (a *PaddingOracleAttack) checkCipherText(cipherText, iv []byte) error {
+	if len(cipherText) == 0 || len(cipherText)%a.BlockSize != 0 {
+		return ErrPartialBlock
+	}
+	return checkIV(iv, a.BlockSize)
+}
+
+// Decrypt decrypts cipherText, returning the plaintext with its padding
+func (a *PaddingOracleAttack) Decrypt(cipherText, iv []byte) ([]byte, error) {
+	if err := a.checkCipherText(cipherText, iv); err != nil {
+		return nil, err
+	}
+	res := make([]byte, 0, len(cipherText))
+	prev := iv
+	for i := 0; i < len(cipherText); i += a.BlockSize {
+		block := cipherText[i : i+a.BlockSize]
+		inter, err := a.intermediate(block)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i/a.BlockSize, err)
+		}
+		res = append(res, utils.XorBytes(inter, prev)...)
+		prev = block
+	}
+	return res, nil
+}
+
+// LastBytes returns the last plaintext byte of every block of cipherText.
+// Unlike Decrypt it works with every scheme
+func (a *PaddingOracleAttack) LastBytes(cipherText, iv []byte) ([]byte, error) {
+	if err := a.checkCipherText(cipherText, iv); err != nil {
+		return nil, err
+	}
+	res := make([]byte, 0, len(cipherText)/a.BlockSize)
+	prev := iv
+	for i := 0; i < len(cipherText); i += a.BlockSize {
+		block := cipherText[i : i+a.BlockSize]
+		last, err := a.lastIntermediate(block, utils.RandBytes(a.BlockSize))
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i/a.BlockSize, err)
+		}
+		res = append(res, last^prev[a.BlockSize-1])
+		prev = block
+	}
+	return res, nil
+}
+
+// Encrypt pads plainText and encrypts it without the key, CBC-R. It starts
+// from a random last ciphertext block and works backwards, each block is the
+// decryption of the one after it xored with the plaintext block that has to
+// come out of it. The first one is the iv
+func (a *PaddingOracleAttack) Encrypt(plainText []byte) ([]byte, []byte, error) {
+	msg := a.Scheme.Pad(plainText, a.BlockSize)
+	res := make([]byte, len(msg)+a.BlockSize)
+	copy(res[len(msg):], utils.RandBytes(a.BlockSize))
+	for i := len(msg); i > 0; i -= a.BlockSize {
+		inter, err := a.intermediate(res[i : i+a.BlockSize])
+		if err != nil {
+			return nil, nil, fmt.Errorf("block %d: %w", i/a.BlockSize-1, err)
+		}
+		copy(res[i-a.BlockSize:], utils.XorBytes(inter, msg[i-a.BlockSize:i]))
+	}
+	return res[a.BlockSize:], res[:a.BlockSize], nil
+}
@@ -0,0 +1,425 @@
+package crypto
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	bi "github.com/sukunrt/bigint"
+)
+
+var (
+	ErrNoPEMBlock     = errors.New("pem: no PEM block found")
+	ErrUnsupportedKey = errors.New("unsupported key type")
+)
+
+// PEM block types of the encodings below, as written by OpenSSL
+const (
+	PEMRSAPrivateKey = "RSA PRIVATE KEY"
+	PEMRSAPublicKey  = "RSA PUBLIC KEY"
+	PEMPrivateKey    = "PRIVATE KEY"
+	PEMPublicKey     = "PUBLIC KEY"
+	PEMDSAPrivateKey = "DSA PRIVATE KEY"
+	PEMDSAParams     = "DSA PARAMETERS"
+	PEMDHParams      = "DH PARAMETERS"
+	PEMX942DHParams  = "X9.42 DH PARAMETERS"
+)
+
+var (
+	oidRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidDSA = asn1.ObjectIdentifier{1, 2, 840, 10040, 4, 1}
+)
+
+// pkcs1PrivateKey is RSAPrivateKey from RFC 8017. Only two prime keys, version
+// 0, are supported
+type pkcs1PrivateKey struct {
+	Version                     int
+	N, E, D, P, Q, DP, DQ, QInv *big.Int
+}
+
+// pkcs1PublicKey is RSAPublicKey from RFC 8017
+type pkcs1PublicKey struct {
+	N, E *big.Int
+}
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+// pkcs8 is PrivateKeyInfo from RFC 5208, the optional attributes are ignored
+type pkcs8 struct {
+	Version    int
+	Algorithm  algorithmIdentifier
+	PrivateKey []byte
+}
+
+// spki is SubjectPublicKeyInfo from RFC 5280
+type spki struct {
+	Algorithm algorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// dssParams is Dss-Parms from RFC 3279
+type dssParams struct {
+	P, Q, G *big.Int
+}
+
+// dsaPrivateKey is the OpenSSL DSA private key format
+type dsaPrivateKey struct {
+	Version       int
+	P, Q, G, Y, X *big.Int
+}
+
+// pkcs3Params is DHParameter from PKCS#3
+type pkcs3Params struct {
+	P, G               *big.Int
+	PrivateValueLength int `asn1:"optional"`
+}
+
+// x942Params is DomainParameters from X9.42 / RFC 3279. The seed and counter
+// are ignored
+type x942Params struct {
+	P, G, Q    *big.Int
+	J          *big.Int      `asn1:"optional"`
+	Validation asn1.RawValue `asn1:"optional"`
+}
+
+// toBig converts the non-negative x to a big.Int for encoding
+func toBig(x bi.Int) *big.Int {
+	return new(big.Int).SetBytes(x.Bytes())
+}
+
+// fromBig converts xs to bi.Ints, all of them have to be present and
+// positive
+func fromBig(xs ...*big.Int) ([]bi.Int, error) {
+	res := make([]bi.Int, len(xs))
+	for i, x := range xs {
+		if x == nil || x.Sign() <= 0 {
+			return nil, errors.New("asn1: missing or non-positive integer")
+		}
+		res[i] = bi.FromBigInt(x)
+	}
+	return res, nil
+}
+
+func mustMarshal(v any) []byte {
+	der, err := asn1.Marshal(v)
+	if err != nil {
+		// the structures above only hold integers, oids and byte strings
+		panic(err)
+	}
+	return der
+}
+
+// unmarshal parses der into v and rejects trailing data
+func unmarshal(der []byte, v any) error {
+	rest, err := asn1.Unmarshal(der, v)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return errors.New("asn1: trailing data")
+	}
+	return nil
+}
+
+// MarshalRSAPrivateKey returns r as a PKCS#1 RSAPrivateKey
+func MarshalRSAPrivateKey(r RSA) []byte {
+	return mustMarshal(pkcs1PrivateKey{
+		N: toBig(r.N), E: toBig(r.E), D: toBig(r.D),
+		P: toBig(r.P), Q: toBig(r.Q),
+		DP: toBig(r.DP), DQ: toBig(r.DQ), QInv: toBig(r.QInv),
+	})
+}
+
+// ParseRSAPrivateKey parses a PKCS#1 RSAPrivateKey and validates it
+func ParseRSAPrivateKey(der []byte) (RSA, error) {
+	var k pkcs1PrivateKey
+	if err := unmarshal(der, &k); err != nil {
+		return RSA{}, fmt.Errorf("pkcs1: %w", err)
+	}
+	if k.Version != 0 {
+		return RSA{}, fmt.Errorf("pkcs1: version %d: %w", k.Version, ErrUnsupportedKey)
+	}
+	v, err := fromBig(k.N, k.E, k.D, k.P, k.Q, k.DP, k.DQ, k.QInv)
+	if err != nil {
+		return RSA{}, fmt.Errorf("pkcs1: %w", err)
+	}
+	r, err := NewRSAFromComponents(v[0], v[1], v[2], v[3], v[4])
+	if err != nil {
+		return RSA{}, err
+	}
+	if !r.DP.Equal(v[5]) || !r.DQ.Equal(v[6]) || !r.QInv.Equal(v[7]) {
+		return RSA{}, fmt.Errorf("crt values don't match d: %w", ErrRSAInvalidKey)
+	}
+	return r, nil
+}
+
+// MarshalRSAPublicKey returns k as a PKCS#1 RSAPublicKey
+func MarshalRSAPublicKey(k RSAKey) []byte {
+	return mustMarshal(pkcs1PublicKey{N: toBig(k.N), E: toBig(k.E)})
+}
+
+// ParseRSAPublicKey parses a PKCS#1 RSAPublicKey and validates it
+func ParseRSAPublicKey(der []byte) (RSAKey, error) {
+	var k pkcs1PublicKey
+	if err := unmarshal(der, &k); err != nil {
+		return RSAKey{}, fmt.Errorf("pkcs1: %w", err)
+	}
+	v, err := fromBig(k.N, k.E)
+	if err != nil {
+		return RSAKey{}, fmt.Errorf("pkcs1: %w", err)
+	}
+	key := RSAKey{N: v[0], E: v[1], Sz: len(v[0].Bytes())}
+	if err := key.Validate(); err != nil {
+		return RSAKey{}, err
+	}
+	return key, nil
+}
+
+// MarshalDSAParams returns d as Dss-Parms
+func MarshalDSAParams(d DSAParams) []byte {
+	return mustMarshal(dssParams{P: toBig(d.P), Q: toBig(d.Q), G: toBig(d.G)})
+}
+
+// ParseDSAParams parses Dss-Parms and validates them
+func ParseDSAParams(der []byte) (DSAParams, error) {
+	var p dssParams
+	if err := unmarshal(der, &p); err != nil {
+		return DSAParams{}, fmt.Errorf("dsa: %w", err)
+	}
+	v, err := fromBig(p.P, p.Q, p.G)
+	if err != nil {
+		return DSAParams{}, fmt.Errorf("dsa: %w", err)
+	}
+	d := DSAParams{P: v[0], Q: v[1], G: v[2]}
+	if err := d.Validate(); err != nil {
+		return DSAParams{}, err
+	}
+	return d, nil
+}
+
+// MarshalDSAPrivateKey returns d in the OpenSSL DSA private key format
+func MarshalDSAPrivateKey(d DSAPerUserParams) []byte {
+	return mustMarshal(dsaPrivateKey{
+		P: toBig(d.P), Q: toBig(d.Q), G: toBig(d.G),
+		Y: toBig(d.Y), X: toBig(d.X),
+	})
+}
+
+// ParseDSAPrivateKey parses a key in the OpenSSL DSA private key format and
+// validates it. The hash is the default for its Q, SHA-1, SHA-224 or SHA-256
+func ParseDSAPrivateKey(der []byte) (DSAPerUserParams, error) {
+	var k dsaPrivateKey
+	if err := unmarshal(der, &k); err != nil {
+		return DSAPerUserParams{}, fmt.Errorf("dsa: %w", err)
+	}
+	if k.Version != 0 {
+		return DSAPerUserParams{}, fmt.Errorf("dsa: version %d: %w", k.Version, ErrUnsupportedKey)
+	}
+	v, err := fromBig(k.P, k.Q, k.G, k.Y, k.X)
+	if err != nil {
+		return DSAPerUserParams{}, fmt.Errorf("dsa: %w", err)
+	}
+	return newDSAKey(DSAParams{P: v[0], Q: v[1], G: v[2]}, v[3], v[4])
+}
+
+// newDSAKey returns the validated key with the default hash for its Q
+func newDSAKey(params DSAParams, y, x bi.Int) (DSAPerUserParams, error) {
+	d := DSAPerUserParams{DSAParams: params, Y: y, X: x, H: params.DefaultHash()}
+	if err := d.Validate(); err != nil {
+		return DSAPerUserParams{}, err
+	}
+	return d, nil
+}
+
+// MarshalPKCS8PrivateKey returns key, an RSA or a DSAPerUserParams, as a
+// PKCS#8 PrivateKeyInfo
+func MarshalPKCS8PrivateKey(key any) ([]byte, error) {
+	var info pkcs8
+	switch k := key.(type) {
+	case RSA:
+		info.Algorithm = algorithmIdentifier{Algorithm: oidRSA, Parameters: asn1.NullRawValue}
+		info.PrivateKey = MarshalRSAPrivateKey(k)
+	case DSAPerUserParams:
+		info.Algorithm = algorithmIdentifier{Algorithm: oidDSA, Parameters: asn1.RawValue{FullBytes: MarshalDSAParams(k.DSAParams)}}
+		info.PrivateKey = mustMarshal(toBig(k.X))
+	default:
+		return nil, fmt.Errorf("pkcs8: %T: %w", key, ErrUnsupportedKey)
+	}
+	return mustMarshal(info), nil
+}
+
+// ParsePKCS8PrivateKey parses a PKCS#8 PrivateKeyInfo holding an RSA or a DSA
+// key. It returns an RSA or a DSAPerUserParams
+func ParsePKCS8PrivateKey(der []byte) (any, error) {
+	var info pkcs8
+	if err := unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("pkcs8: %w", err)
+	}
+	switch alg := info.Algorithm; {
+	case alg.Algorithm.Equal(oidRSA):
+		return ParseRSAPrivateKey(info.PrivateKey)
+	case alg.Algorithm.Equal(oidDSA):
+		params, err := ParseDSAParams(alg.Parameters.FullBytes)
+		if err != nil {
+			return nil, err
+		}
+		var x *big.Int
+		if err := unmarshal(info.PrivateKey, &x); err != nil {
+			return nil, fmt.Errorf("pkcs8: %w", err)
+		}
+		v, err := fromBig(x)
+		if err != nil {
+			return nil, fmt.Errorf("pkcs8: %w", err)
+		}
+		return newDSAKey(params, bi.Exp(params.G, v[0], params.P), v[0])
+	default:
+		return nil, fmt.Errorf("pkcs8: algorithm %s: %w", alg.Algorithm, ErrUnsupportedKey)
+	}
+}
+
+// MarshalPKIXPublicKey returns the public part of key, an RSA, RSAKey or
+// DSAPerUserParams, as a SubjectPublicKeyInfo
+func MarshalPKIXPublicKey(key any) ([]byte, error) {
+	var info spki
+	var pub []byte
+	switch k := key.(type) {
+	case RSA:
+		return MarshalPKIXPublicKey(k.PubKey())
+	case RSAKey:
+		info.Algorithm = algorithmIdentifier{Algorithm: oidRSA, Parameters: asn1.NullRawValue}
+		pub = MarshalRSAPublicKey(k)
+	case DSAPerUserParams:
+		info.Algorithm = algorithmIdentifier{Algorithm: oidDSA, Parameters: asn1.RawValue{FullBytes: MarshalDSAParams(k.DSAParams)}}
+		pub = mustMarshal(toBig(k.Y))
+	default:
+		return nil, fmt.Errorf("spki: %T: %w", key, ErrUnsupportedKey)
+	}
+	info.PublicKey = asn1.BitString{Bytes: pub, BitLength: 8 * len(pub)}
+	return mustMarshal(info), nil
+}
+
+// ParsePKIXPublicKey parses a SubjectPublicKeyInfo holding an RSA or a DSA
+// key. It returns an RSAKey or a DSAPerUserParams with X zero
+func ParsePKIXPublicKey(der []byte) (any, error) {
+	var info spki
+	if err := unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("spki: %w", err)
+	}
+	pub := info.PublicKey.RightAlign()
+	switch alg := info.Algorithm; {
+	case alg.Algorithm.Equal(oidRSA):
+		return ParseRSAPublicKey(pub)
+	case alg.Algorithm.Equal(oidDSA):
+		params, err := ParseDSAParams(alg.Parameters.FullBytes)
+		if err != nil {
+			return nil, err
+		}
+		var y *big.Int
+		if err := unmarshal(pub, &y); err != nil {
+			return nil, fmt.Errorf("spki: %w", err)
+		}
+		v, err := fromBig(y)
+		if err != nil {
+			return nil, fmt.Errorf("spki: %w", err)
+		}
+		return newDSAKey(params, v[0], bi.Zero)
+	default:
+		return nil, fmt.Errorf("spki: algorithm %s: %w", alg.Algorithm, ErrUnsupportedKey)
+	}
+}
+
+// MarshalDHParams returns g as a PKCS#3 DHParameter, which leaves out the
+// order of G
+func MarshalDHParams(g DHGroup) []byte {
+	return mustMarshal(pkcs3Params{P: toBig(g.P), G: toBig(g.G)})
+}
+
+// ParseDHParams parses a PKCS#3 DHParameter and validates it. O is zero since
+// PKCS#3 doesn't carry the order of G
+func ParseDHParams(der []byte) (DHGroup, error) {
+	var p pkcs3Params
+	if err := unmarshal(der, &p); err != nil {
+		return DHGroup{}, fmt.Errorf("pkcs3: %w", err)
+	}
+	v, err := fromBig(p.P, p.G)
+	if err != nil {
+		return DHGroup{}, fmt.Errorf("pkcs3: %w", err)
+	}
+	g := DHGroup{P: v[0], G: v[1], O: bi.Zero}
+	if err := g.Validate(); err != nil {
+		return DHGroup{}, err
+	}
+	return g, nil
+}
+
+// MarshalX942DHParams returns g as X9.42 DomainParameters with q = O and
+// j = (P-1)/O
+func MarshalX942DHParams(g DHGroup) ([]byte, error) {
+	if g.O.Equal(bi.Zero) {
+		return nil, fmt.Errorf("x9.42: the order of g is needed: %w", ErrDHInvalidParams)
+	}
+	return mustMarshal(x942Params{
+		P: toBig(g.P), G: toBig(g.G), Q: toBig(g.O),
+		J: toBig(g.P.Sub(bi.One).Div(g.O)),
+	}), nil
+}
+
+// ParseX942DHParams parses X9.42 DomainParameters and validates them
+func ParseX942DHParams(der []byte) (DHGroup, error) {
+	var p x942Params
+	if err := unmarshal(der, &p); err != nil {
+		return DHGroup{}, fmt.Errorf("x9.42: %w", err)
+	}
+	v, err := fromBig(p.P, p.G, p.Q)
+	if err != nil {
+		return DHGroup{}, fmt.Errorf("x9.42: %w", err)
+	}
+	g := DHGroup{P: v[0], G: v[1], O: v[2]}
+	if err := g.Validate(); err != nil {
+		return DHGroup{}, err
+	}
+	return g, nil
+}
+
+// EncodePEM returns der in a PEM block of the given type
+func EncodePEM(typ string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+}
+
+// ParsePEM parses the key or parameters in the first PEM block of data,
+// picking the encoding by the block type. It returns an RSA, RSAKey,
+// DSAParams, DSAPerUserParams or DHGroup. Encrypted blocks are not supported
+func ParsePEM(data []byte) (any, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrNoPEMBlock
+	}
+	if len(block.Headers) != 0 {
+		return nil, fmt.Errorf("pem: encrypted %s: %w", block.Type, ErrUnsupportedKey)
+	}
+	switch block.Type {
+	case PEMRSAPrivateKey:
+		return ParseRSAPrivateKey(block.Bytes)
+	case PEMRSAPublicKey:
+		return ParseRSAPublicKey(block.Bytes)
+	case PEMPrivateKey:
+		return ParsePKCS8PrivateKey(block.Bytes)
+	case PEMPublicKey:
+		return ParsePKIXPublicKey(block.Bytes)
+	case PEMDSAPrivateKey:
+		return ParseDSAPrivateKey(block.Bytes)
+	case PEMDSAParams:
+		return ParseDSAParams(block.Bytes)
+	case PEMDHParams:
+		return ParseDHParams(block.Bytes)
+	case PEMX942DHParams:
+		return ParseX942DHParams(block.Bytes)
+	default:
+		return nil, fmt.Errorf("pem: block type %q: %w", block.Type, ErrUnsupportedKey)
+	}
+}
@@ -0,0 +1,223 @@
+package crypto
+
+import (
+	stddsa "crypto/dsa"
+	"crypto/rand"
+	stdrsa "crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"hash"
+	"testing"
+
+	bi "github.com/sukunrt/bigint"
+)
+
+// generated with openssl dhparam 512 and openssl genpkey -genparam -algorithm DHX
+const (
+	opensslDHParams = `-----BEGIN DH PARAMETERS-----
+MEYCQQD8pY0MwPXL0P+A4GiZ90spffaSeXqWCQmkAJsicm0ohWpVc0vJt5tvIYXn
+JPtBF3zDckb2kRt/tnAIgccCPoNHAgEC
+-----END DH PARAMETERS-----
+`
+	opensslX942DHParams = `-----BEGIN X9.42 DH PARAMETERS-----
+MIIBOwKBgQCEhELjsJ5Cr3deWirA9HhKM514a9TauASOTxDjdUZmq6xd3nJxHRwd
+4eSGf6Gb1/zYwdwX6NG4FZVoIVTQgagg4/IC8aDald4bs99+XyEtmLIM3Sm3Sciz
+ZIWa+djAWobtDXM44d6+rx3jnQPFlBw8kSsbLmFWXbtcQP6P3PlhZQKBgGNZXAst
+kk7bILDTC2a+QH1DynNZioIocgllXdmUPS5Uzv05SW4epjXMDjwu3dA2NFM37qFg
+zFKWlg9lVCpva4RLXMKdFFWWS2kcOMB8zj6noEb/x8Bg+m4wlZE+cKN7bd0pD8B6
+EKe/mtYOpWKmOKH0p7WOOufiCF0GrLwz60iZAhUAh3TuXooKILrEYCessOUJvt9n
+JcEwGwMVADn5nu0/tSXOErN8Hlad3Ki0NBF/AgIAnA==
+-----END X9.42 DH PARAMETERS-----
+`
+)
+
+func mustParsePEM(t *testing.T, data []byte) any {
+	t.Helper()
+	k, err := ParsePEM(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestRSAPEM(t *testing.T) {
+	r, err := NewRSA(RSAOptions{Bits: 512})
+	if err != nil {
+		t.Fatal(err)
+	}
+	der8, err := MarshalPKCS8PrivateKey(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range [][]byte{
+		EncodePEM(PEMRSAPrivateKey, MarshalRSAPrivateKey(r)),
+		EncodePEM(PEMPrivateKey, der8),
+	} {
+		if got := mustParsePEM(t, data).(RSA); !got.D.Equal(r.D) || !got.N.Equal(r.N) {
+			t.Fatalf("got a different key back from\n%s", data)
+		}
+	}
+	spki, err := MarshalPKIXPublicKey(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range [][]byte{
+		EncodePEM(PEMRSAPublicKey, MarshalRSAPublicKey(r.PubKey())),
+		EncodePEM(PEMPublicKey, spki),
+	} {
+		if got := mustParsePEM(t, data).(RSAKey); !got.N.Equal(r.N) || !got.E.Equal(r.E) {
+			t.Fatalf("got a different key back from\n%s", data)
+		}
+	}
+
+	// crypto/x509 reads what we write and the other way around
+	std, err := x509.ParsePKCS1PrivateKey(MarshalRSAPrivateKey(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if std.D.Cmp(toBig(r.D)) != 0 {
+		t.Fatal("x509 parsed a different d")
+	}
+	std, err = stdrsa.GenerateKey(rand.Reader, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der8, err = x509.MarshalPKCS8PrivateKey(std)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := ParsePKCS8PrivateKey(der8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !k.(RSA).D.Equal(bi.FromBigInt(std.D)) {
+		t.Fatal("parsed a different d from x509")
+	}
+
+	bad := r
+	bad.D = bad.D.Add(bi.Two)
+	if _, err := ParseRSAPrivateKey(MarshalRSAPrivateKey(bad)); !errors.Is(err, ErrRSAInvalidKey) {
+		t.Fatalf("inconsistent key: err = %v", err)
+	}
+}
+
+func TestDSAPEM(t *testing.T) {
+	params, err := GenerateDSAParams(1024, 160)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := params.GenKey(nil)
+	der8, err := MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range [][]byte{
+		EncodePEM(PEMDSAPrivateKey, MarshalDSAPrivateKey(key)),
+		EncodePEM(PEMPrivateKey, der8),
+	} {
+		if got := mustParsePEM(t, data).(DSAPerUserParams); !got.X.Equal(key.X) || !got.Y.Equal(key.Y) {
+			t.Fatalf("got a different key back from\n%s", data)
+		}
+	}
+	got := mustParsePEM(t, EncodePEM(PEMDSAParams, MarshalDSAParams(params))).(DSAParams)
+	if !got.P.Equal(params.P) || !got.Q.Equal(params.Q) || !got.G.Equal(params.G) {
+		t.Fatal("got different parameters back")
+	}
+
+	spki, err := MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	std, err := x509.ParsePKIXPublicKey(spki)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if std.(*stddsa.PublicKey).Y.Cmp(toBig(key.Y)) != 0 {
+		t.Fatal("x509 parsed a different y")
+	}
+	pub := mustParsePEM(t, EncodePEM(PEMPublicKey, spki)).(DSAPerUserParams)
+	if !pub.Y.Equal(key.Y) || !pub.X.Equal(bi.Zero) {
+		t.Fatal("got a different public key back")
+	}
+}
+
+// a parsed key signs like crypto/dsa, with SHA-1 for a 160 bit q and a
+// longer hash cut down to q's size
+func TestDSAInterop(t *testing.T) {
+	params, err := GenerateDSAParams(1024, 160)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := mustParsePEM(t, EncodePEM(PEMDSAPrivateKey, MarshalDSAPrivateKey(params.GenKey(nil)))).(DSAPerUserParams)
+	std := &stddsa.PrivateKey{
+		PublicKey: stddsa.PublicKey{
+			Parameters: stddsa.Parameters{P: toBig(key.P), Q: toBig(key.Q), G: toBig(key.G)},
+			Y:          toBig(key.Y),
+		},
+		X: toBig(key.X),
+	}
+	msg := []byte("hello world")
+	for _, h := range []hash.Hash{nil, sha256.New()} {
+		if h != nil {
+			key.H = h
+		}
+		key.H.Reset()
+		key.H.Write(msg)
+		// crypto/dsa leaves cutting the hash down to the caller
+		digest := key.H.Sum(nil)[:len(key.Q.Bytes())]
+
+		r, s := key.Sign(msg)
+		if !stddsa.Verify(&std.PublicKey, digest, toBig(r), toBig(s)) {
+			t.Fatalf("%d byte hash: crypto/dsa rejected our signature", key.H.Size())
+		}
+		sr, ss, err := stddsa.Sign(rand.Reader, std, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !key.Verify(msg, bi.FromBigInt(sr), bi.FromBigInt(ss)) {
+			t.Fatalf("%d byte hash: rejected the crypto/dsa signature", key.H.Size())
+		}
+	}
+}
+
+func TestDHPEM(t *testing.T) {
+	g := mustParsePEM(t, []byte(opensslDHParams)).(DHGroup)
+	if !g.G.Equal(bi.Two) || g.P.BitLen() != 512 || !g.O.Equal(bi.Zero) {
+		t.Fatalf("parsed %+v", g)
+	}
+	if got := mustParsePEM(t, EncodePEM(PEMDHParams, MarshalDHParams(g))).(DHGroup); !got.P.Equal(g.P) {
+		t.Fatal("got different parameters back")
+	}
+
+	g = mustParsePEM(t, []byte(opensslX942DHParams)).(DHGroup)
+	if g.P.BitLen() != 1024 || g.O.BitLen() != 160 {
+		t.Fatalf("parsed a %d bit p and %d bit q", g.P.BitLen(), g.O.BitLen())
+	}
+	der, err := MarshalX942DHParams(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mustParsePEM(t, EncodePEM(PEMX942DHParams, der)).(DHGroup); !got.O.Equal(g.O) {
+		t.Fatal("got different parameters back")
+	}
+
+	g.G = g.G.Add(bi.One)
+	der, _ = MarshalX942DHParams(g)
+	if _, err := ParseX942DHParams(der); !errors.Is(err, ErrDHInvalidParams) {
+		t.Fatalf("g of the wrong order: err = %v", err)
+	}
+}
+
+func TestParsePEMErrors(t *testing.T) {
+	if _, err := ParsePEM([]byte("not pem")); !errors.Is(err, ErrNoPEMBlock) {
+		t.Fatalf("err = %v, want %v", err, ErrNoPEMBlock)
+	}
+	data := EncodePEM("EC PRIVATE KEY", []byte{0x30, 0})
+	if _, err := ParsePEM(data); !errors.Is(err, ErrUnsupportedKey) {
+		t.Fatalf("err = %v, want %v", err, ErrUnsupportedKey)
+	}
+	if _, err := ParseRSAPublicKey(append(MarshalRSAPublicKey(RSAKey{N: bi.FromInt(1<<62 + 1), E: bi.Three}), 0)); err == nil {
+		t.Fatal("parsed a key with trailing data")
+	}
+}
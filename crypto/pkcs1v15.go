@@ -0,0 +1,188 @@
+package crypto
+
+import (
+	"bytes"
+	stdcrypto "crypto"
+	_ "crypto/md5"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	bi "github.com/sukunrt/bigint"
+)
+
+var (
+	ErrRSAVerification = errors.New("rsa: verification error")
+	ErrRSAKeyTooSmall  = errors.New("rsa: key too small for the encoded message")
+	ErrUnsupportedHash = errors.New("unsupported hash function")
+)
+
+// pkcs1v15MinPadding is the least number of 0xff bytes EMSA-PKCS1-v1_5 puts
+// before the DigestInfo
+const pkcs1v15MinPadding = 8
+
+// digestInfoPrefixes are the DER encodings of DigestInfo up to the digest,
+// from RFC 8017 section 9.2
+var digestInfoPrefixes = map[stdcrypto.Hash][]byte{
+	stdcrypto.MD5:    {0x30, 0x20, 0x30, 0x0c, 0x06, 0x08, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d, 0x02, 0x05, 0x05, 0x00, 0x04, 0x10},
+	stdcrypto.SHA1:   {0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14},
+	stdcrypto.SHA224: {0x30, 0x2d, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x04, 0x05, 0x00, 0x04, 0x1c},
+	stdcrypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	stdcrypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	stdcrypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+// DigestInfo returns the DER encoded DigestInfo of msg hashed with h
+func DigestInfo(h stdcrypto.Hash, msg []byte) ([]byte, error) {
+	prefix, ok := digestInfoPrefixes[h]
+	if !ok {
+		return nil, fmt.Errorf("%v: %w", h, ErrUnsupportedHash)
+	}
+	hf := h.New()
+	hf.Write(msg)
+	return hf.Sum(append([]byte{}, prefix...)), nil
+}
+
+// EncodePKCS1v15 returns the EMSA-PKCS1-v1_5 encoding of msg hashed with h,
+// 00 01 ff .. ff 00 DigestInfo, in emLen bytes
+func EncodePKCS1v15(h stdcrypto.Hash, msg []byte, emLen int) ([]byte, error) {
+	t, err := DigestInfo(h, msg)
+	if err != nil {
+		return nil, err
+	}
+	if emLen < len(t)+3+pkcs1v15MinPadding {
+		return nil, ErrRSAKeyTooSmall
+	}
+	em := make([]byte, emLen)
+	em[1] = 1
+	for i := 2; i < emLen-len(t)-1; i++ {
+		em[i] = 0xff
+	}
+	copy(em[emLen-len(t):], t)
+	return em, nil
+}
+
+// SignPKCS1v15 signs msg hashed with h with RSASSA-PKCS1-v1_5
+func (r RSA) SignPKCS1v15(h stdcrypto.Hash, msg []byte) ([]byte, error) {
+	em, err := EncodePKCS1v15(h, msg, r.Sz)
+	if err != nil {
+		return nil, err
+	}
+	sig := r.privateBytes(em)
+	if sig == nil {
+		return nil, ErrRSAFault
+	}
+	return sig, nil
+}
+
+// publicBytes raises sig to E and returns the result in Sz bytes
+func (k RSAKey) publicBytes(sig []byte) ([]byte, error) {
+	if len(sig) != k.Sz || bi.FromBytes(sig).Cmp(k.N) >= 0 {
+		return nil, ErrRSAVerification
+	}
+	return EncryptRSA(sig, k.E, k.N, k.Sz), nil
+}
+
+// VerifyPKCS1v15 checks the RSASSA-PKCS1-v1_5 signature sig of msg hashed
+// with h. The encoding is rebuilt from msg and compared as a whole, so
+// nothing in it is left unchecked
+func (k RSAKey) VerifyPKCS1v15(h stdcrypto.Hash, msg, sig []byte) error {
+	em, err := k.publicBytes(sig)
+	if err != nil {
+		return err
+	}
+	want, err := EncodePKCS1v15(h, msg, k.Sz)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(em, want) != 1 {
+		return ErrRSAVerification
+	}
+	return nil
+}
+
+// SloppyPKCS1v15Verifier parses the encoded message from the left the way
+// broken verifiers do instead of rebuilding it. Each field turns on one of
+// the classic mistakes, the zero value is a parser that checks everything
+type SloppyPKCS1v15Verifier struct {
+	// IgnoreTrailing accepts bytes after the digest, the bug behind
+	// Bleichenbacher's e=3 forgery
+	IgnoreTrailing bool
+	// ShortPadding accepts fewer than 8 bytes of 0xff padding
+	ShortPadding bool
+	// LooseDigestInfo only checks the hash OID and the digest length and
+	// skips the length fields and algorithm parameters, so garbage can hide
+	// in the parameters
+	LooseDigestInfo bool
+}
+
+// Verify checks the signature sig of msg hashed with h
+func (v SloppyPKCS1v15Verifier) Verify(k RSAKey, h stdcrypto.Hash, msg, sig []byte) error {
+	prefix, ok := digestInfoPrefixes[h]
+	if !ok {
+		return fmt.Errorf("%v: %w", h, ErrUnsupportedHash)
+	}
+	em, err := k.publicBytes(sig)
+	if err != nil {
+		return err
+	}
+	if em[0] != 0 || em[1] != 1 {
+		return ErrRSAVerification
+	}
+	i := 2
+	for i < len(em) && em[i] == 0xff {
+		i++
+	}
+	if i == len(em) || em[i] != 0 {
+		return ErrRSAVerification
+	}
+	if !v.ShortPadding && i-2 < pkcs1v15MinPadding {
+		return ErrRSAVerification
+	}
+	rest := em[i+1:]
+	if v.LooseDigestInfo {
+		rest, err = skipDigestInfo(rest, prefix)
+		if err != nil {
+			return err
+		}
+	} else {
+		if !bytes.HasPrefix(rest, prefix) {
+			return ErrRSAVerification
+		}
+		rest = rest[len(prefix):]
+	}
+	hf := h.New()
+	hf.Write(msg)
+	digest := hf.Sum(nil)
+	if len(rest) < len(digest) || (!v.IgnoreTrailing && len(rest) != len(digest)) {
+		return ErrRSAVerification
+	}
+	if !bytes.Equal(rest[:len(digest)], digest) {
+		return ErrRSAVerification
+	}
+	return nil
+}
+
+// skipDigestInfo walks the DigestInfo at the start of b the lenient way and
+// returns what follows the digest's OCTET STRING header. prefix is the correct
+// DigestInfo prefix, its OID is the only part that's compared
+func skipDigestInfo(b, prefix []byte) ([]byte, error) {
+	// prefix is 30 len 30 len 06 len OID ...
+	oid := prefix[4 : 6+int(prefix[5])]
+	size := prefix[len(prefix)-1]
+	if len(b) < 4 || b[0] != 0x30 || b[2] != 0x30 {
+		return nil, ErrRSAVerification
+	}
+	algLen := int(b[3])
+	if len(b) < 4+algLen+2 || !bytes.HasPrefix(b[4:4+algLen], oid) {
+		return nil, ErrRSAVerification
+	}
+	b = b[4+algLen:]
+	if b[0] != 0x04 || b[1] != size {
+		return nil, ErrRSAVerification
+	}
+	return b[2:], nil
+}
@@ -0,0 +1,110 @@
+package crypto
+
+import (
+	stdcrypto "crypto"
+	stdrsa "crypto/rsa"
+	"errors"
+	"testing"
+
+	bi "github.com/sukunrt/bigint"
+)
+
+func TestSignPKCS1v15(t *testing.T) {
+	r, err := NewRSA(RSAOptions{Bits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	std := &stdrsa.PublicKey{N: toBig(r.N), E: int(r.E.Int())}
+	msg := []byte("hello world")
+	for _, h := range []stdcrypto.Hash{
+		stdcrypto.MD5, stdcrypto.SHA1, stdcrypto.SHA224,
+		stdcrypto.SHA256, stdcrypto.SHA384, stdcrypto.SHA512,
+	} {
+		sig, err := r.SignPKCS1v15(h, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hf := h.New()
+		hf.Write(msg)
+		if err := stdrsa.VerifyPKCS1v15(std, h, hf.Sum(nil), sig); err != nil {
+			t.Fatalf("%v: crypto/rsa rejected the signature: %v", h, err)
+		}
+		if err := r.PubKey().VerifyPKCS1v15(h, msg, sig); err != nil {
+			t.Fatalf("%v: %v", h, err)
+		}
+		if err := r.PubKey().VerifyPKCS1v15(h, []byte("hello world!"), sig); !errors.Is(err, ErrRSAVerification) {
+			t.Fatalf("%v: tampered message: err = %v", h, err)
+		}
+	}
+	if _, err := r.SignPKCS1v15(stdcrypto.SHA3_256, msg); !errors.Is(err, ErrUnsupportedHash) {
+		t.Fatalf("err = %v, want %v", err, ErrUnsupportedHash)
+	}
+	small, err := NewRSA(RSAOptions{Bits: 256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := small.SignPKCS1v15(stdcrypto.SHA512, msg); !errors.Is(err, ErrRSAKeyTooSmall) {
+		t.Fatalf("err = %v, want %v", err, ErrRSAKeyTooSmall)
+	}
+}
+
+func TestSloppyPKCS1v15Verifier(t *testing.T) {
+	r, err := NewRSA(RSAOptions{Bits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := r.PubKey()
+	msg := []byte("hello world")
+	di, _ := DigestInfo(stdcrypto.SHA256, msg)
+	// sign builds a signature over 00 01 ff*pad 00 t with garbage after it
+	sign := func(pad int, t []byte) []byte {
+		em := make([]byte, r.Sz)
+		em[1] = 1
+		for i := 2; i < 2+pad; i++ {
+			em[i] = 0xff
+		}
+		copy(em[3+pad:], t)
+		for i := 3 + pad + len(t); i < len(em); i++ {
+			em[i] = 0xAB
+		}
+		return r.privateBytes(em)
+	}
+	// the parameters NULL replaced by an OCTET STRING of garbage
+	params := append([]byte{}, di...)
+	params[1] += 2
+	params[3] += 2
+	params[15], params[16] = 0x04, 2
+	params = append(params[:17], append([]byte{0xDE, 0xAD}, params[17:]...)...)
+
+	cases := []struct {
+		name string
+		sig  []byte
+		v    SloppyPKCS1v15Verifier
+	}{
+		{"trailing", sign(r.Sz-3-len(di)-20, di), SloppyPKCS1v15Verifier{IgnoreTrailing: true}},
+		{"short padding", sign(4, di), SloppyPKCS1v15Verifier{ShortPadding: true, IgnoreTrailing: true}},
+		{"parameters", sign(r.Sz-3-len(params), params), SloppyPKCS1v15Verifier{LooseDigestInfo: true}},
+	}
+	for _, c := range cases {
+		if err := c.v.Verify(pub, stdcrypto.SHA256, msg, c.sig); err != nil {
+			t.Fatalf("%s: sloppy verifier rejected the signature: %v", c.name, err)
+		}
+		if err := (SloppyPKCS1v15Verifier{}).Verify(pub, stdcrypto.SHA256, msg, c.sig); !errors.Is(err, ErrRSAVerification) {
+			t.Fatalf("%s: careful parser: err = %v", c.name, err)
+		}
+		if err := pub.VerifyPKCS1v15(stdcrypto.SHA256, msg, c.sig); !errors.Is(err, ErrRSAVerification) {
+			t.Fatalf("%s: strict verifier: err = %v", c.name, err)
+		}
+	}
+
+	sig, _ := r.SignPKCS1v15(stdcrypto.SHA256, msg)
+	if err := (SloppyPKCS1v15Verifier{}).Verify(pub, stdcrypto.SHA256, msg, sig); err != nil {
+		t.Fatal(err)
+	}
+	if err := pub.VerifyPKCS1v15(stdcrypto.SHA256, msg, sig[1:]); !errors.Is(err, ErrRSAVerification) {
+		t.Fatalf("short signature: err = %v", err)
+	}
+	if err := pub.VerifyPKCS1v15(stdcrypto.SHA256, msg, r.N.Add(bi.One).Bytes()); !errors.Is(err, ErrRSAVerification) {
+		t.Fatalf("signature above N: err = %v", err)
+	}
+}
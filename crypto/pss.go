@@ -0,0 +1,169 @@
+package crypto
+
+import (
+	"bytes"
+	stdcrypto "crypto"
+	"fmt"
+
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+const (
+	// PSSSaltLengthAuto signs with the longest salt that fits and makes
+	// VerifyPSS work out the salt length from the signature
+	PSSSaltLengthAuto = 0
+	// PSSSaltLengthEqualsHash uses a salt as long as the hash
+	PSSSaltLengthEqualsHash = -1
+)
+
+// PSSOptions configures RSASSA-PSS. The zero value uses SHA-256, for the
+// message and MGF1, and PSSSaltLengthAuto
+type PSSOptions struct {
+	Hash       stdcrypto.Hash
+	SaltLength int
+}
+
+// hash returns the hash to use, checking the options on the way
+func (o PSSOptions) hash() (stdcrypto.Hash, error) {
+	h := o.Hash
+	if h == 0 {
+		h = stdcrypto.SHA256
+	}
+	if !h.Available() {
+		return 0, fmt.Errorf("%v: %w", h, ErrUnsupportedHash)
+	}
+	if o.SaltLength < PSSSaltLengthEqualsHash {
+		return 0, fmt.Errorf("pss: invalid salt length %d", o.SaltLength)
+	}
+	return h, nil
+}
+
+// saltLength returns the salt length opts asks for with emLen bytes of
+// encoding, -1 for one to be detected
+func (o PSSOptions) saltLength(h stdcrypto.Hash, emLen int, signing bool) int {
+	switch o.SaltLength {
+	case PSSSaltLengthAuto:
+		if signing {
+			return emLen - h.Size() - 2
+		}
+		return -1
+	case PSSSaltLengthEqualsHash:
+		return h.Size()
+	}
+	return o.SaltLength
+}
+
+// pssHash returns H(00*8 || mHash || salt), the hash the encoding carries
+func pssHash(h stdcrypto.Hash, mHash, salt []byte) []byte {
+	hf := h.New()
+	hf.Write(make([]byte, 8))
+	hf.Write(mHash)
+	hf.Write(salt)
+	return hf.Sum(nil)
+}
+
+// EncodePSS returns the EMSA-PSS encoding of the digest mHash in emBits bits,
+// maskedDB || H || bc with DB = 00 .. 00 || 01 || salt
+func EncodePSS(h stdcrypto.Hash, mHash, salt []byte, emBits int) ([]byte, error) {
+	hLen := h.Size()
+	emLen := (emBits + 7) / 8
+	if len(mHash) != hLen {
+		return nil, fmt.Errorf("pss: %d byte digest for %v", len(mHash), h)
+	}
+	if emLen < hLen+len(salt)+2 {
+		return nil, ErrRSAKeyTooSmall
+	}
+	H := pssHash(h, mHash, salt)
+	db := make([]byte, emLen-hLen-1)
+	db[len(db)-len(salt)-1] = 1
+	copy(db[len(db)-len(salt):], salt)
+	maskedDB := utils.XorBytes(db, MGF1(h, H, len(db)))
+	maskedDB[0] &= 0xFF >> (8*emLen - emBits)
+	return utils.ConcatBytes(maskedDB, H, []byte{0xBC}), nil
+}
+
+// DecodePSS checks that em is the EMSA-PSS encoding of the digest mHash in
+// emBits bits with a salt of sLen bytes, or any length if sLen is -1
+func DecodePSS(h stdcrypto.Hash, mHash, em []byte, emBits, sLen int) error {
+	hLen := h.Size()
+	emLen := (emBits + 7) / 8
+	if len(em) != emLen || len(mHash) != hLen || emLen < hLen+2 {
+		return ErrRSAVerification
+	}
+	if em[emLen-1] != 0xBC {
+		return ErrRSAVerification
+	}
+	maskedDB, H := em[:emLen-hLen-1], em[emLen-hLen-1:emLen-1]
+	topBits := byte(0xFF) >> (8*emLen - emBits)
+	if maskedDB[0]&^topBits != 0 {
+		return ErrRSAVerification
+	}
+	db := utils.XorBytes(maskedDB, MGF1(h, H, len(maskedDB)))
+	db[0] &= topBits
+
+	// the salt starts after the first nonzero byte, which has to be 01
+	i := 0
+	for i < len(db) && db[i] == 0 {
+		i++
+	}
+	if i == len(db) || db[i] != 1 {
+		return ErrRSAVerification
+	}
+	salt := db[i+1:]
+	if sLen >= 0 && len(salt) != sLen {
+		return ErrRSAVerification
+	}
+	if !bytes.Equal(pssHash(h, mHash, salt), H) {
+		return ErrRSAVerification
+	}
+	return nil
+}
+
+// SignPSS signs msg with RSASSA-PSS
+func (r RSA) SignPSS(msg []byte, opts PSSOptions) ([]byte, error) {
+	h, err := opts.hash()
+	if err != nil {
+		return nil, err
+	}
+	emBits := r.N.BitLen() - 1
+	sLen := opts.saltLength(h, (emBits+7)/8, true)
+	if sLen < 0 {
+		return nil, ErrRSAKeyTooSmall
+	}
+	hf := h.New()
+	hf.Write(msg)
+	em, err := EncodePSS(h, hf.Sum(nil), utils.RandBytes(sLen), emBits)
+	if err != nil {
+		return nil, err
+	}
+	sig := r.privateBytes(em)
+	if sig == nil {
+		return nil, ErrRSAFault
+	}
+	return sig, nil
+}
+
+// VerifyPSS checks the RSASSA-PSS signature sig of msg. With
+// PSSSaltLengthAuto any salt length is accepted
+func (k RSAKey) VerifyPSS(msg, sig []byte, opts PSSOptions) error {
+	h, err := opts.hash()
+	if err != nil {
+		return err
+	}
+	m, err := k.publicBytes(sig)
+	if err != nil {
+		return err
+	}
+	// the encoding is a bit shorter than N, which can leave a zero byte in front
+	emBits := k.N.BitLen() - 1
+	emLen := (emBits + 7) / 8
+	if len(m) > emLen {
+		if m[0] != 0 {
+			return ErrRSAVerification
+		}
+		m = m[1:]
+	}
+	hf := h.New()
+	hf.Write(msg)
+	return DecodePSS(h, hf.Sum(nil), m, emBits, opts.saltLength(h, emLen, false))
+}
@@ -0,0 +1,77 @@
+package crypto
+
+import (
+	stdcrypto "crypto"
+	"crypto/rand"
+	stdrsa "crypto/rsa"
+	"errors"
+	"testing"
+)
+
+func TestPSS(t *testing.T) {
+	msg := []byte("hello world")
+	// 1025 bits leaves the encoding a byte shorter than N
+	for _, bits := range []int{1024, 1025, 1028} {
+		r, err := NewRSA(RSAOptions{Bits: bits})
+		if err != nil {
+			t.Fatal(err)
+		}
+		std := stdPrivateKey(r)
+		for _, opts := range []PSSOptions{
+			{},
+			{Hash: stdcrypto.SHA1, SaltLength: PSSSaltLengthEqualsHash},
+			{Hash: stdcrypto.SHA512, SaltLength: 10},
+		} {
+			h, _ := opts.hash()
+			hf := h.New()
+			hf.Write(msg)
+			digest := hf.Sum(nil)
+			stdOpts := &stdrsa.PSSOptions{SaltLength: opts.SaltLength, Hash: h}
+
+			sig, err := r.SignPSS(msg, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := stdrsa.VerifyPSS(&std.PublicKey, h, digest, sig, stdOpts); err != nil {
+				t.Fatalf("%d bits %+v: crypto/rsa rejected the signature: %v", bits, opts, err)
+			}
+			if err := r.PubKey().VerifyPSS(msg, sig, opts); err != nil {
+				t.Fatalf("%d bits %+v: %v", bits, opts, err)
+			}
+			// the salt length is worked out from the signature
+			if err := r.PubKey().VerifyPSS(msg, sig, PSSOptions{Hash: opts.Hash}); err != nil {
+				t.Fatalf("%d bits %+v: auto salt length: %v", bits, opts, err)
+			}
+			if err := r.PubKey().VerifyPSS([]byte("hello world!"), sig, opts); !errors.Is(err, ErrRSAVerification) {
+				t.Fatalf("%d bits %+v: tampered message: err = %v", bits, opts, err)
+			}
+
+			sig, err = stdrsa.SignPSS(rand.Reader, std, h, digest, stdOpts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.PubKey().VerifyPSS(msg, sig, opts); err != nil {
+				t.Fatalf("%d bits %+v: rejected crypto/rsa's signature: %v", bits, opts, err)
+			}
+		}
+	}
+
+	r, err := NewRSA(RSAOptions{Bits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := r.SignPSS(msg, PSSOptions{SaltLength: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.PubKey().VerifyPSS(msg, sig, PSSOptions{SaltLength: 32}); !errors.Is(err, ErrRSAVerification) {
+		t.Fatalf("wrong salt length: err = %v", err)
+	}
+	pkcs, _ := r.SignPKCS1v15(stdcrypto.SHA256, msg)
+	if err := r.PubKey().VerifyPSS(msg, pkcs, PSSOptions{}); !errors.Is(err, ErrRSAVerification) {
+		t.Fatalf("PKCS#1 v1.5 signature: err = %v", err)
+	}
+	if _, err := r.SignPSS(msg, PSSOptions{SaltLength: 100}); !errors.Is(err, ErrRSAKeyTooSmall) {
+		t.Fatalf("long salt: err = %v", err)
+	}
+}
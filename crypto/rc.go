@@ -1,7 +1,6 @@
 package crypto
 
 import (
-	"crypto/rand"
 	"crypto/rc4"
 
 	"github.com/sukunrt/cryptopals/utils"
@@ -46,11 +45,7 @@ func BreakRC4(msg []byte) []byte {
 	for i := len(msg) - 1; i >= 0; i-- {
 		msg := utils.ConcatBytes(utils.RepBytes('A', 32-i-1), msg)
 		mb := getBiasByte(31, msg, func(b []byte) []byte {
-			key := make([]byte, 16)
-			_, err := rand.Read(key)
-			if err != nil {
-				panic(err)
-			}
+			key := utils.RandBytes(16)
 			cipher, err := rc4.NewCipher(key)
 			if err != nil {
 				panic(err)
@@ -1,18 +1,44 @@
 package crypto
 
 import (
-	"bytes"
-	"crypto/sha256"
+	stdcrypto "crypto"
+	"errors"
 	"fmt"
-	"math/rand"
 
 	bi "github.com/sukunrt/bigint"
 	"github.com/sukunrt/cryptopals/utils"
 )
 
+var (
+	ErrRSASamePrimes = errors.New("p and q are the same prime")
+	ErrRSAExponent   = errors.New("e is not invertible mod (p-1)(q-1)")
+	ErrRSAKeySize    = errors.New("rsa: invalid modulus size")
+	ErrRSAInvalidKey = errors.New("rsa: invalid key")
+	ErrRSAMsgTooLong = errors.New("rsa: message is not smaller than the modulus")
+	// ErrRSAFault is returned when the result of a private key operation
+	// doesn't check out against the public key. Releasing it would leak a
+	// factor of N if the fault was in only one of the CRT halves
+	ErrRSAFault = errors.New("rsa: fault in private key operation")
+)
+
+const (
+	// DefaultRSAExponent is the public exponent NewRSA uses unless told
+	// otherwise
+	DefaultRSAExponent = 65537
+	// DefaultRSABits is the modulus size NewRSA uses unless told otherwise
+	DefaultRSABits = 2048
+	minRSABits     = 64
+)
+
+// RSA is an RSA private key. DP, DQ and QInv are D mod P-1, D mod Q-1 and
+// Q^-1 mod P, which the private key operations use to work mod P and Q
+// separately. With Blinding set they work on a random multiple of their input
+// so their timing doesn't depend on it
 type RSA struct {
 	Sz                int
 	P, Q, N, ET, E, D bi.Int
+	DP, DQ, QInv      bi.Int
+	Blinding          bool
 }
 
 type RSAKey struct {
@@ -20,6 +46,14 @@ type RSAKey struct {
 	Sz   int
 }
 
+// RSAOptions configures NewRSA. The zero value gives a DefaultRSABits modulus
+// with e = DefaultRSAExponent and no blinding
+type RSAOptions struct {
+	Bits     int
+	E        int
+	Blinding bool
+}
+
 func (r RSA) Encrypt(b []byte) []byte {
 	return EncryptRSAWithPublicKey(b, r)
 }
@@ -28,130 +62,322 @@ func (r RSA) Decrypt(b []byte) []byte {
 	return DecryptRSAWithPrivateKey(b, r)
 }
 
+// PrivateOp returns x^D mod N. It is computed mod P and Q and recombined with
+// Garner's formula, then checked by raising it to E
+func (r RSA) PrivateOp(x bi.Int) (bi.Int, error) {
+	if x.Cmp(r.N) >= 0 {
+		return bi.Zero, ErrRSAMsgTooLong
+	}
+	c := x
+	var unblind bi.Int
+	if r.Blinding {
+		b := RandInt(r.N)
+		for !gcd(b, r.N).Equal(bi.One) {
+			b = RandInt(r.N)
+		}
+		c = c.Mul(bi.Exp(b, r.E, r.N)).Mod(r.N)
+		unblind = ModInv(b, r.N)
+	}
+	m1 := bi.Exp(c, r.DP, r.P)
+	m2 := bi.Exp(c, r.DQ, r.Q)
+	h := r.QInv.Mul(m1.Sub(m2)).Mod(r.P)
+	m := m2.Add(h.Mul(r.Q))
+	if !bi.Exp(m, r.E, r.N).Equal(c) {
+		return bi.Zero, ErrRSAFault
+	}
+	if r.Blinding {
+		m = m.Mul(unblind).Mod(r.N)
+	}
+	return m, nil
+}
+
+// privateBytes applies the private key to b and returns the result padded to
+// the size of the modulus, nil if that fails
+func (r RSA) privateBytes(b []byte) []byte {
+	m, err := r.PrivateOp(bi.FromBytes(b))
+	if err != nil {
+		return nil
+	}
+	res := m.Bytes()
+	return append(utils.RepBytes(0, r.Sz-len(res)), res...)
+}
+
+// Sign returns the RSASSA-PKCS1-v1_5 signature of msg with SHA-256, nil if
+// the key is too small for it
 func (r RSA) Sign(msg []byte) []byte {
-	sha := sha256.New()
-	sha.Write(msg)
-	digest := sha.Sum(nil)
-	padLen := r.Sz - len(digest) - 2 - 2 - 1 // 2 prefix, 2 hashId, 1 suffix
-	i := 0
-	block := make([]byte, r.Sz)
-	block[i] = 0
-	i++
-	block[1] = 1
-	i++
-	for j := 0; j < padLen; i, j = i+1, j+1 {
-		block[i] = 0xFF
-	}
-	block[i] = 00
-	i++
-	block[i] = 0xA
-	i++
-	block[i] = 0xB
-	i++
-	copy(block[i:], digest)
-	return EncryptRSA(block, r.D, r.N, r.Sz)
+	sig, err := r.SignPKCS1v15(stdcrypto.SHA256, msg)
+	if err != nil {
+		return nil
+	}
+	return sig
 }
 
 func (r RSA) PubKey() RSAKey {
 	return RSAKey{E: r.E, N: r.N, Sz: r.Sz}
 }
 
+// NewRSAN returns a key with e = 3 and two primes of n bytes each, the key
+// most of the challenges are written for
 func NewRSAN(n int) RSA {
-	for {
-		p, q := RandPrimeN(n), RandPrimeN(n)
-		nn := p.Mul(q)
-		p1, q1 := p.Sub(bi.One), q.Sub(bi.One)
-		et := p1.Mul(q1)
-		e := bi.FromInt(3)
+	r, err := NewRSA(RSAOptions{Bits: 16 * n, E: 3})
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
 
-		if !gcd(e, et).Equal(bi.One) {
+// NewRSA generates a key with a modulus of exactly opts.Bits bits. The primes
+// are half that size each, p-1 and q-1 are coprime to e and p and q are not
+// close enough for Fermat's method
+func NewRSA(opts RSAOptions) (RSA, error) {
+	if opts.Bits == 0 {
+		opts.Bits = DefaultRSABits
+	}
+	if opts.E == 0 {
+		opts.E = DefaultRSAExponent
+	}
+	if opts.Bits < minRSABits {
+		return RSA{}, fmt.Errorf("%d bits: %w", opts.Bits, ErrRSAKeySize)
+	}
+	if opts.E < 3 || opts.E%2 == 0 {
+		return RSA{}, fmt.Errorf("e = %d: %w", opts.E, ErrRSAExponent)
+	}
+	e := bi.FromInt(opts.E)
+	pBits := (opts.Bits + 1) / 2
+	// FIPS 186-4 asks for |p-q| > 2^(bits/2-100)
+	minDist := bi.One
+	if opts.Bits/2 > 100 {
+		minDist = bi.Exp(bi.Two, bi.FromInt(opts.Bits/2-100), bi.Zero)
+	}
+	for {
+		p, q := randRSAPrime(pBits, e), randRSAPrime(opts.Bits-pBits, e)
+		dist := p.Sub(q)
+		if dist.Cmp(bi.Zero) < 0 {
+			dist = q.Sub(p)
+		}
+		if dist.Cmp(minDist) <= 0 {
 			continue
 		}
-		d := ModInv(e, et)
-		return RSA{2 * n, p, q, nn, et, e, d}
+		r, err := NewRSAFromPrimes(p, q, e)
+		if err != nil || r.N.BitLen() != opts.Bits {
+			continue
+		}
+		r.Blinding = opts.Blinding
+		return r, nil
 	}
 }
 
-func EncryptRSAWithPublicKey(b []byte, r RSA) []byte {
-	return EncryptRSA(b, r.E, r.N, r.Sz)
+// randRSAPrime returns a prime of the given bit length with its top two bits
+// set, so that the product of two of them has exactly twice the bits, and
+// with p-1 coprime to e
+func randRSAPrime(bits int, e bi.Int) bi.Int {
+	quarter := bi.Exp(bi.Two, bi.FromInt(bits-2), bi.Zero)
+	for {
+		p := RandInt(quarter).Add(quarter.Mul(bi.Three))
+		if p.Mod(bi.Two).Equal(bi.Zero) {
+			p = p.Add(bi.One)
+		}
+		if gcd(e, p.Sub(bi.One)).Equal(bi.One) && MillerRabin(p) {
+			return p
+		}
+	}
 }
 
-func DecryptRSAWithPrivateKey(b []byte, r RSA) []byte {
-	return EncryptRSA(b, r.D, r.N, r.Sz)
+// NewRSAFromPrimes returns the RSA key with primes p and q and public exponent e
+func NewRSAFromPrimes(p, q, e bi.Int) (RSA, error) {
+	if p.Equal(q) {
+		return RSA{}, ErrRSASamePrimes
+	}
+	nn := p.Mul(q)
+	et := p.Sub(bi.One).Mul(q.Sub(bi.One))
+	if !gcd(e.Mod(et), et).Equal(bi.One) {
+		return RSA{}, ErrRSAExponent
+	}
+	d := ModInv(e, et)
+	return withCRT(RSA{Sz: len(nn.Bytes()), P: p, Q: q, N: nn, ET: et, E: e, D: d}), nil
 }
 
-func EncryptRSA(b []byte, exp, N bi.Int, sz int) []byte {
-	i := bi.FromBytes(b)
-	if i.Cmp(N) > 0 {
-		return nil
+// NewRSAFromComponents returns the key with the given modulus, exponents and
+// primes after checking that they belong together. It is meant for keys that
+// were generated elsewhere, whose d may be reduced mod lcm(p-1, q-1)
+func NewRSAFromComponents(n, e, d, p, q bi.Int) (RSA, error) {
+	r := withCRT(RSA{
+		Sz: len(n.Bytes()),
+		P:  p, Q: q, N: n, E: e, D: d,
+		ET: p.Sub(bi.One).Mul(q.Sub(bi.One)),
+	})
+	if err := r.Validate(); err != nil {
+		return RSA{}, err
 	}
-	bytes := bi.Exp(i, exp, N).Bytes()
-	bytes = append(utils.RepBytes(0, sz-len(bytes)), bytes...)
-	return bytes
+	return r, nil
 }
 
-func VerifyRSASignatureCorrect(msg []byte, signature []byte, r RSA) bool {
-	block := r.Encrypt(signature)
-	i := 0
-	if block[i] != 0 {
-		return false
+// withCRT returns r with DP, DQ and QInv filled in from P, Q and D
+func withCRT(r RSA) RSA {
+	r.DP = r.D.Mod(r.P.Sub(bi.One))
+	r.DQ = r.D.Mod(r.Q.Sub(bi.One))
+	r.QInv = ModInv(r.Q, r.P)
+	return r
+}
+
+// Validate checks that r is a consistent private key. P and Q have to be
+// distinct primes with N = PQ, E*D has to be 1 mod lcm(P-1, Q-1) and the CRT
+// values have to match D
+func (r RSA) Validate() error {
+	if err := r.PubKey().Validate(); err != nil {
+		return err
 	}
-	i++
-	if block[i] != 1 {
-		return false
+	if !MillerRabin(r.P) || !MillerRabin(r.Q) {
+		return fmt.Errorf("p or q is not prime: %w", ErrRSAInvalidKey)
 	}
-	i++
-	for ; i < len(block); i++ {
-		if block[i] != 0xFF {
-			break
-		}
+	if r.P.Equal(r.Q) {
+		return ErrRSASamePrimes
 	}
-	if i == len(block) {
-		return false
+	if !r.P.Mul(r.Q).Equal(r.N) {
+		return fmt.Errorf("n != pq: %w", ErrRSAInvalidKey)
 	}
-
-	if block[i] != 0 {
-		return false
+	p1, q1 := r.P.Sub(bi.One), r.Q.Sub(bi.One)
+	if !r.ET.Equal(p1.Mul(q1)) {
+		return fmt.Errorf("et != (p-1)(q-1): %w", ErrRSAInvalidKey)
 	}
-	i++
-
-	if i+32+2 != len(block) {
-		return false
+	lambda := p1.Mul(q1).Div(gcd(p1, q1))
+	if !r.E.Mul(r.D).Mod(lambda).Equal(bi.One) {
+		return fmt.Errorf("d is not the inverse of e: %w", ErrRSAInvalidKey)
+	}
+	if !r.DP.Equal(r.D.Mod(p1)) || !r.DQ.Equal(r.D.Mod(q1)) || !r.QInv.Mul(r.Q).Mod(r.P).Equal(bi.One) {
+		return fmt.Errorf("crt values don't match d: %w", ErrRSAInvalidKey)
 	}
-	i += 2
+	return nil
+}
 
-	sha := sha256.New()
-	sha.Write(msg)
-	return bytes.Equal(block[i:], sha.Sum(nil))
+// Validate checks that k could be an RSA public key: N is odd, at least
+// minRSABits bits and Sz bytes long and E is odd with 3 <= E < N
+func (k RSAKey) Validate() error {
+	if k.N.BitLen() < minRSABits || k.N.Mod(bi.Two).Equal(bi.Zero) {
+		return fmt.Errorf("n is even or too small: %w", ErrRSAInvalidKey)
+	}
+	if k.Sz != len(k.N.Bytes()) {
+		return fmt.Errorf("size %d for a %d byte modulus: %w", k.Sz, len(k.N.Bytes()), ErrRSAInvalidKey)
+	}
+	if k.E.Cmp(bi.Three) < 0 || k.E.Cmp(k.N) >= 0 || k.E.Mod(bi.Two).Equal(bi.Zero) {
+		return fmt.Errorf("e = %s: %w", k.E, ErrRSAExponent)
+	}
+	return nil
 }
 
-func VerifyRSASignatureInCorrect(msg []byte, signature []byte, r RSA) bool {
-	block := r.Encrypt(signature)
-	i := 0
-	if block[i] != 0 {
-		return false
+// smoothPrime returns a prime p of the given bit length with p-1 the product
+// of 2 and distinct odd primes below 2^12 that are not in used, along with
+// those factors of p-1
+func smoothPrime(bits int, used map[int]bool) (bi.Int, []bi.Int) {
+	var small []int
+	for _, r := range primesBelow(1 << 12)[1:] {
+		if !used[r] {
+			small = append(small, r)
+		}
 	}
-	i++
-	if block[i] != 1 {
-		return false
+	top := bi.Exp(bi.Two, bi.FromInt(bits), bi.Zero)
+	for {
+		n := bi.Two
+		factors := []bi.Int{bi.Two}
+		picked := make(map[int]bool)
+		pick := func(r int) {
+			picked[r] = true
+			n = n.Mul(bi.FromInt(r))
+			factors = append(factors, bi.FromInt(r))
+		}
+		for n.BitLen() < bits-12 {
+			if r := small[utils.RandIntn(len(small))]; !picked[r] {
+				pick(r)
+			}
+		}
+		// the last factor brings n up to exactly bits bits
+		lo, hi := CeilDiv(top.Div(bi.Two), n).Int(), top.Div(n).Int()
+		var last []int
+		for _, r := range small {
+			if r >= lo && r < hi && !picked[r] {
+				last = append(last, r)
+			}
+		}
+		if len(last) == 0 {
+			continue
+		}
+		pick(last[utils.RandIntn(len(last))])
+		if p := n.Add(bi.One); MillerRabin(p) {
+			return p, factors
+		}
 	}
-	i++
-	for ; i < len(block); i++ {
-		if block[i] != 0xFF {
-			break
+}
+
+// isGenerator reports whether g generates Z_p* given the prime factors of p-1
+func isGenerator(g, p bi.Int, factors []bi.Int) bool {
+	for _, r := range factors {
+		if bi.Exp(g, p.Sub(bi.One).Div(r), p).Equal(bi.One) {
+			return false
 		}
 	}
-	if i == len(block) {
-		return false
+	return true
+}
+
+// RSAKeySelectionAttack returns an RSA key, of the same size as pk, under which
+// sig also verifies for the message pk signed. This is the duplicate signature
+// key selection attack: with smooth p-1 and q-1 and sig generating both Z_p*
+// and Z_q*, discrete logs mod p and q give an e' with sig^e' = pad(m) mod pq
+func RSAKeySelectionAttack(sig []byte, pk RSAKey) RSA {
+	s := bi.FromBytes(sig)
+	m := bi.Exp(s, pk.E, pk.N)
+	bits := 4 * pk.Sz
+	for {
+		p, pf := smoothPrime(bits, nil)
+		if !isGenerator(s, p, pf) {
+			continue
+		}
+		ep, err := SmoothDiscreteLog(m, s, p, pf)
+		if err != nil || ep.Mod(bi.Two).Equal(bi.Zero) {
+			// an even e' is never invertible mod (p-1)(q-1)
+			continue
+		}
+		used := make(map[int]bool)
+		for _, r := range pf {
+			used[r.Int()] = true
+		}
+		// a p near the bottom of its range may not leave room for pq > sig, so
+		// only try a few q before moving on
+		for try := 0; try < 32; try++ {
+			q, qf := smoothPrime(bits, used)
+			n := p.Mul(q)
+			if n.Cmp(s) <= 0 || n.Cmp(m) <= 0 || !isGenerator(s, q, qf) {
+				continue
+			}
+			eq, err := SmoothDiscreteLog(m, s, q, qf)
+			if err != nil || eq.Mod(bi.Two).Equal(bi.Zero) {
+				continue
+			}
+			// p-1 and (q-1)/2 are coprime and ep, eq agree mod 2
+			q2 := q.Sub(bi.One).Div(bi.Two)
+			e := CRT([]bi.Int{ep, eq.Mod(q2)}, []bi.Int{p.Sub(bi.One), q2})
+			if r, err := NewRSAFromPrimes(p, q, e); err == nil {
+				return r
+			}
+		}
 	}
-	if block[i] != 0 {
-		return false
+}
+
+func EncryptRSAWithPublicKey(b []byte, r RSA) []byte {
+	return EncryptRSA(b, r.E, r.N, r.Sz)
+}
+
+func DecryptRSAWithPrivateKey(b []byte, r RSA) []byte {
+	return r.privateBytes(b)
+}
+
+func EncryptRSA(b []byte, exp, N bi.Int, sz int) []byte {
+	i := bi.FromBytes(b)
+	if i.Cmp(N) > 0 {
+		return nil
 	}
-	i++
-	i += 2
-	sha := sha256.New()
-	sha.Write(msg)
-	return bytes.Equal(block[i:i+32], sha.Sum(nil))
+	bytes := bi.Exp(i, exp, N).Bytes()
+	bytes = append(utils.RepBytes(0, sz-len(bytes)), bytes...)
+	return bytes
 }
 
 func UnPaddedRSAOracle(m string) string {
@@ -177,17 +403,26 @@ func EncryptRSAWithPadding(b []byte, r RSA) []byte {
 }
 
 func ValidPadding(b []byte, r RSA) bool {
+	// Decrypt returns nil for ciphertexts it rejects
 	blk := r.Decrypt(b)
-	return blk[0] == 0 && blk[1] == 2
+	return len(blk) >= 2 && blk[0] == 0 && blk[1] == 2
 }
 
-func RemovePadding(b []byte) []byte {
+// RemovePadding returns the message in the PKCS#1 v1.5 encryption block b,
+// 00 02 followed by at least 8 nonzero bytes and a zero
+func RemovePadding(b []byte) ([]byte, error) {
+	if len(b) < 11 || b[0] != 0 || b[1] != 2 {
+		return nil, ErrRSADecryption
+	}
 	for i := 2; i < len(b); i++ {
 		if b[i] == 0 {
-			return b[i+1:]
+			if i-2 < 8 {
+				return nil, ErrRSADecryption
+			}
+			return b[i+1:], nil
 		}
 	}
-	return nil
+	return nil, ErrRSADecryption
 }
 
 func PadBlock(b []byte, r RSA) []byte {
@@ -200,7 +435,7 @@ func PadBlock(b []byte, r RSA) []byte {
 	rb := utils.RandBytes(padSize)
 	for i := 0; i < padSize; i++ {
 		for rb[i] == 0 {
-			rb[i] = byte(rand.Intn(1 << 8))
+			rb[i] = byte(utils.RandIntn(1 << 8))
 		}
 		res[2+i] = rb[i]
 	}
@@ -223,6 +458,9 @@ func (i interval) String() string {
 	return fmt.Sprintf("interval{%s, %s}", i.a.String(), i.b.String())
 }
 
+// BreakRSAWithPaddingOracle decrypts c with Bleichenbacher's attack on an
+// oracle that reports whether a ciphertext decrypts to a block starting 00 02.
+// It returns the whole block, padding included
 func BreakRSAWithPaddingOracle(c []byte, oracle func([]byte) bool, r RSA) []byte {
 	B := bi.Exp(bi.Two, bi.FromInt(r.Sz*8-16), bi.Zero)
 	B2 := B.Mul(bi.Two)
@@ -254,7 +492,8 @@ func BreakRSAWithPaddingOracle(c []byte, oracle func([]byte) bool, r RSA) []byte
 			}
 		default:
 			if M[0].b.Sub(M[0].a).Equal(bi.Zero) {
-				return M[0].b.Bytes()
+				m := M[0].b.Bytes()
+				return append(utils.RepBytes(0, r.Sz-len(m)), m...)
 			}
 			a, b := M[0].a, M[0].b
 			sprev := s
@@ -305,3 +544,58 @@ func BreakRSAWithPaddingOracle(c []byte, oracle func([]byte) bool, r RSA) []byte
 		M = NM
 	}
 }
+
+// BreakRSAWithMangerOracle decrypts c with Manger's attack on an oracle that
+// reports whether a ciphertext decrypts to a block starting with a zero byte,
+// i.e. whether it is below B = 2^(8(Sz-1)). c has to be such a ciphertext, as
+// RSAES-OAEP ones are. Each query after the first two steps halves the range
+// the message is in, so it takes about as many queries as N has bits where
+// BreakRSAWithPaddingOracle takes thousands. It returns the whole block
+func BreakRSAWithMangerOracle(c []byte, oracle func([]byte) bool, k RSAKey) ([]byte, error) {
+	N := k.N
+	B := bi.Exp(bi.Two, bi.FromInt(8*(k.Sz-1)), bi.Zero)
+	if B.Mul(bi.Two).Cmp(N) >= 0 {
+		return nil, fmt.Errorf("manger: N is too close to B: %w", ErrAttackFailed)
+	}
+	ci := bi.FromBytes(c)
+	// below reports whether f*m mod N < B
+	below := func(f bi.Int) bool {
+		fe := bi.Exp(f, k.E, N)
+		return oracle(ci.Mul(fe).Mod(N).Bytes())
+	}
+
+	// step 1: f1*m in [B, 2B) and so f1/2*m in [B/2, B)
+	f1 := bi.Two
+	for below(f1) {
+		f1 = f1.Mul(bi.Two)
+	}
+	f12 := f1.Div(bi.Two)
+
+	// step 2: f2*m in [N, N+B)
+	f2 := FloorDiv(N.Add(B), B).Mul(f12)
+	for !below(f2) {
+		f2 = f2.Add(f12)
+		if f2.Cmp(N.Mul(bi.Two)) > 0 {
+			return nil, fmt.Errorf("manger: step 2 didn't end: %w", ErrAttackFailed)
+		}
+	}
+
+	// step 3: narrow [mmin, mmax] down with f3*m near i*N+B, one bit a query
+	mmin, mmax := CeilDiv(N, f2), FloorDiv(N.Add(B), f2)
+	for mmin.Cmp(mmax) < 0 {
+		ftmp := FloorDiv(B.Mul(bi.Two), mmax.Sub(mmin))
+		i := FloorDiv(ftmp.Mul(mmin), N)
+		iN := i.Mul(N)
+		f3 := CeilDiv(iN, mmin)
+		if below(f3) {
+			mmax = FloorDiv(iN.Add(B), f3)
+		} else {
+			mmin = CeilDiv(iN.Add(B), f3)
+		}
+	}
+	if !bi.Exp(mmin, k.E, N).Equal(ci) {
+		return nil, fmt.Errorf("manger: %w", ErrAttackFailed)
+	}
+	m := mmin.Bytes()
+	return append(utils.RepBytes(0, k.Sz-len(m)), m...), nil
+}
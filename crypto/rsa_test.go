@@ -0,0 +1,107 @@
+package crypto
+
+import (
+	"bytes"
+	stdcrypto "crypto"
+	"errors"
+	"testing"
+
+	bi "github.com/sukunrt/bigint"
+)
+
+func TestRSAKeySelectionAttack(t *testing.T) {
+	r := NewRSAN(32)
+	msg := []byte("hello world")
+	sig := r.Sign(msg)
+	evil := RSAKeySelectionAttack(sig, r.PubKey())
+	if evil.N.Equal(r.N) {
+		t.Fatal("attack returned the original modulus")
+	}
+	if evil.PubKey().VerifyPKCS1v15(stdcrypto.SHA256, msg, sig) != nil {
+		t.Fatal("signature doesn't verify under the chosen key")
+	}
+	if evil.PubKey().VerifyPKCS1v15(stdcrypto.SHA256, msg, evil.Sign(msg)) != nil {
+		t.Fatal("chosen key can't sign")
+	}
+}
+
+func TestNewRSA(t *testing.T) {
+	for _, opts := range []RSAOptions{
+		{Bits: 512},
+		{Bits: 521, E: 3},
+		{Bits: 512, Blinding: true},
+	} {
+		r, err := NewRSA(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := opts.E
+		if e == 0 {
+			e = DefaultRSAExponent
+		}
+		if r.N.BitLen() != opts.Bits || !r.E.Equal(bi.FromInt(e)) {
+			t.Fatalf("%+v: got a %d bit modulus with e = %s", opts, r.N.BitLen(), r.E)
+		}
+		if err := r.Validate(); err != nil {
+			t.Fatalf("%+v: %v", opts, err)
+		}
+		msg := []byte("hello world")
+		if got := r.Decrypt(r.Encrypt(msg)); !bytes.Equal(bytes.TrimLeft(got, "\x00"), msg) {
+			t.Fatalf("%+v: decrypted to %q", opts, got)
+		}
+	}
+	if _, err := NewRSA(RSAOptions{E: 4}); !errors.Is(err, ErrRSAExponent) {
+		t.Fatalf("even e: err = %v", err)
+	}
+	if _, err := NewRSA(RSAOptions{Bits: 32}); !errors.Is(err, ErrRSAKeySize) {
+		t.Fatalf("32 bits: err = %v", err)
+	}
+}
+
+func TestRSAFaultCheck(t *testing.T) {
+	r, err := NewRSA(RSAOptions{Bits: 512})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.DP = r.DP.Add(bi.One)
+	if _, err := r.PrivateOp(bi.FromInt(12345)); !errors.Is(err, ErrRSAFault) {
+		t.Fatalf("err = %v, want %v", err, ErrRSAFault)
+	}
+	if err := r.Validate(); !errors.Is(err, ErrRSAInvalidKey) {
+		t.Fatalf("err = %v, want %v", err, ErrRSAInvalidKey)
+	}
+}
+
+func TestValidPaddingRejected(t *testing.T) {
+	r, err := NewRSA(RSAOptions{Bits: 512})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the private op rejects x >= N and faults
+	if ValidPadding(r.N.Bytes(), r) {
+		t.Fatal("N has valid padding")
+	}
+	r.DP = r.DP.Add(bi.One)
+	if ValidPadding(EncryptRSAWithPadding([]byte("hi"), r), r) {
+		t.Fatal("faulty decryption has valid padding")
+	}
+}
+
+func TestNewRSAFromComponents(t *testing.T) {
+	r, err := NewRSA(RSAOptions{Bits: 512})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// d reduced mod lcm(p-1, q-1) like OpenSSL does
+	p1, q1 := r.P.Sub(bi.One), r.Q.Sub(bi.One)
+	d := r.D.Mod(p1.Mul(q1).Div(gcd(p1, q1)))
+	if _, err := NewRSAFromComponents(r.N, r.E, d, r.P, r.Q); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRSAFromComponents(r.N, r.E, d.Add(bi.Two), r.P, r.Q); !errors.Is(err, ErrRSAInvalidKey) {
+		t.Fatalf("wrong d: err = %v", err)
+	}
+	if _, err := NewRSAFromComponents(r.N.Add(bi.Two), r.E, d, r.P, r.Q); !errors.Is(err, ErrRSAInvalidKey) {
+		t.Fatalf("wrong n: err = %v", err)
+	}
+}
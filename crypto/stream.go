@@ -0,0 +1,166 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"errors"
+	"io"
+
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+var (
+	// ErrInvalidPadding is wrapped by the errors returned when decrypted data
+	// doesn't end in valid PKCS#7 padding
+	ErrInvalidPadding = utils.ErrInvalidPadding
+	// ErrPartialBlock is returned when ciphertext for a block mode isn't
+	// whole blocks
+	ErrPartialBlock = errors.New("ciphertext is not a multiple of the block size")
+)
+
+// streamChunk is how much a block reader asks its source for at once
+const streamChunk = 32 * 1024
+
+// crypt runs m over the whole blocks in buf and returns how many bytes it
+// processed. When decrypting the last block is held back, it may be the one
+// with the padding
+func crypt(m cipher.BlockMode, buf []byte, decrypt bool) int {
+	n := len(buf) - len(buf)%AESBlockSize
+	if decrypt && n == len(buf) && n > 0 {
+		n -= AESBlockSize
+	}
+	m.CryptBlocks(buf[:n], buf[:n])
+	return n
+}
+
+// finish handles the bytes left at EOF, padding them when encrypting and
+// checking and removing the padding when decrypting
+func finish(m cipher.BlockMode, rest []byte, decrypt bool) ([]byte, error) {
+	if !decrypt {
+		last := utils.PadBytes(rest, AESBlockSize)
+		m.CryptBlocks(last, last)
+		return last, nil
+	}
+	if len(rest) != AESBlockSize {
+		return nil, ErrPartialBlock
+	}
+	last := append([]byte{}, rest...)
+	m.CryptBlocks(last, last)
+	return utils.Unpad(last, AESBlockSize)
+}
+
+// blockWriter passes everything written to it through a BlockMode to w. Only
+// the bytes that don't make a whole block yet are kept. Once a write to w
+// fails the BlockMode's state is lost with the bytes, so err is returned from
+// then on
+type blockWriter struct {
+	w       io.Writer
+	m       cipher.BlockMode
+	buf     []byte
+	decrypt bool
+	closed  bool
+	err     error
+}
+
+// NewEncryptWriter returns a writer that encrypts what is written to it with m
+// and writes the ciphertext to w. Close pads the final block and must be
+// called for it to be written
+func NewEncryptWriter(w io.Writer, m cipher.BlockMode) io.WriteCloser {
+	return &blockWriter{w: w, m: m}
+}
+
+// NewDecryptWriter returns a writer that decrypts what is written to it with m
+// and writes the plaintext to w. The last block is held back until Close,
+// which checks and strips its padding
+func NewDecryptWriter(w io.Writer, m cipher.BlockMode) io.WriteCloser {
+	return &blockWriter{w: w, m: m, decrypt: true}
+}
+
+func (bw *blockWriter) Write(p []byte) (int, error) {
+	if bw.closed {
+		return 0, errors.New("crypto: write after close")
+	}
+	if bw.err != nil {
+		return 0, bw.err
+	}
+	bw.buf = append(bw.buf, p...)
+	n := crypt(bw.m, bw.buf, bw.decrypt)
+	_, bw.err = bw.w.Write(bw.buf[:n])
+	bw.buf = append(bw.buf[:0], bw.buf[n:]...)
+	if bw.err != nil {
+		return 0, bw.err
+	}
+	return len(p), nil
+}
+
+func (bw *blockWriter) Close() error {
+	if bw.closed {
+		return nil
+	}
+	bw.closed = true
+	if bw.err != nil {
+		return bw.err
+	}
+	last, err := finish(bw.m, bw.buf, bw.decrypt)
+	if err != nil {
+		return err
+	}
+	_, err = bw.w.Write(last)
+	return err
+}
+
+// blockReader reads from r through a BlockMode. out is what has been
+// processed and not read yet, in what has been read from r and not processed
+type blockReader struct {
+	r       io.Reader
+	m       cipher.BlockMode
+	in, out []byte
+	decrypt bool
+	err     error
+}
+
+// NewEncryptReader returns a reader of the encryption with m of what is read
+// from r, padded once r is at EOF
+func NewEncryptReader(r io.Reader, m cipher.BlockMode) io.Reader {
+	return &blockReader{r: r, m: m}
+}
+
+// NewDecryptReader returns a reader of the decryption with m of what is read
+// from r. The padding is checked and stripped once r is at EOF
+func NewDecryptReader(r io.Reader, m cipher.BlockMode) io.Reader {
+	return &blockReader{r: r, m: m, decrypt: true}
+}
+
+func (br *blockReader) Read(p []byte) (int, error) {
+	for len(br.out) == 0 {
+		if br.err != nil {
+			return 0, br.err
+		}
+		br.fill()
+	}
+	n := copy(p, br.out)
+	br.out = br.out[n:]
+	return n, nil
+}
+
+// fill reads the next chunk from r and processes what it can of it
+func (br *blockReader) fill() {
+	// keep what's left unprocessed at the front, there's less than two blocks
+	buf := make([]byte, len(br.in), len(br.in)+streamChunk)
+	copy(buf, br.in)
+	n, err := br.r.Read(buf[len(buf):cap(buf)])
+	buf = buf[:len(buf)+n]
+	k := crypt(br.m, buf, br.decrypt)
+	br.out, br.in = buf[:k], buf[k:]
+	switch {
+	case err == io.EOF:
+		last, ferr := finish(br.m, br.in, br.decrypt)
+		if ferr != nil {
+			br.err = ferr
+			return
+		}
+		br.out = append(br.out, last...)
+		br.in, br.err = nil, io.EOF
+	case err != nil:
+		br.err = err
+	}
+}
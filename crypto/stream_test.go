@@ -0,0 +1,144 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+func TestBlockModes(t *testing.T) {
+	key, iv := RandAESKey(), utils.RandBytes(AESBlockSize)
+	block, _ := aes.NewCipher(key)
+	msg := utils.RandBytes(10 * AESBlockSize)
+
+	// two calls chain like one
+	ac := must(NewAESInCBCCipher(key))
+	got := make([]byte, len(msg))
+	enc := must(ac.Encrypter(iv))
+	enc.CryptBlocks(got[:3*AESBlockSize], msg[:3*AESBlockSize])
+	enc.CryptBlocks(got[3*AESBlockSize:], msg[3*AESBlockSize:])
+	want := make([]byte, len(msg))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(want, msg)
+	if !bytes.Equal(got, want) {
+		t.Fatal("CBC encrypter differs from crypto/cipher")
+	}
+	// in place
+	must(ac.Decrypter(iv)).CryptBlocks(got, got)
+	if !bytes.Equal(got, msg) {
+		t.Fatal("CBC decrypter didn't undo the encrypter")
+	}
+
+	ecb := must(NewAESInECBCipher(key))
+	ecb.Encrypter().CryptBlocks(got, msg)
+	for i := 0; i < len(msg); i += AESBlockSize {
+		block.Encrypt(want[i:], msg[i:])
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("ECB encrypter differs from the block cipher")
+	}
+
+	ctr := must(NewAESInCTRCipher(key))
+	want = ctr.Encrypt(msg)
+	s := ctr.Stream(0)
+	for i := 0; i < len(msg); i += 7 {
+		end := utils.MinInt(i+7, len(msg))
+		s.XORKeyStream(got[i:end], msg[i:end])
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("CTR stream in pieces differs from Encrypt")
+	}
+	ctr.Stream(21).XORKeyStream(got[21:], msg[21:])
+	if !bytes.Equal(got[21:], want[21:]) {
+		t.Fatal("CTR stream at an offset differs from Encrypt")
+	}
+}
+
+func TestStreams(t *testing.T) {
+	key, iv := RandAESKey(), utils.RandBytes(AESBlockSize)
+	ac := must(NewAESInCBCCipher(key))
+	for _, size := range []int{0, 1, 15, 16, 17, 100000} {
+		msg := utils.RandBytes(size)
+		want := must(ac.Encrypt(msg, iv))
+
+		// written a few bytes at a time
+		var ct bytes.Buffer
+		w := NewEncryptWriter(&ct, must(ac.Encrypter(iv)))
+		for i := 0; i < len(msg); i += 13 {
+			w.Write(msg[i:utils.MinInt(i+13, len(msg))])
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ct.Bytes(), want) {
+			t.Fatalf("%d bytes: encrypting writer differs from Encrypt", size)
+		}
+		var pt bytes.Buffer
+		w = NewDecryptWriter(&pt, must(ac.Decrypter(iv)))
+		if _, err := w.Write(want); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil || !bytes.Equal(pt.Bytes(), msg) {
+			t.Fatalf("%d bytes: decrypting writer: %v", size, err)
+		}
+
+		got, err := io.ReadAll(NewEncryptReader(iotest.OneByteReader(bytes.NewReader(msg)), must(ac.Encrypter(iv))))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("%d bytes: encrypting reader: %v", size, err)
+		}
+		got, err = io.ReadAll(NewDecryptReader(iotest.DataErrReader(bytes.NewReader(want)), must(ac.Decrypter(iv))))
+		if err != nil || !bytes.Equal(got, msg) {
+			t.Fatalf("%d bytes: decrypting reader: %v", size, err)
+		}
+	}
+
+	// a last block ending in 0 and a partial one
+	ecb := must(NewAESInECBCipher(key))
+	bad := make([]byte, 2*AESBlockSize)
+	ecb.Encrypter().CryptBlocks(bad, bad)
+	if _, err := io.ReadAll(NewDecryptReader(bytes.NewReader(bad), ecb.Decrypter())); !errors.Is(err, ErrInvalidPadding) {
+		t.Fatalf("bad padding: err = %v", err)
+	}
+	if _, err := io.ReadAll(NewDecryptReader(bytes.NewReader(bad[:20]), ecb.Decrypter())); !errors.Is(err, ErrPartialBlock) {
+		t.Fatalf("partial block: err = %v", err)
+	}
+	w := NewDecryptWriter(io.Discard, ecb.Decrypter())
+	w.Write(bad)
+	if err := w.Close(); !errors.Is(err, ErrInvalidPadding) {
+		t.Fatalf("bad padding: err = %v", err)
+	}
+}
+
+// failingWriter fails every write after the first n
+type failingWriter struct{ n int }
+
+func (fw *failingWriter) Write(p []byte) (int, error) {
+	if fw.n == 0 {
+		return 0, errors.New("write failed")
+	}
+	fw.n--
+	return len(p), nil
+}
+
+func TestStreamWriteError(t *testing.T) {
+	key, iv := RandAESKey(), utils.RandBytes(AESBlockSize)
+	w := NewEncryptWriter(&failingWriter{n: 1}, must(must(NewAESInCBCCipher(key)).Encrypter(iv)))
+	if _, err := w.Write(utils.RandBytes(2 * AESBlockSize)); err != nil {
+		t.Fatal(err)
+	}
+	_, err := w.Write(utils.RandBytes(2 * AESBlockSize))
+	if err == nil {
+		t.Fatal("write error was lost")
+	}
+	if _, err2 := w.Write(utils.RandBytes(AESBlockSize)); err2 != err {
+		t.Fatalf("later write: err = %v, want %v", err2, err)
+	}
+	if err2 := w.Close(); err2 != err {
+		t.Fatalf("close: err = %v, want %v", err2, err)
+	}
+}
@@ -2,11 +2,11 @@ package wangmd
 
 import (
 	"bytes"
-	"crypto/rand"
 	"encoding/binary"
 	"errors"
 	"math/bits"
 
+	"github.com/sukunrt/cryptopals/utils"
 	"golang.org/x/crypto/md4"
 )
 
@@ -201,7 +201,7 @@ func (wmd *WangMD4) GenCollision() ([]byte, []byte, error) {
 		if cnt > 1000000 {
 			return nil, nil, errors.New("failed")
 		}
-		rand.Read(m1)
+		utils.ReadRand(m1)
 		wmd.m = unpack(m1)
 	START:
 
@@ -0,0 +1,145 @@
+package crypto
+
+import (
+	"fmt"
+
+	bi "github.com/sukunrt/bigint"
+	"github.com/sukunrt/cryptopals/utils"
+)
+
+// WienerAttack recovers the private key of k when d < N^(1/4)/3. Then k/d,
+// with ed = 1 + k*phi(N), is one of the convergents of the continued fraction
+// of e/N and each convergent can be checked by solving for p and q
+func WienerAttack(k RSAKey) (RSA, error) {
+	e, n := k.E, k.N
+	// h/c runs through the convergents of e/N
+	h0, h1 := bi.Zero, bi.One
+	c0, c1 := bi.One, bi.Zero
+	for a, b := e, n; !b.Equal(bi.Zero); a, b = b, a.Mod(b) {
+		q := a.Div(b)
+		h0, h1 = h1, q.Mul(h1).Add(h0)
+		c0, c1 = c1, q.Mul(c1).Add(c0)
+		if h1.Equal(bi.Zero) {
+			continue
+		}
+		ed1 := e.Mul(c1).Sub(bi.One)
+		if !ed1.Mod(h1).Equal(bi.Zero) {
+			continue
+		}
+		// p and q are the roots of x^2 - (N - phi + 1)x + N
+		phi := ed1.Div(h1)
+		s := n.Sub(phi).Add(bi.One)
+		disc := s.Mul(s).Sub(n.Mul(bi.FromInt(4)))
+		if disc.Cmp(bi.Zero) < 0 {
+			continue
+		}
+		if r := disc.Sqrt(); r.Mul(r).Equal(disc) {
+			p, q := s.Add(r).Div(bi.Two), s.Sub(r).Div(bi.Two)
+			if p.Mul(q).Equal(n) {
+				return NewRSAFromPrimes(p, q, e)
+			}
+		}
+	}
+	return RSA{}, fmt.Errorf("wiener: d is not small enough: %w", ErrAttackFailed)
+}
+
+// CommonModulusAttack decrypts a message encrypted to two keys with the same
+// N and coprime exponents. With a*e1 + b*e2 = 1, c1^a * c2^b = m
+func CommonModulusAttack(c1, c2 []byte, k1, k2 RSAKey) ([]byte, error) {
+	if !k1.N.Equal(k2.N) {
+		return nil, fmt.Errorf("common modulus: keys have different moduli: %w", ErrAttackFailed)
+	}
+	if !gcd(k1.E, k2.E).Equal(bi.One) {
+		return nil, fmt.Errorf("common modulus: exponents aren't coprime: %w", ErrAttackFailed)
+	}
+	n := k1.N
+	a, b := egcd(k1.E, k2.E)
+	pow := func(c []byte, x bi.Int) bi.Int {
+		ci := bi.FromBytes(c)
+		if x.Cmp(bi.Zero) < 0 {
+			ci, x = ModInv(ci, n), bi.Zero.Sub(x)
+		}
+		return bi.Exp(ci, x, n)
+	}
+	m := pow(c1, a).Mul(pow(c2, b)).Mod(n).Bytes()
+	return append(utils.RepBytes(0, k1.Sz-len(m)), m...), nil
+}
+
+// BatchGCD returns gcd(n_i, prod of the other n_j) for every n_i in ns with
+// a product tree and a remainder tree, in quasilinear time instead of the
+// quadratic time of trying every pair
+func BatchGCD(ns []bi.Int) []bi.Int {
+	if len(ns) == 0 {
+		return nil
+	}
+	// tree[0] is ns and tree[i+1][j] is tree[i][2j] * tree[i][2j+1]
+	tree := [][]bi.Int{ns}
+	for level := ns; len(level) > 1; {
+		next := make([]bi.Int, (len(level)+1)/2)
+		for j := range next {
+			next[j] = level[2*j]
+			if 2*j+1 < len(level) {
+				next[j] = next[j].Mul(level[2*j+1])
+			}
+		}
+		tree = append(tree, next)
+		level = next
+	}
+	// walk down taking the product mod the square of each node
+	rems := tree[len(tree)-1]
+	for i := len(tree) - 2; i >= 0; i-- {
+		level := tree[i]
+		next := make([]bi.Int, len(level))
+		for j, x := range level {
+			next[j] = rems[j/2].Mod(x.Mul(x))
+		}
+		rems = next
+	}
+	res := make([]bi.Int, len(ns))
+	for i, n := range ns {
+		res[i] = gcd(rems[i].Div(n), n)
+	}
+	return res
+}
+
+// SharedPrimeAttack finds the keys among keys whose moduli share a prime with
+// another one and returns their private keys by index
+func SharedPrimeAttack(keys []RSAKey) map[int]RSA {
+	ns := make([]bi.Int, len(keys))
+	for i, k := range keys {
+		ns[i] = k.N
+	}
+	res := make(map[int]RSA)
+	for i, g := range BatchGCD(ns) {
+		if g.Equal(bi.One) {
+			continue
+		}
+		if g.Equal(ns[i]) {
+			// both primes are shared, with different moduli or a duplicate
+			// of this one, so fall back to going through the pairs
+			for j := range ns {
+				if d := gcd(ns[i], ns[j]); j != i && !d.Equal(bi.One) && !d.Equal(ns[i]) {
+					g = d
+					break
+				}
+			}
+			if g.Equal(ns[i]) {
+				continue
+			}
+		}
+		if r, err := NewRSAFromPrimes(g, ns[i].Div(g), keys[i].E); err == nil {
+			res[i] = r
+		}
+	}
+	return res
+}
+
+// FermatAttack factors the modulus of k with FermatFactor, which takes a few
+// steps when |p-q| is small next to N^(1/4)
+func FermatAttack(k RSAKey, steps int) (RSA, error) {
+	p, err := FermatFactor(k.N, steps)
+	if err != nil {
+		return RSA{}, fmt.Errorf("fermat: %w", err)
+	}
+	return NewRSAFromPrimes(p, k.N.Div(p), k.E)
+}
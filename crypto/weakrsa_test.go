@@ -0,0 +1,117 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	bi "github.com/sukunrt/bigint"
+)
+
+func TestWienerAttack(t *testing.T) {
+	// d below N^(1/4)/3 and e its inverse
+	p, q := RandPrimeN(64), RandPrimeN(64)
+	n := p.Mul(q)
+	phi := p.Sub(bi.One).Mul(q.Sub(bi.One))
+	d := RandInt(bi.Exp(bi.Two, bi.FromInt(250), bi.Zero))
+	for !gcd(d, phi).Equal(bi.One) {
+		d = d.Add(bi.One)
+	}
+	k := RSAKey{E: ModInv(d, phi), N: n, Sz: len(n.Bytes())}
+	r, err := WienerAttack(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.D.Equal(d) {
+		t.Fatalf("recovered d = %s, want %s", r.D, d)
+	}
+
+	r, err = NewRSA(RSAOptions{Bits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WienerAttack(r.PubKey()); !errors.Is(err, ErrAttackFailed) {
+		t.Fatalf("e = 65537: err = %v", err)
+	}
+}
+
+func TestCommonModulusAttack(t *testing.T) {
+	r1, err := NewRSA(RSAOptions{Bits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r2 RSA
+	for _, e := range []int{3, 5, 17, 257} {
+		if r2, err = NewRSAFromPrimes(r1.P, r1.Q, bi.FromInt(e)); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("hello world")
+	got, err := CommonModulusAttack(r1.Encrypt(msg), r2.Encrypt(msg), r1.PubKey(), r2.PubKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bytes.TrimLeft(got, "\x00"), msg) {
+		t.Fatalf("recovered %q", got)
+	}
+}
+
+func TestSharedPrimeAttack(t *testing.T) {
+	// keys 1 and 4 share a prime, the rest are sound
+	shared := RandPrimeN(32)
+	var keys []RSAKey
+	for i := 0; i < 6; i++ {
+		p := RandPrimeN(32)
+		if i == 1 || i == 4 {
+			p = shared
+		}
+		r, err := NewRSAFromPrimes(p, RandPrimeN(32), bi.FromInt(DefaultRSAExponent))
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, r.PubKey())
+	}
+	broken := SharedPrimeAttack(keys)
+	if len(broken) != 2 {
+		t.Fatalf("broke %d keys, want 2", len(broken))
+	}
+	for _, i := range []int{1, 4} {
+		r, ok := broken[i]
+		if !ok {
+			t.Fatalf("key %d not broken", i)
+		}
+		if err := r.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if !r.P.Equal(shared) && !r.Q.Equal(shared) {
+			t.Fatalf("key %d: shared prime not among the factors", i)
+		}
+	}
+
+	ns := []bi.Int{bi.FromInt(6), bi.FromInt(35), bi.FromInt(143), bi.FromInt(77)}
+	want := []int{1, 7, 11, 77}
+	for i, g := range BatchGCD(ns) {
+		if !g.Equal(bi.FromInt(want[i])) {
+			t.Fatalf("gcd for %s is %s, want %d", ns[i], g, want[i])
+		}
+	}
+}
+
+func TestFermatAttack(t *testing.T) {
+	p := RandPrimeN(64)
+	q := nextPrime(p.Add(RandInt(bi.Exp(bi.Two, bi.FromInt(200), bi.Zero))))
+	r, err := NewRSAFromPrimes(p, q, bi.FromInt(DefaultRSAExponent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := FermatAttack(r.PubKey(), 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.D.Equal(r.D) {
+		t.Fatal("recovered a different d")
+	}
+}
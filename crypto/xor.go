@@ -1,7 +1,6 @@
 package crypto
 
 import (
-	"fmt"
 	"math"
 	"strings"
 
@@ -108,9 +107,6 @@ func BreakRepeatingKeyXor(msg []byte) ([]byte, []byte) {
 	for i := 1; i <= 100; i++ {
 		a, b, c, d := msg[0:i], msg[i:2*i], msg[2*i:3*i], msg[3*i:4*i]
 		dist := normalisedHammingDistance(a, b, c, d)
-		if dist < 3.0 {
-			fmt.Println(i, dist)
-		}
 		if dist < minDist-1e-6 {
 			// prefer smaller keys over larger keys
 			if i%keySize == 0 && dist > minDist-1e-1 {
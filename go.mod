@@ -4,4 +4,4 @@ go 1.19
 
 require github.com/sukunrt/bigint v0.0.0-20230723133015-74ec22e1d33f
 
-require golang.org/x/crypto v0.7.0 // indirect
+require golang.org/x/crypto v0.7.0
@@ -1,16 +1,161 @@
 package main
 
 import (
-	"math/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sukunrt/cryptopals/utils"
 )
 
-func init() {
-	// b := make([]byte, 8)
-	// crand.Read(b)
-	// rand.Seed(int64(binary.BigEndian.Uint64(b)))
-	rand.Seed(35)
-}
+const usage = `usage:
+  cryptopals list                 list all the registered challenges
+  cryptopals run <set>.<n>...     run the given challenges, e.g. run 3.17
+  cryptopals run --set <set>      run every challenge in a set
+  cryptopals run --all            run every challenge
+  cryptopals run --json ...       print a JSON report instead of a summary
+  cryptopals run --seed <n> ...   use a deterministic randomness source seeded
+                                  with n instead of crypto/rand
+`
+
+var (
+	errUsage  = errors.New("invalid usage")
+	errFailed = errors.New("some challenges failed")
+)
 
 func main() {
-	Solve8_58()
+	err := runCLI(os.Args[1:], os.Stdout)
+	if errors.Is(err, errUsage) {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func runCLI(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "list":
+		for _, c := range allChallenges() {
+			fmt.Fprintf(out, "%-6s %s\n", c.ID(), c.Title)
+		}
+		return nil
+	case "run":
+		return runCmd(args[1:], out)
+	case "help", "-h", "--help":
+		fmt.Fprint(out, usage)
+		return nil
+	}
+	return errUsage
+}
+
+func runCmd(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	set := fs.Int("set", 0, "run every challenge in the set")
+	all := fs.Bool("all", false, "run every challenge")
+	asJSON := fs.Bool("json", false, "print a JSON report")
+	seed := fs.Int64("seed", 0, "seed for a deterministic randomness source")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	var cs []Challenge
+	switch {
+	case *all:
+		cs = allChallenges()
+	case *set != 0:
+		cs = challengesInSet(*set)
+		if len(cs) == 0 {
+			return fmt.Errorf("no challenges registered for set %d", *set)
+		}
+	case fs.NArg() > 0:
+		for _, id := range fs.Args() {
+			c, ok := lookupChallenge(id)
+			if !ok {
+				return fmt.Errorf("unknown challenge %s", id)
+			}
+			cs = append(cs, c)
+		}
+	default:
+		return errUsage
+	}
+
+	for _, c := range cs {
+		if err := c.checkInputs(); err != nil {
+			return err
+		}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			utils.UseSeededRand(*seed)
+		}
+	})
+
+	report := make([]reportEntry, 0, len(cs))
+	for _, c := range cs {
+		if !*asJSON {
+			fmt.Fprintf(out, "=== %s %s\n", c.ID(), c.Title)
+		}
+		res := c.Run()
+		e := reportEntry{ID: c.ID(), Title: c.Title, Result: res}
+		if res.Err != nil {
+			e.Error = res.Err.Error()
+		}
+		report = append(report, e)
+		if !*asJSON {
+			printResult(out, res)
+		}
+	}
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	}
+	for _, e := range report {
+		if !e.Result.Pass {
+			return errFailed
+		}
+	}
+	return nil
+}
+
+// reportEntry is a single challenge in the JSON report
+type reportEntry struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Result Result `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+func printResult(out io.Writer, res Result) {
+	status := "PASS"
+	if !res.Pass {
+		status = "FAIL"
+	}
+	fmt.Fprintf(out, "--- %s (%s", status, res.Elapsed.Round(time.Millisecond))
+	if res.Queries > 0 {
+		fmt.Fprintf(out, ", %d queries", res.Queries)
+	}
+	fmt.Fprintln(out, ")")
+	if res.Recovered != "" {
+		fmt.Fprintf(out, "    recovered: %q\n", res.Recovered)
+	}
+	if !res.Pass && res.Expected != "" {
+		fmt.Fprintf(out, "    expected:  %q\n", res.Expected)
+	}
+	if res.Err != nil {
+		fmt.Fprintf(out, "    error: %v\n", res.Err)
+	}
 }
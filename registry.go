@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// Result is what a solver reports after running its challenge
+type Result struct {
+	Recovered string        `json:"recovered,omitempty"` // the secret recovered by the attack
+	Expected  string        `json:"expected,omitempty"`  // the value the attack should recover
+	Pass      bool          `json:"pass"`
+	Queries   int           `json:"queries,omitempty"` // number of oracle queries made by the attack
+	Elapsed   time.Duration `json:"elapsed_ns"`
+	Err       error         `json:"-"`
+}
+
+// check returns a Result that passes if recovered equals expected
+func check(recovered, expected string) Result {
+	return Result{Recovered: recovered, Expected: expected, Pass: recovered == expected}
+}
+
+// failed returns a failing Result for err
+func failed(err error) Result {
+	return Result{Err: err}
+}
+
+// Challenge is a single cryptopals challenge solver along with the
+// metadata needed to list and run it
+type Challenge struct {
+	Set    int
+	Num    int
+	Title  string
+	Inputs []string // files the solver reads, relative to the repo root
+	Slow   bool     // takes minutes to run, skipped by go test unless -slow is set
+	Solve  func() Result
+}
+
+// ID returns the challenge id in the <set>.<n> form used by the cli
+func (c Challenge) ID() string {
+	return fmt.Sprintf("%d.%d", c.Set, c.Num)
+}
+
+// checkInputs returns an error if any of the input files is missing
+func (c Challenge) checkInputs() error {
+	for _, f := range c.Inputs {
+		if _, err := os.Stat(f); err != nil {
+			return fmt.Errorf("challenge %s: missing input %s: %w", c.ID(), f, err)
+		}
+	}
+	return nil
+}
+
+// Run runs the solver and records how long it took
+func (c Challenge) Run() Result {
+	st := time.Now()
+	res := c.Solve()
+	res.Elapsed = time.Since(st)
+	if res.Err != nil {
+		res.Pass = false
+	}
+	return res
+}
+
+var registry = make(map[string]Challenge)
+
+// register adds c to the registry. Solvers call it from an init function
+// in the file for their set
+func register(cs ...Challenge) {
+	for _, c := range cs {
+		if _, ok := registry[c.ID()]; ok {
+			panic(fmt.Sprintf("challenge %s registered twice", c.ID()))
+		}
+		registry[c.ID()] = c
+	}
+}
+
+// lookupChallenge returns the challenge registered with id
+func lookupChallenge(id string) (Challenge, bool) {
+	c, ok := registry[id]
+	return c, ok
+}
+
+// allChallenges returns every registered challenge ordered by set and number
+func allChallenges() []Challenge {
+	res := make([]Challenge, 0, len(registry))
+	for _, c := range registry {
+		res = append(res, c)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Set != res[j].Set {
+			return res[i].Set < res[j].Set
+		}
+		return res[i].Num < res[j].Num
+	})
+	return res
+}
+
+// challengesInSet returns the registered challenges of set ordered by number
+func challengesInSet(set int) []Challenge {
+	var res []Challenge
+	for _, c := range allChallenges() {
+		if c.Set == set {
+			res = append(res, c)
+		}
+	}
+	return res
+}
@@ -3,70 +3,102 @@ package main
 import (
 	"bufio"
 	"encoding/base64"
-	"fmt"
 	"math"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/sukunrt/cryptopals/crypto"
 	"github.com/sukunrt/cryptopals/utils"
 )
 
-func Solve1_4() {
-	f, _ := os.Open("inputs/1-1.txt")
+func init() {
+	register(
+		Challenge{Set: 1, Num: 4, Title: "Detect single-character XOR", Inputs: []string{"inputs/1-1.txt"}, Solve: Solve1_4},
+		Challenge{Set: 1, Num: 6, Title: "Break repeating-key XOR", Inputs: []string{"inputs/1-6.txt"}, Solve: Solve1_6},
+		Challenge{Set: 1, Num: 7, Title: "AES in ECB mode", Inputs: []string{"inputs/1-7.txt"}, Solve: Solve1_7},
+		Challenge{Set: 1, Num: 8, Title: "Detect AES in ECB mode", Inputs: []string{"inputs/1-8.txt"}, Solve: Solve1_8},
+	)
+}
+
+func Solve1_4() Result {
+	f, err := os.Open("inputs/1-1.txt")
+	if err != nil {
+		return failed(err)
+	}
+	defer f.Close()
 	scanner := bufio.NewScanner(f)
-	msg, key, score := []byte{}, byte(0), math.Inf(1)
+	msg, score := []byte{}, math.Inf(1)
 	for scanner.Scan() {
 		t := scanner.Text()
-		m, k, s := crypto.BreakSingleCharacterXor(utils.FromHexString(t))
+		m, _, s := crypto.BreakSingleCharacterXor(utils.FromHexString(t))
 		if s < score {
 			score = s
-			key = k
 			msg = m
 		}
 	}
-	fmt.Println(string(msg), string([]byte{key}), score)
+	return check(string(msg), "Now that the party is jumping\n")
 }
 
-func Solve1_6() {
-	f, _ := os.Open("inputs/1-6.txt")
+func Solve1_6() Result {
+	f, err := os.Open("inputs/1-6.txt")
+	if err != nil {
+		return failed(err)
+	}
+	defer f.Close()
 	scanner := bufio.NewScanner(f)
 	input := make([]byte, 0)
 	for scanner.Scan() {
 		t := scanner.Text()
 		b, err := base64.StdEncoding.DecodeString(t)
 		if err != nil {
-			panic(err)
+			return failed(err)
 		}
 		input = append(input, b...)
 	}
-	plaintext, key := crypto.BreakRepeatingKeyXor(input)
-	fmt.Println(string(plaintext), string(key))
+	_, key := crypto.BreakRepeatingKeyXor(input)
+	return check(string(key), "Terminator X: Bring the noise")
 }
 
-func Solve1_7() {
-	f, _ := os.Open("inputs/1-7.txt")
+func Solve1_7() Result {
+	f, err := os.Open("inputs/1-7.txt")
+	if err != nil {
+		return failed(err)
+	}
+	defer f.Close()
 	scanner := bufio.NewScanner(f)
 	input := make([]byte, 0)
 	for scanner.Scan() {
 		t := scanner.Text()
 		b, err := base64.StdEncoding.DecodeString(t)
 		if err != nil {
-			panic(err)
+			return failed(err)
 		}
 		input = append(input, b...)
 	}
-	plaintext := crypto.NewAESInCBCCipher([]byte("YELLOW SUBMARINE")).Decrypt(input, make([]byte, crypto.AESBlockSize))
-	fmt.Println(string(plaintext))
+	ac, err := crypto.NewAESInECBCipher([]byte("YELLOW SUBMARINE"))
+	if err != nil {
+		return failed(err)
+	}
+	plaintext := ac.Decrypt(input)
+	firstLine := strings.SplitN(string(plaintext), "\n", 2)[0]
+	return check(firstLine, "I'm back and I'm ringin' the bell ")
 }
 
-func Solve1_8() {
-	f, _ := os.Open("inputs/1-8.txt")
+func Solve1_8() Result {
+	f, err := os.Open("inputs/1-8.txt")
+	if err != nil {
+		return failed(err)
+	}
+	defer f.Close()
 	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
+	found := 0
+	for line := 1; scanner.Scan(); line++ {
 		t := scanner.Text()
 		b := utils.FromHexString(t)
 		if cnt := crypto.DetectAESinECBMode(b); cnt > 0 {
-			fmt.Println(cnt)
+			found = line
 		}
 	}
+	return check(strconv.Itoa(found), "133")
 }
@@ -3,29 +3,45 @@ package main
 import (
 	"crypto/aes"
 	"encoding/base64"
-	"fmt"
-	"math/rand"
 	"strings"
 
 	"github.com/sukunrt/cryptopals/crypto"
 	"github.com/sukunrt/cryptopals/utils"
 )
 
-func Solve2_10() {
+func init() {
+	register(
+		Challenge{Set: 2, Num: 10, Title: "Implement CBC mode", Inputs: []string{"inputs/2-10.txt"}, Solve: Solve2_10},
+		Challenge{Set: 2, Num: 12, Title: "Byte-at-a-time ECB decryption (Simple)", Solve: Solve2_12},
+		Challenge{Set: 2, Num: 13, Title: "ECB cut-and-paste", Solve: Solve2_13},
+		Challenge{Set: 2, Num: 14, Title: "Byte-at-a-time ECB decryption (Harder)", Solve: Solve2_14},
+		Challenge{Set: 2, Num: 16, Title: "CBC bitflipping attacks", Solve: Solve2_16},
+	)
+}
+
+func Solve2_10() Result {
 	scanner := utils.GetFileScanner("inputs/2-10.txt")
 	input := make([]byte, 0)
 	for scanner.Scan() {
 		t := scanner.Text()
 		b, err := base64.StdEncoding.DecodeString(t)
 		if err != nil {
-			panic(err)
+			return failed(err)
 		}
 		input = append(input, b...)
 	}
 	key := []byte("YELLOW SUBMARINE")
 	iv := make([]byte, crypto.AESBlockSize)
-	msg := crypto.NewAESInCBCCipher(key).Decrypt(input, iv)
-	fmt.Println(string(msg))
+	ac, err := crypto.NewAESInCBCCipher(key)
+	if err != nil {
+		return failed(err)
+	}
+	msg, err := ac.Decrypt(input, iv)
+	if err != nil {
+		return failed(err)
+	}
+	firstLine := strings.SplitN(string(msg), "\n", 2)[0]
+	return check(firstLine, "I'm back and I'm ringin' the bell ")
 }
 
 type aesUserProfile struct {
@@ -41,11 +57,15 @@ func (aup aesUserProfile) Decrypt(b []byte) map[string]string {
 	return utils.ParseURLEncoding(s)
 }
 
-func NewAESUserProfile(key []byte) aesUserProfile {
-	return aesUserProfile{cipher: crypto.NewAESInECBCipher(key)}
+func NewAESUserProfile(key []byte) (aesUserProfile, error) {
+	c, err := crypto.NewAESInECBCipher(key)
+	if err != nil {
+		return aesUserProfile{}, err
+	}
+	return aesUserProfile{cipher: c}, nil
 }
 
-func Solve2_12() {
+func Solve2_12() Result {
 	secret := `Um9sbGluJyBpbiBteSA1LjAKV2l0aCBteSByYWctdG9wIGRvd24gc28gbXkg
 aGFpciBjYW4gYmxvdwpUaGUgZ2lybGllcyBvbiBzdGFuZGJ5IHdhdmluZyBq
 dXN0IHRvIHNheSBoaQpEaWQgeW91IHN0b3A/IE5vLCBJIGp1c3QgZHJvdmUg
@@ -53,14 +73,23 @@ YnkK`
 	secret = strings.Replace(secret, "\n", "", -1)
 	randKey := utils.RandBytes(crypto.AESBlockSize)
 	bsecret, _ := base64.StdEncoding.DecodeString(secret)
-	encFunc := crypto.AESInECBWithSecretEncryptor(randKey, bsecret)
-	realSecret := crypto.BreakSecretInECB(encFunc)
-	fmt.Println(string(realSecret))
+	encFunc, err := crypto.AESInECBWithSecretEncryptor(randKey, bsecret)
+	if err != nil {
+		return failed(err)
+	}
+	realSecret, err := crypto.BreakSecretInECB(encFunc)
+	if err != nil {
+		return failed(err)
+	}
+	return check(string(realSecret), string(bsecret))
 }
 
-func Solve2_13() {
+func Solve2_13() Result {
 	key := utils.RandBytes(crypto.AESBlockSize)
-	aup := NewAESUserProfile(key)
+	aup, err := NewAESUserProfile(key)
+	if err != nil {
+		return failed(err)
+	}
 	adminBlock := utils.PadBytes([]byte("admin"), crypto.AESBlockSize)
 	emailPrefix := utils.RepBytes('A', aes.BlockSize-len("email="))
 	emailSuffix := []byte("@x.com")
@@ -84,12 +113,15 @@ func Solve2_13() {
 	encProfile = aup.Encrypt(string(email))
 	encProfile = encProfile[:len(encProfile)-crypto.AESBlockSize]
 	encProfile = append(encProfile, adminCipherBlock...)
-	fmt.Println(aup.Decrypt(encProfile))
+	return check(aup.Decrypt(encProfile)["role"], "admin")
 }
 
-func Solve2_14() {
+func Solve2_14() Result {
 	key := utils.RandBytes(crypto.AESBlockSize)
-	aesCipher := crypto.NewAESInECBCipher(key)
+	aesCipher, err := crypto.NewAESInECBCipher(key)
+	if err != nil {
+		return failed(err)
+	}
 	minPrefixLen := 3
 	maxPrefixLen := 31
 	secretS := `Um9sbGluJyBpbiBteSA1LjAKV2l0aCBteSByYWctdG9wIGRvd24gc28gbXkg
@@ -99,39 +131,59 @@ YnkK`
 	secretS = strings.Replace(secretS, "\n", "", -1)
 	secret, err := base64.StdEncoding.DecodeString(secretS)
 	if err != nil {
-		panic(err)
+		return failed(err)
 	}
 	encFunc := func(b []byte) []byte {
 		prefixLen := 0
 		for prefixLen%crypto.AESBlockSize != 0 {
-			prefixLen = minPrefixLen + rand.Intn(maxPrefixLen-minPrefixLen)
+			prefixLen = minPrefixLen + utils.RandIntn(maxPrefixLen-minPrefixLen)
 		}
 		prefix := utils.RandBytes(prefixLen)
 		msg := utils.ConcatBytes(prefix, b, secret)
 		return aesCipher.Encrypt(msg)
 	}
 
-	realSecret := crypto.BreakSecretInECBWithRandomPrefix(encFunc)
-	fmt.Println(string(realSecret), len(realSecret), len(secret))
+	realSecret, err := crypto.BreakSecretInECBWithRandomPrefix(encFunc)
+	if err != nil {
+		return failed(err)
+	}
+	return check(string(realSecret), string(secret))
 }
 
-func Solve2_16() {
+func Solve2_16() Result {
 	key := utils.RandBytes(crypto.AESBlockSize)
-	cipher := crypto.NewAESInCBCCipher(key)
+	cipher, err := crypto.NewAESInCBCCipher(key)
+	if err != nil {
+		return failed(err)
+	}
+	// the attack always passes a full size iv
 	encFunc := func(b, iv []byte) []byte {
 		cookie := utils.GenerateUserCookie(string(b))
-		return cipher.Encrypt([]byte(cookie), iv)
+		ct, _ := cipher.Encrypt([]byte(cookie), iv)
+		return ct
 	}
 
+	queries := 0
 	passFunc := func(b, iv []byte) bool {
-		msg := cipher.Decrypt(b, iv)
+		queries++
+		msg, err := cipher.Decrypt(b, iv)
+		if err != nil {
+			return false
+		}
 		role := utils.FindKeyInCookie(string(msg), "admin")
 		v := utils.FindKeyInCookie(string(msg), "userdata")
-		if role == "true" && v != "" {
-			fmt.Println(string(msg))
-		}
 		return role == "true" && v != ""
 	}
 
-	crypto.BreakCBCWithBitFlipping(encFunc, passFunc)
+	cipherText, iv, err := crypto.BreakCBCWithBitFlipping(encFunc, passFunc)
+	if err != nil {
+		return failed(err)
+	}
+	msg, err := cipher.Decrypt(cipherText, iv)
+	if err != nil {
+		return failed(err)
+	}
+	res := check(utils.FindKeyInCookie(string(msg), "admin"), "true")
+	res.Queries = queries
+	return res
 }
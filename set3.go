@@ -4,8 +4,7 @@ import (
 	"bytes"
 	"encoding/base64"
 	"fmt"
-	"math/rand"
-	"sort"
+	"strconv"
 	"time"
 
 	"github.com/sukunrt/cryptopals/crypto"
@@ -13,58 +12,120 @@ import (
 	"github.com/sukunrt/cryptopals/utils"
 )
 
-func Solve3_17() {
+func init() {
+	register(
+		Challenge{Set: 3, Num: 17, Title: "The CBC padding oracle", Solve: Solve3_17},
+		Challenge{Set: 3, Num: 18, Title: "Implement CTR, the stream cipher mode", Solve: Solve3_18},
+		Challenge{Set: 3, Num: 19, Title: "Break fixed-nonce CTR mode using substitutions", Solve: Solve3_19},
+		Challenge{Set: 3, Num: 20, Title: "Break fixed-nonce CTR statistically", Inputs: []string{"inputs/3-20.txt"}, Solve: Solve3_20},
+		Challenge{Set: 3, Num: 21, Title: "Implement the MT19937 Mersenne Twister RNG", Solve: Solve3_21},
+		Challenge{Set: 3, Num: 22, Title: "Crack an MT19937 seed", Solve: Solve3_22},
+		Challenge{Set: 3, Num: 23, Title: "Clone an MT19937 RNG from its output", Solve: Solve3_23},
+		Challenge{Set: 3, Num: 24, Title: "Create the MT19937 stream cipher and break it", Solve: Solve3_24},
+	)
+}
+
+func Solve3_17() Result {
 	key := utils.RandBytes(crypto.AESBlockSize)
-	cipher := crypto.NewAESInCBCCipher(key)
-	encFunc := func() ([]byte, []byte) {
-		msgs := []string{
-			"MDAwMDAwTm93IHRoYXQgdGhlIHBhcnR5IGlzIGp1bXBpbmc=",
-			"MDAwMDAxV2l0aCB0aGUgYmFzcyBraWNrZWQgaW4gYW5kIHRoZSBWZWdhJ3MgYXJlIHB1bXBpbic=",
-			"MDAwMDAyUXVpY2sgdG8gdGhlIHBvaW50LCB0byB0aGUgcG9pbnQsIG5vIGZha2luZw==",
-			"MDAwMDAzQ29va2luZyBNQydzIGxpa2UgYSBwb3VuZCBvZiBiYWNvbg==",
-			"MDAwMDA0QnVybmluZyAnZW0sIGlmIHlvdSBhaW4ndCBxdWljayBhbmQgbmltYmxl",
-			"MDAwMDA1SSBnbyBjcmF6eSB3aGVuIEkgaGVhciBhIGN5bWJhbA==",
-			"MDAwMDA2QW5kIGEgaGlnaCBoYXQgd2l0aCBhIHNvdXBlZCB1cCB0ZW1wbw==",
-			"MDAwMDA3SSdtIG9uIGEgcm9sbCwgaXQncyB0aW1lIHRvIGdvIHNvbG8=",
-			"MDAwMDA4b2xsaW4nIGluIG15IGZpdmUgcG9pbnQgb2g=",
-			"MDAwMDA5aXRoIG15IHJhZy10b3AgZG93biBzbyBteSBoYWlyIGNhbiBibG93",
-		}
+	cipher, err := crypto.NewAESInCBCCipher(key)
+	if err != nil {
+		return failed(err)
+	}
+	msgs := []string{
+		"MDAwMDAwTm93IHRoYXQgdGhlIHBhcnR5IGlzIGp1bXBpbmc=",
+		"MDAwMDAxV2l0aCB0aGUgYmFzcyBraWNrZWQgaW4gYW5kIHRoZSBWZWdhJ3MgYXJlIHB1bXBpbic=",
+		"MDAwMDAyUXVpY2sgdG8gdGhlIHBvaW50LCB0byB0aGUgcG9pbnQsIG5vIGZha2luZw==",
+		"MDAwMDAzQ29va2luZyBNQydzIGxpa2UgYSBwb3VuZCBvZiBiYWNvbg==",
+		"MDAwMDA0QnVybmluZyAnZW0sIGlmIHlvdSBhaW4ndCBxdWljayBhbmQgbmltYmxl",
+		"MDAwMDA1SSBnbyBjcmF6eSB3aGVuIEkgaGVhciBhIGN5bWJhbA==",
+		"MDAwMDA2QW5kIGEgaGlnaCBoYXQgd2l0aCBhIHNvdXBlZCB1cCB0ZW1wbw==",
+		"MDAwMDA3SSdtIG9uIGEgcm9sbCwgaXQncyB0aW1lIHRvIGdvIHNvbG8=",
+		"MDAwMDA4b2xsaW4nIGluIG15IGZpdmUgcG9pbnQgb2g=",
+		"MDAwMDA5aXRoIG15IHJhZy10b3AgZG93biBzbyBteSBoYWlyIGNhbiBibG93",
+	}
+	encFunc := func() ([]byte, []byte, []byte) {
 		IV := utils.RandBytes(crypto.AESBlockSize)
-		idx := rand.Intn(len(msgs))
-		msg, err := base64.StdEncoding.DecodeString(msgs[idx])
-		if err != nil {
-			panic(err)
-		}
-		return cipher.Encrypt(msg, IV), IV
+		idx := utils.RandIntn(len(msgs))
+		msg := utils.FromBase64String(msgs[idx])
+		ct, _ := cipher.Encrypt(msg, IV)
+		return ct, IV, msg
 	}
 
 	paddingOracle := func(b []byte, IV []byte) bool {
-		msg := cipher.DecryptWithoutPadding(b, IV)
-		msgPaddingRemoved := utils.RemovePad(msg)
-		return !bytes.Equal(msg, msgPaddingRemoved)
+		_, err := cipher.DecryptStrict(b, IV)
+		return err == nil
 	}
+	attack := crypto.NewPaddingOracleAttack(paddingOracle, crypto.AESBlockSize, crypto.PKCS7Padding)
 
+	var res Result
 	for i := 0; i < 100; i++ {
-		cipherText, IV := encFunc()
-		plainText := crypto.BreakCBCWithPaddingOracle(cipherText, IV, paddingOracle)
-		fmt.Println(string(plainText))
+		cipherText, IV, msg := encFunc()
+		plainText, err := attack.Decrypt(cipherText, IV)
+		if err != nil {
+			return failed(err)
+		}
+		unpadded, err := utils.Unpad(plainText, crypto.AESBlockSize)
+		if err != nil {
+			return failed(err)
+		}
+		res = check(string(unpadded), string(msg))
+		if !res.Pass {
+			res.Queries = attack.Queries
+			return res
+		}
+	}
+
+	// the oracle also encrypts, CBC-R
+	forged := "000010Encrypted without the key"
+	cipherText, IV, err := attack.Encrypt([]byte(forged))
+	if err != nil {
+		return failed(err)
+	}
+	plainText, err := cipher.DecryptStrict(cipherText, IV)
+	if err != nil {
+		return failed(err)
 	}
+	res = check(string(plainText), forged)
+	res.Queries = attack.Queries
+	return res
 }
 
-func Solve3_18() {
+func Solve3_18() Result {
 	s := "L77na/nrFsKvynd6HzOoG7GHTLXsTVu9qvY/2syLXzhPweyyMTJULu/6/kXX0KSvoOLSFQ=="
 	msg, err := base64.StdEncoding.DecodeString(s)
 	if err != nil {
-		panic(err)
+		return failed(err)
 	}
 	key := []byte("YELLOW SUBMARINE")
 	nonce := utils.RepBytes(0, crypto.AESBlockSize/2)
-	aesCipher := crypto.NewAESInCTRCipherWithNonce(key, nonce)
+	aesCipher, err := crypto.NewAESInCTRCipherWithNonce(key, nonce)
+	if err != nil {
+		return failed(err)
+	}
 	plainText := aesCipher.Decrypt(msg)
-	fmt.Println(string(plainText))
+	return check(string(plainText), "Yo, VIP Let's kick it Ice, Ice, baby Ice, Ice, baby ")
+}
+
+// matchRatio returns the fraction of bytes of got that match want
+func matchRatio(got, want []byte) float64 {
+	if len(want) == 0 {
+		return 1
+	}
+	matches := 0
+	for i := 0; i < len(got) && i < len(want); i++ {
+		if got[i] == want[i] {
+			matches++
+		}
+	}
+	return float64(matches) / float64(len(want))
 }
 
-func Solve3_19() {
+// statisticalPass is the fraction of bytes a statistical attack has to get
+// right to pass. Frequency analysis gets a few odd bytes wrong in the columns
+// which only have a handful of samples
+const statisticalPass = 0.9
+
+func Solve3_19() Result {
 	texts := []string{
 		"SSBoYXZlIG1ldCB0aGVtIGF0IGNsb3NlIG9mIGRheQ==",
 		"Q29taW5nIHdpdGggdml2aWQgZmFjZXM=",
@@ -106,58 +167,49 @@ func Solve3_19() {
 		"SGUsIHRvbywgaGFzIGJlZW4gY2hhbmdlZCBpbiBoaXMgdHVybiw=",
 		"VHJhbnNmb3JtZWQgdXR0ZXJseTo=",
 		"QSB0ZXJyaWJsZSBiZWF1dHkgaXMgYm9ybi4=",
-	}[:5]
+	}
 	plainTexts := make([][]byte, len(texts))
 	for i, t := range texts {
 		plainTexts[i] = utils.FromBase64String(t)
 	}
 	key := utils.RandBytes(crypto.AESBlockSize)
-	cipher := crypto.NewAESInCTRCipher(key)
+	cipher, err := crypto.NewAESInCTRCipher(key)
+	if err != nil {
+		return failed(err)
+	}
 	cipherTexts := make([][]byte, len(plainTexts))
+	maxLen := 0
 	for i, t := range plainTexts {
 		cipherTexts[i] = cipher.Encrypt(t)
+		if len(t) > maxLen {
+			maxLen = len(t)
+		}
 	}
 
-	byteMap := make(map[byte]byte)
-	revByteMap := make(map[byte]byte)
-	for i := 0; i < len(cipherTexts); i++ {
-		for j := 0; j < len(cipherTexts[i]) && j < crypto.AESBlockSize; j++ {
-			byteMap[cipherTexts[i][j]] = plainTexts[i][j]
-			if v, ok := revByteMap[plainTexts[i][j]]; ok {
-				fmt.Println(plainTexts[i][j], "Already Assigned to ", v, cipherTexts[i][j], i, j)
-				panic("Failed")
+	// Every ciphertext is xored with the same keystream so the ith bytes of all
+	// the ciphertexts are a single byte xor cipher
+	keyStream := make([]byte, maxLen)
+	for j := 0; j < maxLen; j++ {
+		var column []byte
+		for _, c := range cipherTexts {
+			if j < len(c) {
+				column = append(column, c[j])
 			}
-			revByteMap[plainTexts[i][j]] = cipherTexts[i][j]
 		}
+		_, keyStream[j], _ = crypto.BreakSingleCharacterXor(column)
 	}
 
-	byteCountMap := make(map[byte]int)
-	for i := 0; i < len(cipherTexts); i++ {
-		for j := 0; j < len(cipherTexts[i]) && j < crypto.AESBlockSize; j++ {
-			byteCountMap[cipherTexts[i][j]]++
-		}
-	}
-	type pair struct {
-		B   byte
-		Cnt int
-	}
-	var byteCounts []pair
-	for k, v := range byteCountMap {
-		byteCounts = append(byteCounts, pair{k, v})
-	}
-	sort.SliceStable(byteCounts, func(i, j int) bool {
-		if byteCounts[i].Cnt != byteCounts[j].Cnt {
-			return byteCounts[i].Cnt > byteCounts[j].Cnt
-		}
-		return byteCounts[i].B > byteCounts[j].B
-	})
-
-	for i := 0; i < 5; i++ {
-		fmt.Println(byteCounts[i].B, byteMap[byteCounts[i].B], string(byteMap[byteCounts[i].B]), byteCounts[i].Cnt)
+	recovered := make([][]byte, len(cipherTexts))
+	for i, c := range cipherTexts {
+		recovered[i] = utils.XorBytes(c, keyStream[:len(c)])
 	}
+	got, want := utils.ConcatBytes(recovered...), utils.ConcatBytes(plainTexts...)
+	res := Result{Recovered: string(recovered[0]), Expected: string(plainTexts[0])}
+	res.Pass = matchRatio(got, want) >= statisticalPass
+	return res
 }
 
-func Solve3_20() {
+func Solve3_20() Result {
 	scanner := utils.GetFileScanner("inputs/3-20.txt")
 	var plainTexts [][]byte
 	for scanner.Scan() {
@@ -175,7 +227,10 @@ func Solve3_20() {
 		truncatedPlainTexts[i] = p[:minLen]
 	}
 	key := utils.RandBytes(crypto.AESBlockSize)
-	cipher := crypto.NewAESInCTRCipher(key)
+	cipher, err := crypto.NewAESInCTRCipher(key)
+	if err != nil {
+		return failed(err)
+	}
 	cipherTexts := make([][]byte, len(plainTexts))
 	for i, p := range truncatedPlainTexts {
 		cipherTexts[i] = cipher.Encrypt(p)
@@ -183,52 +238,43 @@ func Solve3_20() {
 
 	msg := utils.ConcatBytes(cipherTexts...)
 	decryptedMsg, _ := crypto.BreakRepeatingKeyXorWithKeySize(msg, minLen)
-	for i, j := 0, 0; i < len(decryptedMsg); i, j = i+minLen, j+1 {
-		if !bytes.Equal(decryptedMsg[i:i+minLen], truncatedPlainTexts[j]) {
-			fmt.Println("Failed to decode")
-			fmt.Println(string(decryptedMsg[i:i+minLen]), string(truncatedPlainTexts[j]))
-		} else {
-			fmt.Println("done")
-		}
-	}
+	res := Result{Recovered: string(decryptedMsg[:minLen]), Expected: string(truncatedPlainTexts[0])}
+	res.Pass = matchRatio(decryptedMsg, utils.ConcatBytes(truncatedPlainTexts...)) >= statisticalPass
+	return res
 }
 
-func Solve3_21() {
-	for seed := 0; seed < 1000; seed++ {
-		mt1 := mt.NewMTRNG(seed)
-		mt2 := mt.NewMTRNG(seed)
-		for i := 0; i < 1000; i++ {
-			if mt1.Int() != mt2.Int() {
-				fmt.Println("failed")
-			}
-		}
+func Solve3_21() Result {
+	// The first outputs of the reference implementation seeded with 5489
+	want := []int{3499211612, 581869302, 3890346734, 3586334585, 545404204}
+	m := mt.NewMTRNG(5489)
+	got := make([]int, len(want))
+	for i := range got {
+		got[i] = m.Int()
 	}
+	return check(fmt.Sprint(got), fmt.Sprint(want))
 }
 
-func Solve3_22() {
-	diff := rand.Intn(4 * 1000_0000_000)
+func Solve3_22() Result {
+	diff := utils.RandIntn(4 * 1000_0000_000)
 	seed := time.Now().Add(time.Duration(-1 * diff)).Unix()
 	m := mt.NewMTRNG(int(seed))
 	x := m.Int()
-	fmt.Println("Used", seed)
 	now := time.Now()
 	for {
-		seed = now.Unix()
-		y := mt.NewMTRNG(int(seed)).Int()
+		found := now.Unix()
+		y := mt.NewMTRNG(int(found)).Int()
 		if y == x {
-			fmt.Println("Found: ", seed)
-			break
+			return check(strconv.FormatInt(found, 10), strconv.FormatInt(seed, 10))
 		} else {
 			now = now.Add(-1 * time.Millisecond)
 		}
 	}
-
 }
 
 const MTStateSize = 624
 
-func Solve3_23() {
-	seed := rand.Intn(1 << 31)
+func Solve3_23() Result {
+	seed := utils.RandIntn(1 << 31)
 	m := mt.NewMTRNG(seed)
 	var state [MTStateSize]int
 	for i := 0; i < MTStateSize; i++ {
@@ -244,34 +290,37 @@ func Solve3_23() {
 			break
 		}
 	}
-	fmt.Println("success: ", success)
+	return Result{Pass: success}
 }
 
-func Solve3_24() {
-	seed := rand.Intn(1 << 16)
-	fmt.Println("Used Seed:", seed)
+func Solve3_24() Result {
+	seed := utils.RandIntn(1 << 16)
 	mtc := crypto.NewMTCipher(seed)
 	plainText := utils.RepBytes('A', 10)
 
-	prefix := utils.RandBytes(rand.Intn(100))
+	prefix := utils.RandBytes(utils.RandIntn(100))
 	inputPlainText := utils.ConcatBytes(prefix, plainText)
 	cipherText := mtc.Encrypt(inputPlainText)
 
 	msg := utils.ConcatBytes(utils.RepBytes('A', len(cipherText)-len(plainText)), plainText)
 	lastCipherTextN := cipherText[len(cipherText)-len(plainText):]
-	for trySeed := 0; ; trySeed++ {
+	foundSeed := -1
+	for trySeed := 0; trySeed < 1<<16; trySeed++ {
 		mtc := crypto.NewMTCipher(trySeed)
 		ct := mtc.Encrypt(msg)
 		lastN := ct[len(ct)-len(plainText):]
 		if bytes.Equal(lastN, lastCipherTextN) {
-			fmt.Println("Found Seed: ", trySeed)
+			foundSeed = trySeed
 			break
 		}
 	}
+	if foundSeed != seed {
+		return check(strconv.Itoa(foundSeed), strconv.Itoa(seed))
+	}
 
-	seed = int(time.Now().Unix()) - rand.Intn(1<<20)
+	seed = int(time.Now().Unix()) - utils.RandIntn(1<<20)
 	mtc = crypto.NewMTCipher(seed)
 	token := mtc.Bytes(5)
-	foundSeed := crypto.BreakMTCipherToken(token)
-	fmt.Println(seed, foundSeed)
+	foundSeed = crypto.BreakMTCipherToken(token)
+	return check(strconv.Itoa(foundSeed), strconv.Itoa(seed))
 }
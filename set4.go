@@ -3,7 +3,7 @@ package main
 import (
 	"bytes"
 	"encoding/hex"
-	"fmt"
+	"net"
 	"net/http"
 	"sort"
 	"strings"
@@ -13,10 +13,25 @@ import (
 	"github.com/sukunrt/cryptopals/utils"
 )
 
-func Solve4_25() {
+func init() {
+	register(
+		Challenge{Set: 4, Num: 25, Title: "Break \"random access read/write\" AES CTR", Inputs: []string{"inputs/4-25.txt"}, Solve: Solve4_25},
+		Challenge{Set: 4, Num: 26, Title: "CTR bitflipping", Solve: Solve4_26},
+		Challenge{Set: 4, Num: 27, Title: "Recover the key from CBC with IV=Key", Solve: Solve4_27},
+		Challenge{Set: 4, Num: 28, Title: "Implement a SHA-1 keyed MAC", Solve: Solve4_28},
+		Challenge{Set: 4, Num: 29, Title: "Break a SHA-1 keyed MAC using length extension", Solve: Solve4_29},
+		Challenge{Set: 4, Num: 30, Title: "Break an MD4 keyed MAC using length extension", Solve: Solve4_30},
+		Challenge{Set: 4, Num: 31, Title: "Implement and break HMAC-SHA1 with an artificial timing leak", Slow: true, Solve: Solve4_31},
+	)
+}
+
+func Solve4_25() Result {
 	plainText := "Imagine the \"edit\" function was exposed to attackers by means of an API call"
 	key := crypto.RandAESKey()
-	cipher := crypto.NewAESInCTRCipher(key)
+	cipher, err := crypto.NewAESInCTRCipher(key)
+	if err != nil {
+		return failed(err)
+	}
 	cipherText := cipher.Encrypt([]byte(plainText))
 	reEncryptF := func(original []byte) func([]byte, int) []byte {
 		cipherCopy := make([]byte, len(original))
@@ -36,7 +51,9 @@ func Solve4_25() {
 		ct := reEncrypt(text, i)
 		recoveredText[i] = ct[i] ^ cipherText[i] ^ text[0]
 	}
-	fmt.Println(string(recoveredText))
+	if res := check(string(recoveredText), plainText); !res.Pass {
+		return res
+	}
 
 	scanner := utils.GetFileScanner("inputs/4-25.txt")
 	b64Text := make([]byte, 0)
@@ -45,7 +62,10 @@ func Solve4_25() {
 		b64Text = append(b64Text, []byte(t)...)
 	}
 	cipherText = utils.FromBase64String(string(b64Text))
-	cbcCipher := crypto.NewAESInECBCipher([]byte("YELLOW SUBMARINE"))
+	cbcCipher, err := crypto.NewAESInECBCipher([]byte("YELLOW SUBMARINE"))
+	if err != nil {
+		return failed(err)
+	}
 	pt := cbcCipher.Decrypt(cipherText)
 
 	cipherText = cipher.Encrypt(pt)
@@ -56,36 +76,50 @@ func Solve4_25() {
 		ct := reEncrypt(text, i)
 		recoveredText[i] = ct[i] ^ cipherText[i] ^ text[0]
 	}
-	fmt.Println(string(recoveredText))
+	return check(string(recoveredText), string(pt))
 }
 
-func Solve4_26() {
+func Solve4_26() Result {
 	key := utils.RandBytes(crypto.AESBlockSize)
-	cipher := crypto.NewAESInCTRCipher(key)
+	cipher, err := crypto.NewAESInCTRCipher(key)
+	if err != nil {
+		return failed(err)
+	}
 	encFunc := func(b []byte) []byte {
 		cookie := utils.GenerateUserCookie(string(b))
 		return cipher.Encrypt([]byte(cookie))
 	}
 
+	queries := 0
 	passFunc := func(b []byte) bool {
+		queries++
 		msg := cipher.Decrypt(b)
 		role := utils.FindKeyInCookie(string(msg), "admin")
 		v := utils.FindKeyInCookie(string(msg), "userdata")
-		if role == "true" && v != "" {
-			fmt.Println(string(msg))
-		}
 		return role == "true" && v != ""
 	}
 
-	crypto.BreakCTRWithBitFlipping(encFunc, passFunc)
+	cipherText, err := crypto.BreakCTRWithBitFlipping(encFunc, passFunc)
+	if err != nil {
+		return failed(err)
+	}
+	res := check(utils.FindKeyInCookie(string(cipher.Decrypt(cipherText)), "admin"), "true")
+	res.Queries = queries
+	return res
 }
 
-func Solve4_27() {
+func Solve4_27() Result {
 	key := utils.RandBytes(crypto.AESBlockSize)
-	cipher := crypto.NewAESInCBCCipher(key)
+	cipher, err := crypto.NewAESInCBCCipher(key)
+	if err != nil {
+		return failed(err)
+	}
+	// the key is the iv so it is always the right size, and the attack only
+	// sends whole blocks
 	encFunc := func(b []byte) []byte {
 		cookie := utils.GenerateUserCookie(string(b))
-		return cipher.Encrypt([]byte(cookie), key)
+		ct, _ := cipher.Encrypt([]byte(cookie), key)
+		return ct
 	}
 
 	validate := func(b []byte) bool {
@@ -98,7 +132,7 @@ func Solve4_27() {
 	}
 
 	passFunc := func(b []byte) (bool, []byte) {
-		msg := cipher.Decrypt(b, key)
+		msg, _ := cipher.Decrypt(b, key)
 		if validate(msg) {
 			return true, nil
 		}
@@ -112,31 +146,25 @@ func Solve4_27() {
 		success, msg := passFunc(attackMsg)
 		if !success {
 			recoveredKey := utils.XorBytes(msg[:crypto.AESBlockSize], msg[2*crypto.AESBlockSize:3*crypto.AESBlockSize])
-			if !bytes.Equal(recoveredKey, key) {
-				panic("failure")
-			} else {
-				fmt.Println("Key: ", recoveredKey)
-				break
-			}
+			res := check(utils.ToHexString(recoveredKey), utils.ToHexString(key))
+			res.Queries = i + 1
+			return res
 		}
 	}
+	return failed(crypto.ErrAttackFailed)
 }
 
-func Solve4_28() {
+func Solve4_28() Result {
 	secret := utils.RandBytes(crypto.AESBlockSize + 12)
 	macFunc := crypto.SHA1MacF(secret)
 	msg := []byte("Some very serious msg")
 	mac1 := macFunc(msg)
 	msg[5] = 0
 	mac2 := macFunc(msg)
-	if bytes.Equal(mac1, mac2) {
-		fmt.Println("failed")
-	} else {
-		fmt.Println("pass")
-	}
+	return Result{Pass: !bytes.Equal(mac1, mac2)}
 }
 
-func Solve4_29() {
+func Solve4_29() Result {
 	secret := []byte("Submarine world is here forever")
 	macF := crypto.SHA1MacF(secret)
 	msg := utils.GenerateUserCookie("hello world")
@@ -148,11 +176,14 @@ func Solve4_29() {
 		return strings.Contains(string(b), ";admin=true")
 	}
 
-	crypto.BreakPrefixSHA1([]byte(msg), originalCheckSum, validatorF)
-
+	forged, mac, err := crypto.BreakPrefixSHA1([]byte(msg), originalCheckSum, validatorF)
+	if err != nil {
+		return failed(err)
+	}
+	return Result{Recovered: utils.ToHexString(mac), Pass: validatorF(forged, mac)}
 }
 
-func Solve4_30() {
+func Solve4_30() Result {
 	secret := []byte("Submarine world is here")
 	macF := crypto.MD4MacF(secret)
 	msg := utils.GenerateUserCookie("hello world for this msg")
@@ -164,11 +195,14 @@ func Solve4_30() {
 		return strings.Contains(string(b), ";admin=true")
 	}
 
-	crypto.BreakPrefixMD4([]byte(msg), originalCheckSum, validatorF)
-
+	forged, mac, err := crypto.BreakPrefixMD4([]byte(msg), originalCheckSum, validatorF)
+	if err != nil {
+		return failed(err)
+	}
+	return Result{Recovered: utils.ToHexString(mac), Pass: validatorF(forged, mac)}
 }
 
-func Solve4_31() {
+func Solve4_31() Result {
 	hashValue := "2daa7426c49ce43323b4009a70294420847ed0f8e1dc2e20104588c7f248e9b7"
 	checkSum := utils.FromHexString(hashValue)
 	httpHandler := func(w http.ResponseWriter, r *http.Request) {
@@ -194,11 +228,14 @@ func Solve4_31() {
 		w.WriteHeader(http.StatusOK)
 	}
 	server := utils.GetLocalHTTPServer(10001, httpHandler, "checkfile")
-	go func() {
-		server.ListenAndServe()
-		fmt.Println("server started")
-	}()
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return failed(err)
+	}
+	go server.Serve(ln)
+	defer server.Close()
 	attackCheckSum := make([]byte, 32)
+	queries := 0
 	for bytePos := 0; bytePos < 32; bytePos++ {
 		m := make(map[byte]int)
 		type Pair struct {
@@ -207,7 +244,6 @@ func Solve4_31() {
 		}
 		var byteDelays []Pair
 		epsilon := 0.2 // Error margin from expected difference
-		st := time.Now()
 		tries := 0
 		invalidBytes := make(map[byte]struct{})
 		for {
@@ -221,10 +257,12 @@ func Solve4_31() {
 					attackCheckSum[bytePos] = bb
 					msg := hex.EncodeToString(attackCheckSum)
 					t := time.Now()
-					_, err := http.Get("http://localhost:10001/checkfile?signature=" + msg)
+					resp, err := http.Get("http://localhost:10001/checkfile?signature=" + msg)
 					if err != nil {
-						panic(err)
+						return failed(err)
 					}
+					resp.Body.Close()
+					queries++
 					d := time.Since(t).Microseconds()
 					m[bb] += int(d)
 				}
@@ -254,10 +292,8 @@ func Solve4_31() {
 				}
 			}
 		}
-		d := time.Since(st)
-		fmt.Println("Took ", d.Milliseconds(), "For ", bytePos)
 	}
-	fmt.Println(hex.EncodeToString(checkSum))
-	fmt.Println(hex.EncodeToString(attackCheckSum))
-	server.Close()
+	res := check(hex.EncodeToString(attackCheckSum), hex.EncodeToString(checkSum))
+	res.Queries = queries
+	return res
 }
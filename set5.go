@@ -1,46 +1,64 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"crypto/hmac"
 	"crypto/sha256"
-	"fmt"
 	"math/big"
-	"os"
 
 	bi "github.com/sukunrt/bigint"
 	"github.com/sukunrt/cryptopals/crypto"
 	"github.com/sukunrt/cryptopals/utils"
 )
 
-func Solve5_34() {
+func init() {
+	register(
+		Challenge{Set: 5, Num: 34, Title: "Implement a MITM key-fixing attack on Diffie-Hellman with parameter injection", Solve: Solve5_34},
+		Challenge{Set: 5, Num: 36, Title: "Implement Secure Remote Password (SRP)", Solve: Solve5_36},
+		Challenge{Set: 5, Num: 37, Title: "Break SRP with a zero key", Solve: Solve5_37},
+		Challenge{Set: 5, Num: 38, Title: "Offline dictionary attack on simplified SRP", Inputs: []string{"passwords.txt"}, Slow: true, Solve: Solve5_38},
+		Challenge{Set: 5, Num: 40, Title: "Implement an E=3 RSA Broadcast attack", Solve: func() Result {
+			return check(Solve5_40("hello world"), "hello world")
+		}},
+	)
+}
+
+func Solve5_34() Result {
 	asch := make(chan bi.Int, 10)
 	arch := make(chan bi.Int, 10)
 	bsch := make(chan bi.Int, 10)
 	brch := make(chan bi.Int, 10)
 	donech := make(chan string)
+	middlech := make(chan string, 1)
 	mach := make(chan []byte, 1)
 	mbch := make(chan []byte, 1)
+	// the session key is derived from the shared secret, which is never a
+	// valid AES key by itself. A 16 byte key and a full iv can't fail
+	sessionCipher := func(secret []byte) crypto.AESInCBCCipher {
+		key, _ := crypto.AESKeyFromSecret(secret, crypto.AESBlockSize)
+		c, _ := crypto.NewAESInCBCCipher(key)
+		return c
+	}
 	middle := func() {
 		p := <-arch
 		g := <-arch
 		<-arch
 		// the private key will now be simply 0
 		asch <- p
-		aesCipherA := crypto.NewAESInCBCCipher(make([]byte, 0))
+		aesCipherA := sessionCipher(nil)
 		bsch <- p
 		bsch <- g
 		bsch <- p
 
 		<-brch
 		// The private key is again 0
-		aesCipherB := crypto.NewAESInCBCCipher(make([]byte, 0))
+		aesCipherB := sessionCipher(nil)
 		msg := <-mach
-		decmsg := aesCipherA.Decrypt(msg[crypto.AESBlockSize:], msg[:crypto.AESBlockSize])
-		fmt.Println("middle:", string(decmsg))
+		decmsg, _ := aesCipherA.Decrypt(msg[crypto.AESBlockSize:], msg[:crypto.AESBlockSize])
+		middlech <- string(decmsg)
 		iv := utils.RandBytes(crypto.AESBlockSize)
-		mbch <- append(iv, aesCipherB.Encrypt(decmsg, iv)...)
+		ct, _ := aesCipherB.Encrypt(decmsg, iv)
+		mbch <- append(iv, ct...)
 	}
 
 	B := func() {
@@ -50,12 +68,11 @@ func Solve5_34() {
 
 		dh := crypto.NewDHFromPAndG(p, g)
 		brch <- dh.A
-		key := dh.MakeSessionKey(A).Bytes()
-		cipher := crypto.NewAESInCBCCipher(key)
+		cipher := sessionCipher(dh.MakeSessionKey(A).Bytes())
 
 		msg := <-mbch
-		fmt.Println("end:", string(cipher.Decrypt(msg[crypto.AESBlockSize:], msg[:crypto.AESBlockSize])))
-		donech <- "done"
+		pt, _ := cipher.Decrypt(msg[crypto.AESBlockSize:], msg[:crypto.AESBlockSize])
+		donech <- string(pt)
 	}
 
 	A := func() {
@@ -64,20 +81,22 @@ func Solve5_34() {
 		arch <- dh.G
 		arch <- dh.A
 		B := <-asch
-		key := dh.MakeSessionKey(B).Bytes()
-		cipher := crypto.NewAESInCBCCipher(key)
+		cipher := sessionCipher(dh.MakeSessionKey(B).Bytes())
 		msg := []byte("hello world")
 		iv := utils.RandBytes(crypto.AESBlockSize)
-		mach <- append(iv, cipher.Encrypt(msg, iv)...)
+		ct, _ := cipher.Encrypt(msg, iv)
+		mach <- append(iv, ct...)
 	}
 	go B()
 	go A()
 	go middle()
-	<-donech
-	fmt.Println("done")
+	received := <-donech
+	res := check(<-middlech, "hello world")
+	res.Pass = res.Pass && received == res.Expected
+	return res
 }
 
-func Solve5_36() {
+func Solve5_36() Result {
 	password := []byte("hello world")
 	p, g, k, serverFunc := crypto.SRPServer(password)
 	inputCh := make(chan []byte, 10)
@@ -103,13 +122,12 @@ func Solve5_36() {
 	shaHF.Reset()
 	shaHF.Write(kk.Bytes())
 	key := shaHF.Sum(nil)
-	fmt.Println("key in client", key)
 	outputCh <- key
 	msg := <-inputCh
-	fmt.Println(string(msg))
+	return check(string(msg), "true")
 }
 
-func Solve5_37() {
+func Solve5_37() Result {
 	password := []byte("hello world")
 	p, _, _, serverFunc := crypto.SRPServer(password)
 	inputCh := make(chan []byte, 10)
@@ -122,25 +140,22 @@ func Solve5_37() {
 	shaHF := sha256.New()
 	shaHF.Write(kk.Bytes())
 	key := shaHF.Sum(nil)
-	fmt.Println("key in client", key)
 	outputCh <- key
 	msg := <-inputCh
-	fmt.Println(string(msg))
+	return check(string(msg), "true")
 }
 
-func Solve5_38() {
+func Solve5_38() Result {
 	dh := crypto.NewDH()
 	n := dh.P
 	g := bi.FromInt(2)
-	done := make(chan struct{})
+	done := make(chan string, 2)
+	crackedCh := make(chan string, 1)
 	server := func(readCh, writeCh chan []byte) {
 		password := "helloworld"
 		salt := "salty"
 		sha := sha256.New()
-		_, err := sha.Write([]byte(salt + password))
-		if err != nil {
-			panic(err)
-		}
+		sha.Write([]byte(salt + password))
 		x := sha.Sum(nil)
 		xi := bi.FromBytes(x)
 		v := bi.Exp(g, xi, n)
@@ -196,20 +211,20 @@ func Solve5_38() {
 		writeCh <- v
 
 		worked := <-readCh
-		fmt.Println(string(worked))
-		done <- struct{}{}
+		done <- string(worked)
 	}
 	c1, c2 := make(chan []byte), make(chan []byte)
 	go server(c1, c2)
 	go client(c2, c1, "helloworld")
-	<-done
+	if res := check(<-done, "true"); !res.Pass {
+		return res
+	}
 
 	mitm := func(readCh, writeCh chan []byte) {
 		salt := "salty"
 		sha := sha256.New()
 		shaDict := make(map[string]string)
-		f, _ := os.Open("passwords.txt")
-		scanner := bufio.NewScanner(f)
+		scanner := utils.GetFileScanner("passwords.txt")
 		for scanner.Scan() {
 			pwd := []byte(scanner.Text())
 			sha.Reset()
@@ -228,6 +243,7 @@ func Solve5_38() {
 		writeCh <- u
 		got := <-readCh
 		writeCh <- []byte("true")
+		cracked := ""
 		for s, pwd := range shaDict {
 			xi := bi.FromBytes([]byte(s))
 			v := bi.Exp(g, xi, n)
@@ -239,17 +255,17 @@ func Solve5_38() {
 			hm := hmac.New(sha256.New, k)
 			hm.Write([]byte(salt))
 			if bytes.Equal(got, hm.Sum(nil)) {
-				fmt.Println(pwd)
+				cracked = pwd
 				break
 			}
 		}
-		done <- struct{}{}
+		crackedCh <- cracked
 	}
 	go mitm(c1, c2)
 	go client(c2, c1, "avatar")
 
 	<-done
-	<-done
+	return check(<-crackedCh, "avatar")
 }
 
 func Solve5_40(s string) string {
@@ -2,51 +2,74 @@ package main
 
 import (
 	"bytes"
+	stdcrypto "crypto"
 	"crypto/sha1"
-	"crypto/sha256"
 	"fmt"
-	"strings"
+	"os"
 
 	bi "github.com/sukunrt/bigint"
 	"github.com/sukunrt/cryptopals/crypto"
 	"github.com/sukunrt/cryptopals/utils"
 )
 
-func Solve6_41() {
+func init() {
+	register(
+		Challenge{Set: 6, Num: 41, Title: "Implement unpadded message recovery oracle", Solve: Solve6_41},
+		Challenge{Set: 6, Num: 42, Title: "Bleichenbacher's e=3 RSA Attack", Solve: func() Result {
+			return Solve6_42("hi mom")
+		}},
+		Challenge{Set: 6, Num: 43, Title: "DSA key recovery from nonce", Inputs: []string{"inputs/6-43.pem"}, Solve: Solve6_43},
+		Challenge{Set: 6, Num: 44, Title: "DSA nonce recovery from repeated nonce", Inputs: []string{"inputs/6-44.txt", "inputs/6-44.pem"}, Solve: Solve6_44},
+		Challenge{Set: 6, Num: 45, Title: "DSA parameter tampering", Solve: Solve6_45},
+		Challenge{Set: 6, Num: 46, Title: "RSA parity oracle", Solve: Solve6_46},
+		Challenge{Set: 6, Num: 48, Title: "Bleichenbacher's PKCS 1.5 Padding Oracle (Complete Case)", Solve: func() Result {
+			return Solve6_48("kick it, CC")
+		}},
+	)
+}
+
+func Solve6_41() Result {
 	msgs := []string{"hello", "world", "smallstrings", "with spaces", "long string also"}
+	var res Result
 	for _, m := range msgs {
-		out := crypto.UnPaddedRSAOracle(m)
-		if out != m {
-			fmt.Println(m, "FAILED")
-			return
+		res = check(crypto.UnPaddedRSAOracle(m), m)
+		if !res.Pass {
+			break
 		}
 	}
-	fmt.Println("SUCCESS")
+	return res
 }
 
-func Solve6_42(m string) bool {
-	r := crypto.NewRSAN(64)
-	msg := "hello world"
-	signature := r.Sign([]byte(msg))
-	if !crypto.VerifyRSASignatureCorrect([]byte(msg), signature, r) {
-		fmt.Println("failed", msg)
-		return false
+func Solve6_42(m string) Result {
+	// the forged prefix has to fit in the top third of the block, with SHA-256
+	// and a full 8 bytes of padding that takes 2048 bits
+	r, err := crypto.NewRSA(crypto.RSAOptions{Bits: 2048, E: 3})
+	if err != nil {
+		return failed(err)
 	}
-	if !crypto.VerifyRSASignatureInCorrect([]byte(msg), signature, r) {
-		fmt.Println("failed", msg)
-		return false
+	pub := r.PubKey()
+	sloppy := crypto.SloppyPKCS1v15Verifier{IgnoreTrailing: true}
+	msg := []byte("hello world")
+	signature := r.Sign(msg)
+	if err := pub.VerifyPKCS1v15(stdcrypto.SHA256, msg, signature); err != nil {
+		return failed(fmt.Errorf("valid signature rejected by the strict verifier: %w", err))
+	}
+	if err := sloppy.Verify(pub, stdcrypto.SHA256, msg, signature); err != nil {
+		return failed(fmt.Errorf("valid signature rejected by the sloppy verifier: %w", err))
 	}
 
+	// 00 01 ff*8 00 DigestInfo followed by zeros. The cube root of that rounded
+	// up only changes the bytes after the digest
+	di, err := crypto.DigestInfo(stdcrypto.SHA256, []byte(m))
+	if err != nil {
+		return failed(err)
+	}
 	block := make([]byte, r.Sz)
-	block[0] = 0
 	block[1] = 1
-	block[2] = 0xFF
-	block[3] = 0
-	block[4] = 0xA
-	block[5] = 0xB
-	sha := sha256.New()
-	sha.Write([]byte(m))
-	copy(block[6:], sha.Sum(nil))
+	for i := 2; i < 10; i++ {
+		block[i] = 0xFF
+	}
+	copy(block[11:], di)
 
 	target := bi.FromBytes(block)
 	st, ed := bi.Zero, r.N
@@ -63,45 +86,55 @@ func Solve6_42(m string) bool {
 		}
 	}
 	cb := make([]byte, 1)
-	cb = append(cb, ed.Mul(ed).Mul(ed).Bytes()...) //
-
-	if !bytes.Equal(cb[:6+32], block[:6+32]) {
-		fmt.Println(cb[:40], block[:40])
-		panic("cbrt failed")
+	cb = append(cb, ed.Mul(ed).Mul(ed).Bytes()...)
+	if !bytes.Equal(cb[:11+len(di)], block[:11+len(di)]) {
+		return failed(fmt.Errorf("cube root doesn't have the forged prefix"))
 	}
 
-	if !crypto.VerifyRSASignatureInCorrect([]byte(m), ed.Bytes(), r) {
-		fmt.Println("forgery failed")
-		return false
+	forged := append(utils.RepBytes(0, r.Sz-len(ed.Bytes())), ed.Bytes()...)
+	if pub.VerifyPKCS1v15(stdcrypto.SHA256, []byte(m), forged) == nil {
+		return failed(fmt.Errorf("forged signature accepted by the strict verifier"))
+	}
+	// nothing in a PSS encoding is left for a cube root to fill with garbage
+	pss, err := r.SignPSS(msg, crypto.PSSOptions{})
+	if err != nil {
+		return failed(err)
+	}
+	if err := pub.VerifyPSS(msg, pss, crypto.PSSOptions{}); err != nil {
+		return failed(fmt.Errorf("valid PSS signature rejected: %w", err))
 	}
-	if crypto.VerifyRSASignatureCorrect([]byte(m), ed.Bytes(), r) {
-		fmt.Println("correct algor is incorrect")
-		return false
+	if pub.VerifyPSS([]byte(m), forged, crypto.PSSOptions{}) == nil {
+		return failed(fmt.Errorf("forged signature accepted by the PSS verifier"))
+	}
+	return Result{
+		Recovered: utils.ToHexString(forged),
+		Pass:      sloppy.Verify(pub, stdcrypto.SHA256, []byte(m), forged) == nil,
 	}
-	return true
 }
 
-func readHex(s string) bi.Int {
-	for _, c := range []string{"\n", "\t", " "} {
-		s = strings.ReplaceAll(s, c, "")
+// readDSAPublicKey reads the DSA public key in the PEM file f
+func readDSAPublicKey(f string) (crypto.DSAPerUserParams, error) {
+	data, err := os.ReadFile(f)
+	if err != nil {
+		return crypto.DSAPerUserParams{}, err
+	}
+	k, err := crypto.ParsePEM(data)
+	if err != nil {
+		return crypto.DSAPerUserParams{}, err
+	}
+	pub, ok := k.(crypto.DSAPerUserParams)
+	if !ok {
+		return crypto.DSAPerUserParams{}, fmt.Errorf("%s holds a %T, not a DSA key", f, k)
 	}
-	return bi.FromBytes(utils.FromHexString(s))
+	return pub, nil
 }
 
-func Solve6_43() {
-	p := readHex(`800000000000000089e1855218a0e7dac38136ffafa72eda7
-	859f2171e25e65eac698c1702578b07dc2a1076da241c76c6
-	2d374d8389ea5aeffd3226a0530cc565f3bf6b50929139ebe
-	ac04f48c3c84afb796d61e5a4f9a8fda812ab59494232c7d2
-	b4deb50aa18ee9e132bfa85ac4374d7f9091abc3d015efc87
-	1a584471bb1`)
-	q := readHex(`f4f47f05794b256174bba6e9b396a7707e563c5b`)
-	g := readHex(`5958c9d3898b224b12672c0b98e06c60df923cb8bc999d119
-	458fef538b8fa4046c8db53039db620c094c9fa077ef389b5
-	322a559946a71903f990f1f7e0e025e2d7f7cf494aff1a047
-	0f5b64c36b625a097f1651fe775323556fe00b3608c887892
-	878480e99041be601a62166ca6894bdd41a7054ec89f756ba
-	9fc95302291`)
+func Solve6_43() Result {
+	pub, err := readDSAPublicKey("inputs/6-43.pem")
+	if err != nil {
+		return failed(err)
+	}
+	q := pub.Q
 	msg := `For those that envy a MC it can be hazardous to your health
 So be friendly, a matter of life and death, just like a etch-a-sketch
 `
@@ -109,7 +142,7 @@ So be friendly, a matter of life and death, just like a etch-a-sketch
 	sh.Write([]byte(msg))
 	shVal := sh.Sum(nil)
 	if utils.ToHexString(shVal) != "d2d0714f014a9784047eaeccf956520045c45265" {
-		panic("hashing failed")
+		return failed(fmt.Errorf("hashing failed"))
 	}
 
 	sh.Reset()
@@ -117,73 +150,33 @@ So be friendly, a matter of life and death, just like a etch-a-sketch
 	h := sh.Sum(nil)
 	hi := bi.FromBytes(h)
 
-	y := readHex(`084ad4719d044495496a3201c8ff484feb45b962e7302e56a392aee4
-	abab3e4bdebf2955b4736012f21a08084056b19bcd7fee56048e004
-	e44984e2f411788efdc837a0d2e5abb7b555039fd243ac01f0fb2ed
-	1dec568280ce678e931868d23eb095fde9d3779191b8c0299d6e07b
-	bb283e6633451e535c45513b2d33c99ea17`)
-	var dsa crypto.DSAPerUserParams
-	var kk bi.Int
-	found := false
+	want := "0954edd5e0afe5542a4adf012611a91912a3ec16"
 	r, _ := bi.FromString("548099063082341131477253921760299949438196259240", 10)
 	s, _ := bi.FromString("857042759984254168557880549501802188789837994940", 10)
 	for k := 1; k < 1<<16; k++ {
 		ki := bi.FromInt(k)
 		x := ki.Mul(s).Sub(hi).Mul(crypto.ModInv(r, q)).Mod(q)
-		d := crypto.DSAPerUserParams{
-			DSAParams: crypto.DSAParams{P: p, Q: q, G: g},
-			X:         x,
-			Y:         y,
-			H:         sh,
-		}
+		d := pub
+		d.X, d.H = x, sh
 		ri, si := d.SignWithK([]byte(msg), ki)
 		if ri.Equal(r) && si.Equal(s) {
 			sh.Reset()
 			sh.Write([]byte(x.Text(16)))
-			want := "0954edd5e0afe5542a4adf012611a91912a3ec16"
 			got := utils.ToHexString(sh.Sum(nil))
-			if got != want {
-				continue
-			} else {
-				fmt.Printf("Private Key: 0x%s\n", x)
-				found = true
-				dsa = d
-				kk = ki
-				break
+			if got == want {
+				return check(got, want)
 			}
 		}
 	}
-	if !found {
-		fmt.Println("failed")
-	}
-	r, s = dsa.SignWithK([]byte(msg), kk)
-	fmt.Println(r)
-	fmt.Println(s)
+	return Result{Expected: want}
 }
 
-func Solve6_44() {
-	p := readHex(`800000000000000089e1855218a0e7dac38136ffafa72eda7
-	859f2171e25e65eac698c1702578b07dc2a1076da241c76c6
-	2d374d8389ea5aeffd3226a0530cc565f3bf6b50929139ebe
-	ac04f48c3c84afb796d61e5a4f9a8fda812ab59494232c7d2
-	b4deb50aa18ee9e132bfa85ac4374d7f9091abc3d015efc87
-	1a584471bb1`)
-
-	q := readHex(`f4f47f05794b256174bba6e9b396a7707e563c5b`)
-
-	g := readHex(`5958c9d3898b224b12672c0b98e06c60df923cb8bc999d119
-	458fef538b8fa4046c8db53039db620c094c9fa077ef389b5
-	322a559946a71903f990f1f7e0e025e2d7f7cf494aff1a047
-	0f5b64c36b625a097f1651fe775323556fe00b3608c887892
-	878480e99041be601a62166ca6894bdd41a7054ec89f756ba
-	9fc95302291`)
-
-	y := readHex(`2d026f4bf30195ede3a088da85e398ef869611d0f68f07
-	13d51c9c1a3a26c95105d915e2d8cdf26d056b86b8a7b8
-	5519b1c23cc3ecdc6062650462e3063bd179c2a6581519
-	f674a61f1d89a1fff27171ebc1b93d4dc57bceb7ae2430
-	f98a6a4d83d8279ee65d71c1203d2c96d65ebbf7cce9d3
-	2971c3de5084cce04a2e147821`)
+func Solve6_44() Result {
+	pub, err := readDSAPublicKey("inputs/6-44.pem")
+	if err != nil {
+		return failed(err)
+	}
+	q := pub.Q
 
 	type signMsg struct {
 		msg string
@@ -209,9 +202,7 @@ func Solve6_44() {
 		sh.Reset()
 		sh.Write([]byte(msg))
 		if !bytes.Equal(sh.Sum(nil), sm.m) {
-			fmt.Println(msg, utils.ToHexString(m))
-			fmt.Println("failed to parse input")
-			return
+			return failed(fmt.Errorf("failed to parse input: hash mismatch for %q", msg))
 		}
 		msgs = append(msgs, sm)
 	}
@@ -227,33 +218,22 @@ func Solve6_44() {
 			mdiff := msgs[i].hi.Sub(msgs[j].hi).Mod(q)
 			k := mdiff.Mul(crypto.ModInv(sdiff, q)).Mod(q)
 			x := msgs[i].s.Mul(k).Sub(msgs[i].hi).Mul(crypto.ModInv(msgs[i].r, q)).Mod(q)
-			dsa := crypto.DSAPerUserParams{
-				DSAParams: crypto.DSAParams{
-					P: p,
-					Q: q,
-					G: g,
-				},
-				Y: y,
-				X: x,
-				H: sh,
-			}
+			dsa := pub
+			dsa.X, dsa.H = x, sh
 			r1, s1 := dsa.SignWithK([]byte(msgs[i].msg), k)
 			r2, s2 := dsa.SignWithK([]byte(msgs[j].msg), k)
 			if r1.Equal(msgs[i].r) && s1.Equal(msgs[i].s) && r2.Equal(msgs[j].r) && s2.Equal(msgs[j].s) {
 				sh.Reset()
 				sh.Write([]byte(x.Text(16)))
 				h := utils.ToHexString(sh.Sum(nil))
-				fmt.Println(h)
-				fmt.Println("ca8f6f7c66fa362d40760d135b763eb8527d3d52")
-				fmt.Println(x)
-				return
+				return check(h, "ca8f6f7c66fa362d40760d135b763eb8527d3d52")
 			}
 		}
 	}
-	fmt.Println("FAILED")
+	return failed(crypto.ErrAttackFailed)
 }
 
-func Solve6_45() {
+func Solve6_45() Result {
 	dsa, _ := crypto.GenerateDSAParams(1024, 160)
 	dsa.G = bi.Zero
 
@@ -272,19 +252,22 @@ func Solve6_45() {
 	// here g = 1 => y = 1 & r = 1
 	// any exponent of g and y will always be 1
 	r := bi.One
-	z := bi.RandInt(dsaU.Q)
+	z := crypto.RandInt(dsaU.Q)
 	s := crypto.ModInv(z, dsaU.Q)
 
-	fmt.Println(dsaU.Verify([]byte(msg1), r, s))
-	fmt.Println(dsaU.Verify([]byte(msg2), r, s))
-
+	return Result{Pass: dsaU.Verify([]byte(msg1), r, s) && dsaU.Verify([]byte(msg2), r, s)}
 }
 
-func Solve6_46() {
+func Solve6_46() Result {
 	msg := "VGhhdCdzIHdoeSBJIGZvdW5kIHlvdSBkb24ndCBwbGF5IGFyb3VuZCB3aXRoIHRoZSBGdW5reSBDb2xkIE1lZGluYQ=="
 	msgB := utils.FromBase64String(msg)
-	rsa := crypto.NewRSAN((2048) / 16)
+	rsa, err := crypto.NewRSA(crypto.RSAOptions{Bits: 2048})
+	if err != nil {
+		return failed(err)
+	}
+	queries := 0
 	oddOracle := func(b []byte) bool {
+		queries++
 		pt := rsa.Decrypt(b)
 		return bi.FromBytes(pt).Mod(bi.Two).Equal(bi.One)
 	}
@@ -314,16 +297,28 @@ func Solve6_46() {
 			lo = mid.Add(bi.One)
 		}
 	}
-	fmt.Println(string(hi.Bytes()))
-	fmt.Println(string(msgB))
+	res := check(string(hi.Bytes()), string(msgB))
+	res.Queries = queries
+	return res
 }
 
-func Solve6_48(msg string) string {
-	rsa := crypto.NewRSAN(768 / (16))
+func Solve6_48(msg string) Result {
+	rsa, err := crypto.NewRSA(crypto.RSAOptions{Bits: 768})
+	if err != nil {
+		return failed(err)
+	}
+	queries := 0
 	oracle := func(b []byte) bool {
+		queries++
 		return crypto.ValidPadding(b, rsa)
 	}
 	c := crypto.EncryptRSAWithPadding([]byte(msg), rsa)
 	m := crypto.BreakRSAWithPaddingOracle(c, oracle, rsa)
-	return string(crypto.RemovePadding(m))
+	pt, err := crypto.RemovePadding(m)
+	if err != nil {
+		return failed(err)
+	}
+	res := check(string(pt), msg)
+	res.Queries = queries
+	return res
 }
@@ -3,8 +3,8 @@ package main
 import (
 	"bytes"
 	"compress/zlib"
+	"errors"
 	"fmt"
-	"math/rand"
 	"os"
 	"strconv"
 	"strings"
@@ -13,26 +13,38 @@ import (
 	"github.com/sukunrt/cryptopals/utils"
 )
 
+func init() {
+	register(
+		Challenge{Set: 7, Num: 49, Title: "CBC-MAC Message Forgery", Solve: Solve7_49},
+		Challenge{Set: 7, Num: 50, Title: "Hashing with CBC-MAC", Solve: Solve7_50},
+		Challenge{Set: 7, Num: 51, Title: "Compression Ratio Side-Channel Attacks", Solve: Solve7_51},
+		Challenge{Set: 7, Num: 52, Title: "Iterated Hash Function Multicollisions", Solve: Solve7_52},
+	)
+}
+
 const AESBlkSz = crypto.AESBlockSize
 
+// makeCBCMac returns the last ciphertext block, every iv here is a full block
 func makeCBCMac(cipher crypto.AESInCBCCipher, iv []byte, msg []byte) []byte {
-	c := cipher.Encrypt(msg, iv)
+	c, _ := cipher.Encrypt(msg, iv)
 	return c[len(c)-AESBlkSz:]
 }
 
-func Solve7_49() {
-	fmt.Println("partA:")
-	partA_49()
-	fmt.Println("partB:")
-	partB_49()
+func Solve7_49() Result {
+	if !partA_49() {
+		return failed(fmt.Errorf("part A: forged message has a different mac"))
+	}
+	if err := partB_49(); err != nil {
+		return failed(fmt.Errorf("part B: %w", err))
+	}
+	return Result{Pass: true}
 }
 
-func partA_49() {
+func partA_49() bool {
 	// This attack is not very sophisticated.
 	// We can only change the first block which is not too long. But in this case we'll assume account
 	// numbers are 2 digits. Also we cannot change the digits much otherwise the msg will get scrambled
-	key := crypto.RandAESKey()
-	cipher := crypto.NewAESInCBCCipher(key)
+	cipher, _ := crypto.NewAESInCBCCipher(crypto.RandAESKey())
 	ogIv := utils.RandBytes(AESBlkSz)
 	pt := "from=23&to=45&amount=1000000"
 	mac := makeCBCMac(cipher, ogIv, []byte(pt))
@@ -42,21 +54,20 @@ func partA_49() {
 	originalIV := msg[len(msg)-2*AESBlkSz : len(msg)-1*AESBlkSz]
 	targetIv := utils.XorBytes(utils.XorBytes([]byte(targetMsg[:AESBlkSz]), originalIV), []byte(pt[:AESBlkSz]))
 	mac2 := makeCBCMac(cipher, targetIv, []byte(targetMsg))
-	if bytes.Equal(mac, mac2) {
-		fmt.Println("success")
-	} else {
-		fmt.Println("failure")
-	}
+	return bytes.Equal(mac, mac2)
 }
 
-func partB_49() {
+func partB_49() error {
 	// This is again not a very sophisticated attack. Requires the server to use the same key for every
 	// client
 	ourAccount := 20
 	theirAccount := 30
 	thirdAccount := 40
 	fourthAccount := 50
-	cipher := crypto.NewAESInCBCCipher(crypto.RandAESKey())
+	cipher, err := crypto.NewAESInCBCCipher(crypto.RandAESKey())
+	if err != nil {
+		return err
+	}
 	m1 := string(utils.PadBytes([]byte(fmt.Sprintf("from=%d&tx_list=%d:100", theirAccount, thirdAccount)), AESBlkSz))
 	m2 := fmt.Sprintf("from=%d&tx_list=%d:0000000000001;%d:1000000", ourAccount, fourthAccount, ourAccount)
 	mac1 := makeCBCMac(cipher, make([]byte, AESBlkSz), []byte(m1))
@@ -67,8 +78,7 @@ func partB_49() {
 	newMsg := m1 + string(utils.RepBytes(16, 16)) + string(newB1) + m2[AESBlkSz:]
 	mac3 := makeCBCMac(cipher, make([]byte, AESBlkSz), []byte(newMsg))
 	if !bytes.Equal(mac3, mac2) {
-		fmt.Println("FAILED mac should be same")
-		return
+		return errors.New("mac should be same")
 	}
 
 	// Parse transactions and see if we are getting any money:
@@ -76,47 +86,55 @@ func partB_49() {
 	parts := strings.Split(newMsg, "&")
 	from, _ = strconv.Atoi(parts[0][strings.Index(parts[0], "from=")+len("from="):])
 	if from != theirAccount {
-		fmt.Println("FAILED money should be from their account")
-		return
+		return errors.New("money should be from their account")
 	}
 	txns := strings.Split(parts[1][len("tx_list="):], ";")
 	for _, tx := range txns {
+		// the garbled glue block can split off junk transactions, skip them
 		parts := strings.Split(tx, ":")
+		if len(parts) != 2 {
+			continue
+		}
 		to, _ := strconv.Atoi(parts[0])
 		amt, _ := strconv.Atoi(parts[1])
 		if to == ourAccount && amt > 100000 {
-			fmt.Println("success")
-			return
+			return nil
 		}
 	}
-	fmt.Println("failed: received no money")
+	return errors.New("received no money")
 }
 
-func Solve7_50() {
+func Solve7_50() Result {
 	b := []byte("alert('MZA who was that?');\n")
-	cipher := crypto.NewAESInCBCCipher([]byte("YELLOW SUBMARINE"))
+	cipher, err := crypto.NewAESInCBCCipher([]byte("YELLOW SUBMARINE"))
+	if err != nil {
+		return failed(err)
+	}
 	mac := makeCBCMac(cipher, make([]byte, AESBlkSz), b)
-	fmt.Println(utils.ToHexString(mac))
 
 	//         |              |               | ;
 	attack := "alert('Ayo, the Wu is back!');//"
-	enc := cipher.Encrypt([]byte(attack), make([]byte, AESBlkSz))
+	enc, err := cipher.Encrypt([]byte(attack), make([]byte, AESBlkSz))
+	if err != nil {
+		return failed(err)
+	}
 	cx := enc[len(enc)-2*AESBlkSz : len(enc)-AESBlkSz]
 	cx = append(cx, utils.RepBytes(0, len(b)-len(cx))...)
 	pad := utils.XorBytes(b, cx)
 	attackB := utils.ConcatBytes([]byte(attack), pad)
 	mac2 := makeCBCMac(cipher, make([]byte, AESBlkSz), attackB)
-	if bytes.Equal(mac, mac2) {
-		fmt.Println("SUCCESS")
-		os.WriteFile("t.js", attackB, os.ModePerm)
-		return
+	res := check(utils.ToHexString(mac2), utils.ToHexString(mac))
+	if res.Pass {
+		res.Err = os.WriteFile("t.js", attackB, os.ModePerm)
 	}
-	fmt.Println("FAILED")
+	return res
 }
 
-func Solve7_51() {
-	key := crypto.RandAESKey()
-	cipher := crypto.NewAESInCBCCipher(key)
+func Solve7_51() Result {
+	cipher, err := crypto.NewAESInCBCCipher(crypto.RandAESKey())
+	if err != nil {
+		return failed(err)
+	}
 
 	getInput := func(b []byte) []byte {
 		return []byte(fmt.Sprintf(`POST / HTTP/1.1
@@ -125,43 +143,43 @@ Cookie: sessionid=TmV2ZXIgcmV2ZWFsIHRoZSBXdS1UYW5nIFNlY3JldCE=
 Content-Length: %d
 %s`, len(b), string(b)))
 	}
+	queries := 0
 	oracle := func(b []byte) int {
+		queries++
 		var buf bytes.Buffer
-		w := zlib.NewWriter(&buf)
+		// the default level doesn't look for matches in an input this short
+		w, _ := zlib.NewWriterLevel(&buf, zlib.BestCompression)
 		w.Write(getInput(b))
 		w.Close()
-		e := cipher.Encrypt(buf.Bytes(), crypto.RandAESKey())
+		e, _ := cipher.Encrypt(buf.Bytes(), utils.RandBytes(AESBlkSz))
 		return len(e)
 	}
-	b := []byte("sessionid=")
-	b = compressionAttackDepth(oracle, b)
-	fmt.Println(string(b))
-	fmt.Println(string(utils.FromBase64String(string(b)[len("sessionid="):])))
+	b, err := compressionAttackDepth(oracle, []byte("sessionid="))
+	if err != nil {
+		return failed(err)
+	}
+	sessionID := strings.TrimSpace(string(b)[len("sessionid="):])
+	res := check(sessionID, "TmV2ZXIgcmV2ZWFsIHRoZSBXdS1UYW5nIFNlY3JldCE=")
+	res.Queries = queries
+	return res
 }
 
-func compressionAttackDepth(oracle func([]byte) int, found []byte) []byte {
+// compressionAttackDepth extends found one byte at a time until it reaches the
+// end of the line. The oracle only leaks the length rounded up to the block size,
+// so every guess is scored over a range of random pad lengths which makes the
+// byte saved by a correct guess show up in the total
+func compressionAttackDepth(oracle func([]byte) int, found []byte) ([]byte, error) {
+	pads := make([][]byte, 2*AESBlkSz)
+	for i := range pads {
+		pads[i] = utils.RandBytes(i)
+	}
 	candidates := [][]byte{found}
-	for {
-		rb := make([]byte, 100)
-		for i := 0; i < 100; i++ {
-			rb[i] = byte(rand.Intn(1 << 8))
-		}
-		base := oracle(candidates[0])
-		var pad []byte
-		best := base
-		for i := 0; i < 100; i += 1 {
-			x := utils.ConcatBytes(rb[:i], candidates[0])
-			best = oracle(x)
-			if best > base {
-				pad = rb[:i-1]
-				break
-			}
-		}
-
+	for len(candidates[0]) < len(found)+100 {
 		newCandidates := make([][]byte, 0)
+		best := -1
 		for _, cand := range candidates {
-			extensions, bb := findNext(oracle, pad, cand)
-			if bb < best {
+			extensions, bb := findNext(oracle, pads, cand)
+			if best == -1 || bb < best {
 				newCandidates = make([][]byte, 0)
 				best = bb
 			}
@@ -175,25 +193,29 @@ func compressionAttackDepth(oracle func([]byte) int, found []byte) []byte {
 			}
 		}
 		if len(newCandidates) == 1 && newCandidates[0][len(newCandidates[0])-1] == '\n' {
-			return newCandidates[0]
-		} else if len(newCandidates) == 0 {
-			panic("failed")
+			return newCandidates[0], nil
+		} else if len(newCandidates) == 0 || len(newCandidates) > 64 {
+			return nil, crypto.ErrAttackFailed
 		}
 		candidates = newCandidates
 	}
+	return nil, crypto.ErrAttackFailed
 }
 
-func findNext(oracle func([]byte) int, pad, found []byte) ([]byte, int) {
+func findNext(oracle func([]byte) int, pads [][]byte, found []byte) ([]byte, int) {
 	v := make([]byte, len(found)+1)
 	copy(v, found)
 	n := len(found)
 	candidates := make([]byte, 0)
 	valid := []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789/+=\n")
-	best := 1000000
+	best := -1
 	for i := 0; i < len(valid); i++ {
 		v[n] = valid[i]
-		nl := oracle(utils.ConcatBytes(pad, v))
-		if nl < best {
+		nl := 0
+		for _, pad := range pads {
+			nl += oracle(utils.ConcatBytes(pad, v))
+		}
+		if best == -1 || nl < best {
 			candidates = []byte{valid[i]}
 			best = nl
 		} else if nl == best {
@@ -203,7 +225,7 @@ func findNext(oracle func([]byte) int, pad, found []byte) ([]byte, int) {
 	return candidates, best
 }
 
-func Solve7_52() {
+func Solve7_52() Result {
 	md := crypto.NewMD(16)
 	collisionChain := make([][2][]byte, 0)
 	cmap := make(map[string][]byte)
@@ -225,13 +247,13 @@ func Solve7_52() {
 		var x []byte
 		var y []byte
 		for j := 0; j < 9; j++ {
-			x = append(x, utils.PadBytes(collisionChain[j][rand.Intn(2)], AESBlkSz)...)
-			y = append(y, utils.PadBytes(collisionChain[j][rand.Intn(2)], AESBlkSz)...)
+			x = append(x, utils.PadBytes(collisionChain[j][utils.RandIntn(2)], AESBlkSz)...)
+			y = append(y, utils.PadBytes(collisionChain[j][utils.RandIntn(2)], AESBlkSz)...)
 		}
-		x = append(x, utils.PadBytes(collisionChain[9][rand.Intn(2)], AESBlkSz)...)
-		y = append(y, utils.PadBytes(collisionChain[9][rand.Intn(2)], AESBlkSz)...)
+		x = append(x, utils.PadBytes(collisionChain[9][utils.RandIntn(2)], AESBlkSz)...)
+		y = append(y, utils.PadBytes(collisionChain[9][utils.RandIntn(2)], AESBlkSz)...)
 		if !bytes.Equal(md.Hash(x, h), md.Hash(y, h)) {
-			fmt.Println("Failed")
+			return failed(errors.New("multicollision messages hash differently"))
 		}
 	}
 
@@ -239,9 +261,7 @@ func Solve7_52() {
 	collisionChain = make([][2][]byte, 0)
 	cmap = make(map[string][]byte)
 	h = make([]byte, 2)
-	iter := 0
 	for {
-		iter++
 		x := utils.RandBytes(AESBlkSz)
 		nh := md.Hash(x, h)
 		_, ok := cmap[string(nh)]
@@ -249,46 +269,44 @@ func Solve7_52() {
 			collisionChain = append(collisionChain, [...][]byte{cmap[string(nh)], x})
 			cmap = make(map[string][]byte)
 			h = nh
-			if dfs(collisionChain, 0, md2, nil, make(map[string][]byte), md) {
-				break
+			if m1, m2, ok := dfs(collisionChain, 0, md2, nil, make(map[string][]byte), md); ok {
+				return Result{
+					Recovered: fmt.Sprintf("%x %x", m1, m2),
+					Pass:      !bytes.Equal(m1, m2) && bytes.Equal(md2.Hash(m1, nil), md2.Hash(m2, nil)),
+				}
 			}
 			continue
 		}
 		cmap[string(nh)] = x
-		iter++
 	}
-	fmt.Println(iter)
 }
 
-func dfs(collisionChain [][2][]byte, i int, md *crypto.MD, b []byte, mp map[string][]byte, md1 *crypto.MD) bool {
+// dfs walks through every message of the collision chain looking for a pair that
+// also collides under md. It returns the pair if it finds one
+func dfs(collisionChain [][2][]byte, i int, md *crypto.MD, b []byte, mp map[string][]byte, md1 *crypto.MD) ([]byte, []byte, bool) {
 	var m1, m2 []byte
 	m1 = append(m1, b...)
 	m2 = append(m2, b...)
 	if i < len(collisionChain)-1 {
 		m1 = append(m1, utils.PadBytes(collisionChain[i][0], AESBlkSz)...)
 		m2 = append(m2, utils.PadBytes(collisionChain[i][1], AESBlkSz)...)
-		if !dfs(collisionChain, i+1, md, m1, mp, md1) {
-			return dfs(collisionChain, i+1, md, m2, mp, md1)
+		if x, y, ok := dfs(collisionChain, i+1, md, m1, mp, md1); ok {
+			return x, y, ok
 		}
-		return true
+		return dfs(collisionChain, i+1, md, m2, mp, md1)
 	} else {
 		m1 = append(m1, collisionChain[i][0]...)
 		h1 := md.Hash(m1, make([]byte, (md.Size+7)/8))
-		_, ok := mp[string(h1)]
-		if ok {
-			fmt.Printf("found it\n %x \n %x\n", mp[string(h1)], m1)
-			return true
+		if x, ok := mp[string(h1)]; ok {
+			return x, m1, true
 		}
 		mp[string(h1)] = m1
 		m2 = append(m2, collisionChain[i][1]...)
 		h2 := md.Hash(m2, make([]byte, (md.Size+7)/8))
-		_, ok = mp[string(h2)]
-		if ok {
-			fmt.Printf("found it \n %x \n %x\n", mp[string(h2)], m2)
-			return true
+		if x, ok := mp[string(h2)]; ok {
+			return x, m2, true
 		}
 		mp[string(h2)] = m2
-		return false
-
+		return nil, nil, false
 	}
 }
@@ -1,20 +1,40 @@
 package main
 
 import (
+	"context"
+	stdcrypto "crypto"
+	"crypto/aes"
+	"crypto/sha1"
 	"crypto/sha256"
 	"fmt"
 
 	bi "github.com/sukunrt/bigint"
 	"github.com/sukunrt/cryptopals/crypto"
+	"github.com/sukunrt/cryptopals/crypto/dlog"
+	"github.com/sukunrt/cryptopals/crypto/ec"
+	"github.com/sukunrt/cryptopals/crypto/gf128"
+	"github.com/sukunrt/cryptopals/utils"
 )
 
-func Solve8_57() {
-	success := true
+func init() {
+	register(
+		Challenge{Set: 8, Num: 57, Title: "Diffie-Hellman Revisited: Subgroup-Confined Key-Recovery Attack", Solve: Solve8_57},
+		Challenge{Set: 8, Num: 58, Title: "Pollard's Method for Catching Kangaroos", Slow: true, Solve: Solve8_58},
+		Challenge{Set: 8, Num: 59, Title: "Elliptic Curve Diffie-Hellman and Invalid-Curve Attacks", Solve: Solve8_59},
+		Challenge{Set: 8, Num: 60, Title: "Single-Coordinate Ladders and Insecure Twists", Slow: true, Solve: Solve8_60},
+		Challenge{Set: 8, Num: 61, Title: "Duplicate-Signature Key Selection in ECDSA (and RSA)", Solve: Solve8_61},
+		Challenge{Set: 8, Num: 62, Title: "Key-Recovery Attacks on ECDSA with Biased Nonces", Solve: Solve8_62},
+		Challenge{Set: 8, Num: 63, Title: "Key-Recovery Attacks on GCM with Repeated Nonces", Solve: Solve8_63},
+		Challenge{Set: 8, Num: 64, Title: "Key-Recovery Attacks on GCM with a Truncated MAC", Slow: true, Solve: Solve8_64},
+	)
+}
+
+func Solve8_57() Result {
 	for ii := 0; ii < 10; ii++ {
 		p, _ := bi.FromString("7199773997391911030609999317773941274322764333428698921736339643928346453700085358802973900485592910475480089726140708102474957429903531369589969318716771", 10)
 		g, _ := bi.FromString("4565356397095740655436854503483826832136106141639563487732438195343690437606117828318042418238184896212352329118608100083187535033402010599512641674644143", 10)
 		o, _ := bi.FromString("236234353446506858198510045061214171961", 10)
-		y := bi.RandInt(o).Add(bi.One)
+		y := crypto.RandInt(o).Add(bi.One)
 		hash := sha256.New()
 		handshakeF := func(gx bi.Int) crypto.HandshakeMsg {
 			msg := "crazy flamboyant for the rap enjoyment"
@@ -28,26 +48,35 @@ func Solve8_57() {
 				PK:  K,
 			}
 		}
-		yy, _ := crypto.DHSmallSubgroupAttack(p, g, o, handshakeF)
+		yy, _, err := crypto.DHSmallSubgroupAttack(p, g, o, handshakeF)
+		if err != nil {
+			return failed(fmt.Errorf("round %d: %w", ii, err))
+		}
 		if !y.Equal(yy) {
-			fmt.Println("FAILED", ii, y)
-			success = false
-			break
+			return check(yy.String(), y.String())
 		}
 	}
-	if success {
-		fmt.Println("SUCCESS!")
-	}
+	return Result{Pass: true}
+}
 
+// parallelKangaroo runs dlog.ParallelKangaroo on all cores. It gives up
+// after 16 times the 2*sqrt(b-a) jumps it takes on average, in case the
+// target isn't in [a, b]
+func parallelKangaroo(target, a, b, g, p bi.Int) (bi.Int, error) {
+	budget := b.Sub(a).Sqrt().Mul(bi.FromInt(32))
+	if !budget.IsInt64() {
+		return bi.Zero, fmt.Errorf("interval of %d bits is too wide: %w", b.Sub(a).BitLen(), dlog.ErrNotFound)
+	}
+	opts := dlog.ParallelOptions{MaxSteps: uint64(budget.Int())}
+	return dlog.ParallelKangaroo[bi.Int](context.Background(), dlog.ModP{P: p}, g, target, a, b, opts)
 }
 
-func Solve8_58() {
-	success := true
+func Solve8_58() Result {
 	for ii := 0; ii < 2; ii++ {
 		p, _ := bi.FromString("11470374874925275658116663507232161402086650258453896274534991676898999262641581519101074740642369848233294239851519212341844337347119899874391456329785623", 10)
 		g, _ := bi.FromString("622952335333961296978159266084741085889881358738459939978290179936063635566740258555167783009058567397963466103140082647486611657350811560630587013183357", 10)
 		o, _ := bi.FromString("335062023296420808191071248367701059461", 10)
-		y := bi.RandInt(o).Add(bi.One)
+		y := crypto.RandInt(o).Add(bi.One)
 		hash := sha256.New()
 		handshakeF := func(gx bi.Int) crypto.HandshakeMsg {
 			msg := "crazy flamboyant for the rap enjoyment"
@@ -61,15 +90,220 @@ func Solve8_58() {
 				PK:  bi.Exp(g, y, p),
 			}
 		}
-		yy := crypto.DHSmallSubgroupWithPollardKangarooAttack(p, g, o, handshakeF)
+		yy, err := crypto.DHSmallSubgroupWithKangarooAttack(p, g, o, handshakeF, parallelKangaroo)
+		if err != nil {
+			return failed(fmt.Errorf("round %d: %w", ii, err))
+		}
 		if !y.Equal(yy) {
-			fmt.Println("FAILED", ii, y)
-			success = false
-			break
+			return check(yy.String(), y.String())
+		}
+	}
+	return Result{Pass: true}
+}
+
+func Solve8_59() Result {
+	c := ec.ToyCurve()
+	d, pk := c.GenKey()
+	handshakeF := func(Q ec.Point) ec.HandshakeMsg {
+		msg := "crazy flamboyant for the rap enjoyment"
+		// the victim never checks that Q is on its curve
+		K := c.ScalarMult(Q, d)
+		return ec.HandshakeMsg{
+			Msg: msg,
+			Mac: ec.ECDHMac(K, msg),
+			PK:  pk,
+		}
+	}
+	dd, _, err := ec.InvalidCurveAttack(c, ec.ToyInvalidCurves(), handshakeF)
+	if err != nil {
+		return failed(err)
+	}
+	return check(dd.Mod(c.N).String(), d.String())
+}
+
+func Solve8_60() Result {
+	c := ec.ToyMontgomeryCurve()
+	d := crypto.RandInt(c.N.Sub(bi.One)).Add(bi.One)
+	pk := c.Ladder(c.U, d)
+	handshakeF := func(u bi.Int) crypto.HandshakeMsg {
+		msg := "crazy flamboyant for the rap enjoyment"
+		// the victim never checks that u is on its curve and not the twist
+		K := c.Ladder(u, d)
+		return crypto.HandshakeMsg{
+			Msg: msg,
+			Mac: ec.MontgomeryMac(K, msg),
+			PK:  pk,
+		}
+	}
+	dd, err := ec.MontgomeryTwistWithKangarooAttack(c, handshakeF)
+	if err != nil {
+		return failed(err)
+	}
+	// the public key only pins the private key down up to sign
+	if !dd.Equal(d) {
+		dd = c.N.Sub(dd)
+	}
+	return check(dd.String(), d.String())
+}
+
+func Solve8_61() Result {
+	msg := []byte("crazy flamboyant for the rap enjoyment")
+
+	key := ec.P256().GenECDSAKey(sha256.New())
+	r, s := key.Sign(msg)
+	if !key.Verify(msg, r, s) {
+		return failed(fmt.Errorf("ecdsa: valid signature rejected"))
+	}
+	evil := ec.ECDSAKeySelectionAttack(key, msg, r, s)
+	if evil.Q.Equal(key.Q) || !evil.Verify(msg, r, s) {
+		return failed(fmt.Errorf("ecdsa: signature doesn't verify under a new key"))
+	}
+
+	rsa := crypto.NewRSAN(64)
+	sig := rsa.Sign(msg)
+	evilRSA := crypto.RSAKeySelectionAttack(sig, rsa.PubKey())
+	if evilRSA.N.Equal(rsa.N) || evilRSA.PubKey().VerifyPKCS1v15(stdcrypto.SHA256, msg, sig) != nil {
+		return failed(fmt.Errorf("rsa: signature doesn't verify under a new key"))
+	}
+	return Result{Pass: true}
+}
+
+// biasedSignatures returns n signatures by sign of distinct messages whose
+// nonces have their low l bits zeroed, plus the hashes that went into them
+func biasedSignatures(n, l int, q bi.Int, sign func([]byte, bi.Int) (bi.Int, bi.Int), digest func([]byte) bi.Int) []crypto.SignatureSample {
+	scale := bi.Exp(bi.Two, bi.FromInt(l), bi.Zero)
+	sigs := make([]crypto.SignatureSample, n)
+	for i := range sigs {
+		msg := []byte(fmt.Sprintf("crazy flamboyant for the rap enjoyment %d", i))
+		k := crypto.RandInt(q.Div(scale).Sub(bi.One)).Add(bi.One).Mul(scale)
+		r, s := sign(msg, k)
+		sigs[i] = crypto.SignatureSample{H: digest(msg), R: r, S: s}
+	}
+	return sigs
+}
+
+func Solve8_62() Result {
+	l := 8
+
+	c := ec.ToyCurve()
+	key := c.GenECDSAKey(sha256.New())
+	sigs := biasedSignatures(20, l, c.N, key.SignWithK, key.Digest)
+	d, err := crypto.BiasedNonceAttack(sigs, c.N, l, func(d bi.Int) bool {
+		return c.ScalarBaseMult(d).Equal(key.Q)
+	})
+	if err != nil {
+		return failed(fmt.Errorf("ecdsa: %w", err))
+	}
+	if !d.Equal(key.D) {
+		return check(d.String(), key.D.String())
+	}
+
+	params, err := crypto.GenerateDSAParams(1024, 160)
+	if err != nil {
+		return failed(err)
+	}
+	dsa := params.GenKey(sha1.New())
+	sigs = biasedSignatures(24, l, params.Q, dsa.SignWithK, dsa.Digest)
+	x, err := crypto.BiasedNonceAttack(sigs, params.Q, l, func(x bi.Int) bool {
+		return bi.Exp(params.G, x, params.P).Equal(dsa.Y)
+	})
+	if err != nil {
+		return failed(fmt.Errorf("dsa: %w", err))
+	}
+	return check(x.String(), dsa.X.String())
+}
+
+func Solve8_63() Result {
+	key := crypto.RandAESKey()
+	gc, err := crypto.NewAESInGCMCipher(key)
+	if err != nil {
+		return failed(err)
+	}
+	nonce := utils.RandBytes(crypto.GCMNonceSize)
+	aad := []byte("user=alice")
+	// the oracle forgets to change the nonce between messages
+	sealOracle := func(msg []byte) (crypto.GCMSample, error) {
+		b, err := gc.Seal(msg, nonce, aad)
+		if err != nil {
+			return crypto.GCMSample{}, err
+		}
+		return crypto.GCMSample{
+			AAD:        aad,
+			Ciphertext: b[:len(b)-crypto.GCMTagSize],
+			Tag:        b[len(b)-crypto.GCMTagSize:],
+		}, nil
+	}
+	queries := 0
+	openOracle := func(b, aad []byte) bool {
+		queries++
+		_, err := gc.Open(b, nonce, aad)
+		return err == nil
+	}
+
+	msgs := []string{
+		"transfer 10 from alice to bob",
+		"transfer 20 from alice to carol, thanks",
+		"ping",
+	}
+	samples := make([]crypto.GCMSample, len(msgs))
+	for i, m := range msgs {
+		if samples[i], err = sealOracle([]byte(m)); err != nil {
+			return failed(err)
+		}
+	}
+	candidates, err := crypto.RecoverGCMAuthKey(samples)
+	if err != nil {
+		return failed(err)
+	}
+
+	// flip the amount in the first message and claim it came from the admin
+	ct := utils.XorBytes(samples[0].Ciphertext, utils.XorBytes([]byte(msgs[0]), []byte("transfer 99 from alice to eve")))
+	forgedAAD := []byte("user=admin")
+	for _, h := range candidates {
+		tag := crypto.ForgeGCMTag(h, samples[0], ct, forgedAAD)
+		if openOracle(utils.ConcatBytes(ct, tag), forgedAAD) {
+			// the real H is E(key, 0), only used to check the answer
+			block, _ := aes.NewCipher(key)
+			hb := make([]byte, crypto.AESBlockSize)
+			block.Encrypt(hb, hb)
+			res := check(h.String(), gf128.FromBytes(hb).String())
+			res.Queries = queries
+			return res
 		}
 	}
-	if success {
-		fmt.Println("SUCCESS!")
+	return Result{Err: crypto.ErrAttackFailed, Queries: queries}
+}
+
+func Solve8_64() Result {
+	key := crypto.RandAESKey()
+	gc, err := crypto.NewAESInGCMCipherWithTagSize(key, 4)
+	if err != nil {
+		return failed(err)
+	}
+	nonce := utils.RandBytes(crypto.GCMNonceSize)
+	msg := utils.RandBytes((1 << 17) * crypto.AESBlockSize)
+	sealed, err := gc.Seal(msg, nonce, nil)
+	if err != nil {
+		return failed(err)
+	}
+	ct, tag := sealed[:len(msg)], sealed[len(msg):]
+
+	queries := 0
+	oracle := func(ct, tag []byte) bool {
+		queries++
+		_, err := gc.Open(utils.ConcatBytes(ct, tag), nonce, nil)
+		return err == nil
+	}
+	h, err := crypto.BreakGCMTruncatedMAC(ct, tag, oracle)
+	if err != nil {
+		return Result{Err: err, Queries: queries}
 	}
 
+	// the real H is E(key, 0), only used to check the answer
+	block, _ := aes.NewCipher(key)
+	hb := make([]byte, crypto.AESBlockSize)
+	block.Encrypt(hb, hb)
+	res := check(h.String(), gf128.FromBytes(hb).String())
+	res.Queries = queries
+	return res
 }
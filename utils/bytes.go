@@ -1,7 +1,21 @@
 package utils
 
 import (
-	"math/rand"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidPadding is wrapped by every error Unpad returns
+	ErrInvalidPadding = errors.New("invalid padding")
+	// ErrPaddedLength is returned for padded data that is empty or not a
+	// whole number of blocks
+	ErrPaddedLength = fmt.Errorf("length is not a multiple of the block size: %w", ErrInvalidPadding)
+	// ErrPadValue is returned when the last byte is 0 or larger than the block
+	// size
+	ErrPadValue = fmt.Errorf("pad byte out of range: %w", ErrInvalidPadding)
+	// ErrPadBytes is returned when the padding bytes aren't all the same
+	ErrPadBytes = fmt.Errorf("padding bytes differ: %w", ErrInvalidPadding)
 )
 
 func CountSetBits(b byte) int {
@@ -29,7 +43,8 @@ func PadBytes(b []byte, n int) []byte {
 	return res
 }
 
-// RemovePad removes padding from b
+// RemovePad removes padding from b. It is lenient, b is returned as it is if
+// the padding is invalid, use Unpad to find out
 func RemovePad(b []byte) []byte {
 	if len(b) == 0 {
 		return []byte{}
@@ -48,13 +63,21 @@ func RemovePad(b []byte) []byte {
 	return res
 }
 
-// RandBytes returns a byte slice of n random bytes
-func RandBytes(n int) (res []byte) {
-	res = make([]byte, n)
-	for i := 0; i < n; i++ {
-		res[i] = byte(rand.Intn(1 << 8))
+// Unpad checks and removes the PKCS#7 padding of b, padded to blockSize bytes
+func Unpad(b []byte, blockSize int) ([]byte, error) {
+	if len(b) == 0 || len(b)%blockSize != 0 {
+		return nil, fmt.Errorf("%d bytes: %w", len(b), ErrPaddedLength)
 	}
-	return
+	n := int(b[len(b)-1])
+	if n == 0 || n > blockSize {
+		return nil, fmt.Errorf("pad byte %d: %w", n, ErrPadValue)
+	}
+	for _, c := range b[len(b)-n:] {
+		if int(c) != n {
+			return nil, fmt.Errorf("pad byte %d, want %d: %w", c, n, ErrPadBytes)
+		}
+	}
+	return b[:len(b)-n], nil
 }
 
 func RepBytes(c byte, n int) (res []byte) {
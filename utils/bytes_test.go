@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 )
 
@@ -30,3 +31,32 @@ func TestRemovePad(t *testing.T) {
 		t.Fatalf("Remove padding bytes failed for Yellow Submarine and 20")
 	}
 }
+
+func TestUnpad(t *testing.T) {
+	for _, s := range []string{"", "ICE ICE BABY", "YELLOW SUBMARINE"} {
+		got, err := Unpad(PadBytes([]byte(s), 16), 16)
+		if err != nil || string(got) != s {
+			t.Fatalf("%q: got %q, %v", s, got, err)
+		}
+	}
+	for _, c := range []struct {
+		b    string
+		want error
+	}{
+		{"ICE ICE BABY\x04\x04\x04\x04", nil},
+		{"ICE ICE BABY\x05\x05\x05\x05", ErrPadBytes},
+		{"ICE ICE BABY\x01\x02\x03\x04", ErrPadBytes},
+		{"ICE ICE BABY\x00\x00\x00\x00", ErrPadValue},
+		{"ICE ICE BABY\x04\x04\x04\x11", ErrPadValue},
+		{"ICE ICE BABY\x04\x04\x04", ErrPaddedLength},
+		{"", ErrPaddedLength},
+	} {
+		_, err := Unpad([]byte(c.b), 16)
+		if !errors.Is(err, c.want) {
+			t.Fatalf("%q: err = %v, want %v", c.b, err, c.want)
+		}
+		if c.want != nil && !errors.Is(err, ErrInvalidPadding) {
+			t.Fatalf("%q: %v doesn't wrap ErrInvalidPadding", c.b, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"io"
+	mrand "math/rand"
+	"sync"
+)
+
+// Every key, IV, nonce, salt and prime generated in the module reads its
+// randomness from randSrc. It is crypto/rand by default. Tests and
+// reproducible runs switch it to a seeded source with UseSeededRand.
+var (
+	randMu  sync.Mutex
+	randSrc io.Reader = crand.Reader
+)
+
+// RandReader reads from the current randomness source. It can be handed to
+// anything that wants an io.Reader of random bytes
+var RandReader io.Reader = randReader{}
+
+type randReader struct{}
+
+func (randReader) Read(b []byte) (int, error) {
+	randMu.Lock()
+	defer randMu.Unlock()
+	return io.ReadFull(randSrc, b)
+}
+
+// SetRandSource makes r the source of all randomness
+func SetRandSource(r io.Reader) {
+	randMu.Lock()
+	defer randMu.Unlock()
+	randSrc = r
+}
+
+// UseCryptoRand switches the randomness source to crypto/rand
+func UseCryptoRand() {
+	SetRandSource(crand.Reader)
+}
+
+// UseSeededRand switches the randomness source to a deterministic one
+// seeded with seed. Never use it for keys that protect anything
+func UseSeededRand(seed int64) {
+	SetRandSource(mrand.New(mrand.NewSource(seed)))
+}
+
+// ReadRand fills b with random bytes
+func ReadRand(b []byte) {
+	if _, err := RandReader.Read(b); err != nil {
+		panic(err)
+	}
+}
+
+// RandBytes returns a byte slice of n random bytes
+func RandBytes(n int) []byte {
+	res := make([]byte, n)
+	ReadRand(res)
+	return res
+}
+
+// RandIntn returns a uniform random int in [0, n). It panics if n <= 0
+func RandIntn(n int) int {
+	if n <= 0 {
+		panic("RandIntn: n must be positive")
+	}
+	// reject the values in the last partial range to keep the result uniform
+	max := ^uint64(0) - ^uint64(0)%uint64(n)
+	b := make([]byte, 8)
+	for {
+		ReadRand(b)
+		v := binary.BigEndian.Uint64(b)
+		if v < max {
+			return int(v % uint64(n))
+		}
+	}
+}
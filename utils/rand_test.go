@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUseSeededRandIsDeterministic(t *testing.T) {
+	defer UseCryptoRand()
+	UseSeededRand(35)
+	a := RandBytes(32)
+	UseSeededRand(35)
+	b := RandBytes(32)
+	if !bytes.Equal(a, b) {
+		t.Fatalf("same seed gave different bytes: %x %x", a, b)
+	}
+}
+
+func TestRandIntn(t *testing.T) {
+	seen := make([]bool, 7)
+	for i := 0; i < 1000; i++ {
+		x := RandIntn(7)
+		if x < 0 || x >= 7 {
+			t.Fatalf("RandIntn(7) returned %d", x)
+		}
+		seen[x] = true
+	}
+	for i, ok := range seen {
+		if !ok {
+			t.Fatalf("RandIntn(7) never returned %d", i)
+		}
+	}
+}